@@ -1,78 +1,727 @@
 package main
 
 import (
-    "fmt"
+    "context"
+    "flag"
+    "io"
     "io/ioutil"
-    "os"    
-    "log"
+    "mime"
+    "net"
     "net/http"
-    "encoding/json"     
+    "os"
+    "os/signal"
+    "strconv"
+    "strings"
+    "sync/atomic"
+    "syscall"
+    "time"
+    "encoding/json"
+    "encoding/xml"     
     "github.com/julienschmidt/httprouter"   
 )
 
 type Message struct {
-    Body string
-    Number int8
-    Decimal float32
-    Validate bool
+    XMLName xml.Name `json:"-" xml:"message"`
+    Body string `xml:"body" validate:"required"`
+    Number int8 `xml:"number"`
+    Decimal float32 `xml:"decimal" validate:"min=0"`
+    Validate bool `xml:"validate"`
 }
 
 type Pokemon struct {
-    name string
-    level int8
+    Name string `json:"name"`
+    Level int8 `json:"level"`
 }
 
-func returnJson(url string, w http.ResponseWriter, r *http.Request){
-    fmt.Print("aqui")
-    response, err := http.Get(url)
+// errorResponse is the body of every error reply. Code is a stable,
+// machine-readable name for errors a client may want to tell apart, and
+// Fields maps the offending fields of a request that failed validation to
+// what is wrong with them.
+type errorResponse struct {
+    Error string `json:"error"`
+    Status int `json:"status"`
+    Code string `json:"code,omitempty"`
+    Fields map[string]string `json:"fields,omitempty"`
+}
+
+// writeError replies to the request with the given status code and a
+// JSON body of the form {"error": message, "status": status}.
+func writeError(w http.ResponseWriter, status int, message string) {
+    writeErrorResponse(w, errorResponse{Error: message, Status: status})
+}
+
+// writeErrorCode is writeError with an error code added to the body.
+func writeErrorCode(w http.ResponseWriter, status int, code, message string) {
+    writeErrorResponse(w, errorResponse{Error: message, Status: status, Code: code})
+}
+
+func writeErrorResponse(w http.ResponseWriter, e errorResponse) {
+    b, _ := json.Marshal(e)
+
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(e.Status)
+    w.Write(b)
+}
+
+// writeValidationFailed replies with a 422 listing what is wrong with each
+// of the failed fields.
+func writeValidationFailed(w http.ResponseWriter, failed map[string]string) {
+    writeErrorResponse(w, errorResponse{
+        Error: "validation failed",
+        Status: http.StatusUnprocessableEntity,
+        Code: "validation_failed",
+        Fields: failed,
+    })
+}
+
+// wantsPretty reports whether the request asks for indented output with
+// ?pretty=true.
+func wantsPretty(r *http.Request) bool {
+    pretty, _ := strconv.ParseBool(r.URL.Query().Get("pretty"))
+    return pretty
+}
+
+// marshalJson encodes v as JSON, indented by two spaces when the request
+// wants it pretty.
+func marshalJson(r *http.Request, v interface{}) ([]byte, error) {
+    if wantsPretty(r) {
+        return json.MarshalIndent(v, "", "  ")
+    }
+    return json.Marshal(v)
+}
+
+// writeJsonFor replies to r with the given status code and v encoded as
+// JSON, indented when r wants it pretty.
+func writeJsonFor(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+    b, err := marshalJson(r, v)
+    if err != nil {
+        logErrorf("%v", err)
+        writeError(w, http.StatusInternalServerError, "could not encode response")
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(status)
+    w.Write(b)
+}
+
+// httpError is an error carrying the status code and message that should
+// be reported to our own client.
+type httpError struct {
+    status int
+    message string
+}
+
+func (e *httpError) Error() string {
+    return e.message
+}
+
+// writeHttpError replies with err's status and message when it is an
+// *httpError, with a 429 when an upstream rate limited us, and with a
+// generic 500 otherwise.
+func writeHttpError(w http.ResponseWriter, err error) {
+    switch err := err.(type) {
+    case *httpError:
+        writeError(w, err.status, err.message)
+    case *upstreamRateLimitedError:
+        if err.retryAfter != "" {
+            w.Header().Set("Retry-After", err.retryAfter)
+        }
+        writeErrorCode(w, http.StatusTooManyRequests, "upstream_rate_limited", err.Error())
+    default:
+        writeError(w, http.StatusInternalServerError, "internal error")
+    }
+}
+
+// openUpstream GETs url and returns the response for the caller to read
+// and close. Failures, including non-2xx upstream responses, are reported
+// as an *httpError; an upstream 404 stays a 404.
+func openUpstream(ctx context.Context, url string) (*http.Response, error) {
+    request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+    if err != nil {
+        logErrorf("%v", err)
+        return nil, &httpError{http.StatusInternalServerError, "invalid upstream URL"}
+    }
+    id := requestIDFromContext(ctx)
+    if id != "" {
+        request.Header.Set("X-Request-ID", id)
+    }
+    logInfof("request_id=%s upstream=%q", id, url)
+    logDebugf("request_id=%s upstream=%q request_headers=%v", id, url, request.Header)
+    if err := upstreamSlots.acquire(ctx); err != nil {
+        logErrorf("request_id=%s upstream=%q: no free upstream slot", id, url)
+        return nil, err
+    }
+    response, err := doWithRetry(request)
+
+    if err != nil {
+        upstreamSlots.release()
+        logErrorf("%v", err)
+        // A failing upstream must not take the whole server down, so
+        // report it to this client only.
+        if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+            return nil, &httpError{http.StatusGatewayTimeout, "upstream timeout"}
+        }
+        return nil, &httpError{http.StatusBadGateway, "upstream unavailable"}
+    }
+    logDebugf("request_id=%s upstream=%q status=%d response_headers=%v", id, url, response.StatusCode, response.Header)
+    if response.StatusCode >= 200 && response.StatusCode <= 299 {
+        response.Body = &releasingBody{ReadCloser: response.Body, slots: upstreamSlots}
+        if err := decodeUpstreamBody(response); err != nil {
+            logErrorf("upstream %s: %v", url, err)
+            response.Body.Close()
+            return nil, err
+        }
+        return response, nil
+    }
+
+    response.Body.Close()
+    upstreamSlots.release()
+    switch {
+    case response.StatusCode == http.StatusNotFound:
+        return nil, &httpError{http.StatusNotFound, "not found"}
+    case response.StatusCode == http.StatusTooManyRequests:
+        logErrorf("upstream %s is rate limiting us", url)
+        return nil, &upstreamRateLimitedError{response.Header.Get("Retry-After")}
+    case response.StatusCode >= 500:
+        logErrorf("upstream %s returned %s", url, response.Status)
+        return nil, &httpError{http.StatusBadGateway, "upstream unavailable"}
+    default:
+        logErrorf("upstream %s returned %s", url, response.Status)
+        return nil, &httpError{http.StatusBadGateway, "unexpected upstream response"}
+    }
+}
 
+// fetchUpstream GETs url like openUpstream and reads the whole body,
+// which should be JSON. A body declared as something else, such as the
+// HTML error page of a proxy in front of the upstream, is refused with
+// errUnexpectedUpstream.
+func fetchUpstream(ctx context.Context, url string) ([]byte, error) {
+    response, err := openUpstream(ctx, url)
     if err != nil {
-        fmt.Print(err.Error())
-        os.Exit(1)
+        return nil, err
     }
+    defer response.Body.Close()
 
     responseData, err := ioutil.ReadAll(response.Body)
     if err != nil {
-        log.Fatal(err)
+        logErrorf("%v", err)
+        return nil, &httpError{http.StatusInternalServerError, "could not read upstream response"}
     }
-   
-    w.Header().Set("Content-Type", "application/json")
-    fmt.Fprintf(w, string(responseData)) 
+    if contentType := response.Header.Get("Content-Type"); !maybeJSON(contentType) {
+        logUpstreamBody("upstream " + url + " sent " + contentType, responseData)
+        return nil, errUnexpectedUpstream
+    }
+    return responseData, nil
 }
 
+var errUnexpectedUpstream = &httpError{http.StatusBadGateway, "unexpected upstream response"}
+
+// maxLoggedUpstreamBytes is how much of an unexpected upstream body is
+// logged.
+const maxLoggedUpstreamBytes = 256
+
+// maybeJSON reports whether a body of contentType may be JSON. Besides
+// the JSON types, that is a missing type and text/plain, which is what
+// servers that do not set one end up sending.
+func maybeJSON(contentType string) bool {
+    if contentType == "" {
+        return true
+    }
+    mediaType, _, err := mime.ParseMediaType(contentType)
+    if err != nil {
+        return false
+    }
+    return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") || mediaType == "text/plain"
+}
+
+// logUpstreamBody logs why an upstream body was refused along with its
+// first maxLoggedUpstreamBytes.
+func logUpstreamBody(why string, body []byte) {
+    if len(body) > maxLoggedUpstreamBytes {
+        logErrorf("%s: %s", why, truncateBody(body[:maxLoggedUpstreamBytes], true))
+        return
+    }
+    logErrorf("%s: %s", why, truncateBody(body, false))
+}
+
+// decodeUpstreamJSON decodes the upstream body responseData into v,
+// answering errUnexpectedUpstream when it is not the JSON expected.
+func decodeUpstreamJSON(responseData []byte, v interface{}) error {
+    if err := json.Unmarshal(responseData, v); err != nil {
+        logUpstreamBody("decoding upstream response: " + err.Error(), responseData)
+        return errUnexpectedUpstream
+    }
+    return nil
+}
+
+// proxiedHeaders are the upstream response headers passed on to our
+// client. Hop-by-hop and connection-specific headers are left out.
+var proxiedHeaders = []string{"Cache-Control", "Content-Language", "ETag", "Expires", "Last-Modified"}
+
+// proxyTo returns a handler that passes requests through to baseURL. The
+// path is the route's *path catch-all when it has one and the whole
+// request path otherwise; the query string is kept as is. The upstream
+// request carries the upstream's own Host, as it would for any client.
+func proxyTo(baseURL string) httprouter.Handle {
+    return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+        path := ps.ByName("path")
+        if path == "" {
+            path = r.URL.Path
+        }
+        url := strings.TrimSuffix(baseURL, "/") + path
+        if r.URL.RawQuery != "" {
+            url += "?" + r.URL.RawQuery
+        }
+        returnJson(url, w, r)
+    }
+}
+
+// returnJson streams the body of url to the client as it arrives, along
+// with the upstream Content-Type and those proxiedHeaders the handler has
+// not set itself.
+func returnJson(url string, w http.ResponseWriter, r *http.Request){
+    // Tie the upstream call to the incoming request so it is cancelled
+    // when our client goes away.
+    response, err := openUpstream(r.Context(), url)
+    if err != nil {
+        writeHttpError(w, err)
+        return
+    }
+    defer response.Body.Close()
+
+    contentType := response.Header.Get("Content-Type")
+    if contentType == "" {
+        contentType = "application/json"
+    }
+    w.Header().Set("Content-Type", contentType)
+    for _, name := range proxiedHeaders {
+        if value := response.Header.Get(name); value != "" && w.Header().Get(name) == "" {
+            w.Header().Set(name, value)
+        }
+    }
+    if _, err := io.Copy(w, response.Body); err != nil {
+        // The status is already sent, so all we can do is cut the body
+        // short and log why.
+        logErrorf("copying upstream %s: %v", url, err)
+    }
+}
+
+// seededSince is the Last-Modified of seeded random user replies. The
+// same seed always gives the same users, so they cannot change while we
+// run; a restart may bring a new randomuser.me, so it starts over then.
+var seededSince = time.Now().UTC().Truncate(time.Second)
+
 func retornarUsuarioAleatorio(w http.ResponseWriter, r *http.Request, ps httprouter.Params){
-    fmt.Print("aqui")
-    returnJson("https://randomuser.me/api/", w, r)
+    query, err := randomUserQuery(r)
+    if err != nil {
+        w.Header().Set("Cache-Control", "no-store")
+        writeHttpError(w, err)
+        return
+    }
+
+    if query.Get("seed") == "" {
+        // Every reply is a different random user, so none may be reused.
+        w.Header().Set("Cache-Control", "no-store")
+    } else {
+        // A seeded reply may be kept, as long as it is checked with
+        // If-Modified-Since before being reused.
+        w.Header().Set("Cache-Control", "public, no-cache")
+        w.Header().Set("Last-Modified", seededSince.Format(http.TimeFormat))
+        if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && !seededSince.After(since) {
+            w.WriteHeader(http.StatusNotModified)
+            return
+        }
+    }
+
+    url := config.RandomUserBaseURL + "/"
+    if len(query) > 0 {
+        url += "?" + query.Encode()
+    }
+    returnJson(url, w, r)
 }
 
 func retornarPokemon(w http.ResponseWriter, r *http.Request, ps httprouter.Params){
-    nomePokemon := ps.ByName("nome")
-    urlApi := "https://pokeapi.co/api/v2/pokemon/" + nomePokemon
+    fields, err := parseFields(r, pokemonFields)
+    if err != nil {
+        writeHttpError(w, err)
+        return
+    }
+    raw, stale, err := fetchRawPokemonStale(r.Context(), ps.ByName("nome"))
+    if err != nil {
+        writeHttpError(w, err)
+        return
+    }
+    pokemon, err := project(trimPokemon(raw), fields)
+    if err != nil {
+        logErrorf("%v", err)
+        writeError(w, http.StatusInternalServerError, "could not encode response")
+        return
+    }
+
+    if stale {
+        w.Header().Set("Warning", `110 - "Response is Stale"`)
+    } else {
+        // Pokemon data never changes, so anyone may keep it for a while.
+        w.Header().Set("Cache-Control", "public, max-age=" + strconv.Itoa(int(config.PokemonMaxAge.Seconds())))
+    }
+    writeJsonWithETag(w, r, pokemon)
+}
 
-    returnJson(urlApi, w, r)
+func retornarCacheStats(w http.ResponseWriter, r *http.Request, ps httprouter.Params){
+    writeJsonFor(w, r, http.StatusOK, pokemonCache.stats())
 }
 
 func retornarStruct(w http.ResponseWriter, r *http.Request, ps httprouter.Params){
-    m := Message{"Hello, Mundão!", 124, 1687.87845, true}
-    b, err := json.Marshal(m)
+    m, err := messageFromQuery(r.URL.Query())
     if err != nil {
-        log.Fatal(err)
+        writeHttpError(w, err)
+        return
     }
 
-    w.Header().Set("Content-Type", "application/json")
-    fmt.Fprintf(w, string(b)) 
+    writeNegotiated(w, r, http.StatusOK, m)
 }
 
-func handleRequests() {
+func criarPokemon(w http.ResponseWriter, r *http.Request, ps httprouter.Params){
+    var p Pokemon
+    failed, err := decodeValidated(r.Body, pokemonSchema, &p)
+    if err != nil {
+        writeDecodeError(w, err)
+        return
+    }
+    if len(failed) > 0 {
+        writeValidationFailed(w, failed)
+        return
+    }
+    if err := validatePokemon(p); err != nil {
+        writeError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+
+    if err := store.add(p); err != nil {
+        writeErrorCode(w, http.StatusConflict, "pokemon_exists", err.Error())
+        return
+    }
+    w.Header().Set("ETag", versionETag(1))
+    writeJsonFor(w, r, http.StatusCreated, p)
+}
+
+// atualizarPokemon changes the level of a stored Pokemon. The stored
+// version is sent back as the ETag; sending it in If-Match makes the
+// update fail with a 409 if someone else changed the Pokemon meanwhile.
+func atualizarPokemon(w http.ResponseWriter, r *http.Request, ps httprouter.Params){
+    var body struct {
+        Level *int8 `json:"level"`
+    }
+    if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+        writeDecodeError(w, err)
+        return
+    }
+    if body.Level == nil {
+        writeError(w, http.StatusBadRequest, "level is required")
+        return
+    }
+
+    version := 0
+    if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+        var ok bool
+        if version, ok = parseVersionETag(ifMatch); !ok {
+            writeError(w, http.StatusBadRequest, "invalid If-Match header")
+            return
+        }
+    }
+
+    p, err := store.updateLevel(ps.ByName("nome"), *body.Level, version)
+    switch err {
+    case nil:
+    case errPokemonNotFound:
+        writeError(w, http.StatusNotFound, err.Error())
+        return
+    case errVersionConflict:
+        writeErrorCode(w, http.StatusConflict, "version_conflict", err.Error())
+        return
+    default:
+        writeHttpError(w, err)
+        return
+    }
+    w.Header().Set("ETag", versionETag(p.version))
+    writeJsonFor(w, r, http.StatusOK, p.Pokemon)
+}
+
+func criarMensagem(w http.ResponseWriter, r *http.Request, ps httprouter.Params){
+    if negotiate(r) == "" {
+        writeNotAcceptable(w)
+        return
+    }
+
+    // A value the schema rejects, such as a Number outside the int8
+    // range, is a validation failure rather than bad JSON.
+    var m Message
+    failed, err := decodeValidated(r.Body, messageSchema, &m)
+    if err != nil {
+        writeDecodeError(w, err)
+        return
+    }
+
+    if m.Validate {
+        for field, msg := range validateStruct(m) {
+            if _, ok := failed[field]; !ok {
+                failed[field] = msg
+            }
+        }
+    }
+    if len(failed) > 0 {
+        writeValidationFailed(w, failed)
+        return
+    }
+    writeNegotiated(w, r, http.StatusOK, m)
+}
+
+func deletarPokemon(w http.ResponseWriter, r *http.Request, ps httprouter.Params){
+    if !store.remove(ps.ByName("nome")) {
+        writeError(w, http.StatusNotFound, "pokemon not found")
+        return
+    }
+    w.WriteHeader(http.StatusNoContent)
+}
+
+func listarPokemons(w http.ResponseWriter, r *http.Request, ps httprouter.Params){
+    writeJsonFor(w, r, http.StatusOK, store.all())
+}
+
+// notFound answers requests for paths no route matches.
+func notFound(w http.ResponseWriter, r *http.Request) {
+    writeError(w, http.StatusNotFound, "not found")
+}
+
+// methodNotAllowed answers requests whose path matches a route registered
+// for other methods. The router has already listed those in the Allow
+// header.
+func methodNotAllowed(w http.ResponseWriter, r *http.Request) {
+    writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+}
+
+func newRouter() *httprouter.Router {
     router := httprouter.New()
-    router.GET("/retornarUsuarioAleatorio", retornarUsuarioAleatorio)
-    router.GET("/retornarStruct", retornarStruct)
-    router.GET("/retornarPokemon/:nome", retornarPokemon)
-    //router.POST("/criarPokemon") TODO
+    limiter := newRateLimiter(config.RateLimit, config.RateBurst, config.TrustProxy)
+    cors := corsPolicy{config.CORSAllowedOrigins}
+    auth := apiKeyAuth{config.APIKeys}
 
-    log.Fatal(http.ListenAndServe(":10000", router))
+    // Every route is tagged with a request ID, traced, recovered from
+    // panics, logged and counted. Recovery comes right after the request
+    // ID and the span so that it can log the one and end the other with
+    // the 500. Routes behind a feature flag are refused next while it is
+    // off. All but the bare routes then get CORS, compression, rate
+    // limiting and body size and time limits, streams going without
+    // compression and the size and time limits and with the server's
+    // write deadline lifted, and the routes that need it finally check the
+    // API key and Idempotency-Key.
+    chain := func(rt route) []Middleware {
+        mws := []Middleware{
+            withRequestID,
+            func(next httprouter.Handle) httprouter.Handle { return traceRequests(rt.Path, next) },
+            recoverPanics,
+            withAcceptLanguage,
+            logRequests,
+            func(next httprouter.Handle) httprouter.Handle { return countRequests(rt.Path, next) },
+            func(next httprouter.Handle) httprouter.Handle { return logBodies(config.LogBodyBytes, next) },
+        }
+        if rt.Feature != "" {
+            mws = append(mws, func(next httprouter.Handle) httprouter.Handle { return requireFeature(rt.Feature, next) })
+        }
+        if !rt.Bare {
+            mws = append(mws, cors.handle)
+            if !rt.Stream {
+                mws = append(mws, gzipResponses)
+            }
+            mws = append(mws, limiter.limit)
+            if rt.Stream {
+                mws = append(mws, noWriteDeadline)
+            } else {
+                mws = append(mws,
+                    func(next httprouter.Handle) httprouter.Handle { return limitBody(config.MaxBodyBytes, next) },
+                    func(next httprouter.Handle) httprouter.Handle { return limitDuration(config.HandlerTimeout, next) },
+                )
+            }
+        }
+        if rt.Auth {
+            mws = append(mws, auth.require)
+        }
+        if rt.Idempotent {
+            mws = append(mws, func(next httprouter.Handle) httprouter.Handle { return idempotent(rt.Path, next) })
+        }
+        return mws
+    }
+
+    router.GlobalOPTIONS = http.HandlerFunc(cors.preflight)
+    router.NotFound = http.HandlerFunc(notFound)
+    router.MethodNotAllowed = http.HandlerFunc(methodNotAllowed)
+    for _, rt := range routes() {
+        handle := Chain(rt.handle, chain(rt)...)
+        router.Handle(rt.Method, rt.Path, handle)
+        // Load balancers and monitors probe with HEAD, which httprouter
+        // does not derive from GET by itself.
+        if rt.Method == http.MethodGet && !rt.Stream {
+            router.Handle(http.MethodHead, rt.Path, headAsGet(handle))
+        }
+    }
+
+    return router
+}
+
+const defaultPort = 10000
+
+// listenAddr resolves the address the server listens on: c.Addr when
+// set, otherwise c.Port on every interface.
+func listenAddr(c Config) string {
+    if c.Addr != "" {
+        return c.Addr
+    }
+    return ":" + strconv.Itoa(c.Port)
+}
+
+// shutdownTimeout bounds how long in-flight requests may take to drain.
+const shutdownTimeout = 10 * time.Second
+
+// drainDelay is how long the server keeps serving after a stop signal,
+// with /readyz failing, so that load balancers stop sending us traffic
+// before the listener closes.
+var drainDelay = 5 * time.Second
+
+// newServer returns a server for handler with the timeouts of config.
+func newServer(handler http.Handler) *http.Server {
+    return &http.Server{
+        Handler: handler,
+        ReadHeaderTimeout: config.ReadHeaderTimeout,
+        ReadTimeout: config.ReadTimeout,
+        WriteTimeout: config.WriteTimeout,
+        IdleTimeout: config.IdleTimeout,
+    }
+}
+
+// serve serves handler on listener until a signal arrives on stop, then
+// marks the server as draining for drainDelay and shuts it down
+// gracefully. The upstreams are health-checked in the background
+// meanwhile. When certFile and keyFile are given it serves HTTPS, which
+// also enables HTTP/2.
+func serve(listener net.Listener, handler http.Handler, certFile, keyFile string, stop <-chan os.Signal) error {
+    defer atomic.StoreInt32(&draining, 0)
+    // Hijacked connections, such as WebSockets, are not waited for by
+    // Shutdown, so their handlers watch this channel to end them.
+    shutdown := make(chan struct{})
+    server := newServer(handler)
+    server.BaseContext = func(net.Listener) context.Context {
+        return context.WithValue(context.Background(), shutdownKey, (<-chan struct{})(shutdown))
+    }
+    server.RegisterOnShutdown(func() { close(shutdown) })
+
+    checkCtx, stopChecks := context.WithCancel(context.Background())
+    checksDone := make(chan struct{})
+    go func() {
+        defer close(checksDone)
+        upstreams.run(checkCtx, config.UpstreamCheckInterval)
+    }()
+    defer func() {
+        stopChecks()
+        <-checksDone
+    }()
+    errs := make(chan error, 1)
+    go func() {
+        if certFile != "" && keyFile != "" {
+            errs <- server.ServeTLS(listener, certFile, keyFile)
+            return
+        }
+        errs <- server.Serve(listener)
+    }()
+
+    select {
+    case err := <-errs:
+        return err
+    case <-stop:
+    }
+
+    logInfof("shutting down, draining for %s", drainDelay)
+    atomic.StoreInt32(&draining, 1)
+    select {
+    case err := <-errs:
+        return err
+    case <-time.After(drainDelay):
+    }
+
+    ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+    defer cancel()
+    if err := server.Shutdown(ctx); err != nil {
+        return err
+    }
+    logInfof("shutdown complete")
+    return nil
+}
+
+// shutdownFromContext returns a channel closed when the server serving
+// ctx's request shuts down, or nil outside of serve.
+func shutdownFromContext(ctx context.Context) <-chan struct{} {
+    shutdown, _ := ctx.Value(shutdownKey).(<-chan struct{})
+    return shutdown
+}
+
+func handleRequests(addr, certFile, keyFile string) {
+    listener, err := net.Listen("tcp", addr)
+    if err != nil {
+        logFatalf("%v", err)
+    }
+    scheme := "http"
+    if certFile != "" {
+        scheme = "https"
+    }
+    logInfof("listening on %s (%s)", listener.Addr(), scheme)
+
+    stop := make(chan os.Signal, 1)
+    signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+    if err := serve(listener, newRouter(), certFile, keyFile, stop); err != nil {
+        logFatalf("%v", err)
+    }
 }
 
 func main() {
-    handleRequests()
+    flag.String("addr", "", "listen address, overrides $ADDR and $PORT (e.g. :8080)")
+    configFile := flag.String("config", "", "JSON config file, overridden by environment variables and flags")
+    flag.Duration("cache-ttl", defaultCacheTTL, "how long upstream Pokemon responses are cached, overrides $CACHE_TTL")
+    flag.Int("upstream-retries", defaultConfig.UpstreamRetries, "how many times a failed upstream GET is retried, overrides $UPSTREAM_RETRIES")
+    flag.Bool("trust-proxy", false, "identify clients by X-Forwarded-For when rate limiting, overrides $TRUST_PROXY")
+    verbose := flag.Bool("verbose", false, "log debug messages, overrides $LOG_LEVEL")
+    flag.String("store-file", "", "JSON file the created Pokemon are saved to and loaded from, overrides $STORE_FILE")
+    flag.String("tls-cert", "", "TLS certificate file, serves HTTPS together with -tls-key, overrides $TLS_CERT")
+    flag.String("tls-key", "", "TLS key file, serves HTTPS together with -tls-cert, overrides $TLS_KEY")
+    flag.Parse()
+
+    var err error
+    if config, err = loadConfig(*configFile, flag.CommandLine); err != nil {
+        logFatalf("%v", err)
+    }
+    if *verbose {
+        config.LogLevel = levelDebug
+    }
+    setLogLevel(config.LogLevel)
+    toggleDebugOnSignal(config.LogLevel)
+
+    pokemonCache = newResponseCache(config.CacheTTL, config.CacheMaxEntries)
+    pokeApiBreaker = newCircuitBreaker(config.BreakerThreshold, config.BreakerCooldown)
+    upstreamClient = newUpstreamClient(config)
+    flushSpans, err := exportSpans(config.TraceExporter)
+    if err != nil {
+        logFatalf("tracing: %v", err)
+    }
+    upstreams = registryFromConfig(config)
+    features = newFeatureFlags(config.Features)
+    upstreamSlots = newSemaphore(config.MaxUpstreamConcurrency)
+    upstreamRetries = config.UpstreamRetries
+    if config.StoreFile != "" {
+        store = openPokemonStore(config.StoreFile)
+    }
+
+    handleRequests(listenAddr(config), config.TLSCert, config.TLSKey)
+    store.flush()
+    if err := flushSpans(context.Background()); err != nil {
+        logErrorf("flushing spans: %v", err)
+    }
 }
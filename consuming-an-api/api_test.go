@@ -0,0 +1,751 @@
+package main
+
+import (
+    "bytes"
+    "crypto/ecdsa"
+    "crypto/elliptic"
+    "crypto/rand"
+    "crypto/tls"
+    "crypto/x509"
+    "crypto/x509/pkix"
+    "encoding/json"
+    "encoding/pem"
+    "io/ioutil"
+    "math/big"
+    "net"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "syscall"
+    "testing"
+    "time"
+
+    "github.com/julienschmidt/httprouter"
+)
+
+// closedURL returns the URL of a local port that nothing is listening on.
+func closedURL(t *testing.T) string {
+    l, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    addr := l.Addr().String()
+    l.Close()
+    return "http://" + addr
+}
+
+// newTestServer runs the router on a real listener, with randomuser.me
+// and PokéAPI replaced by servers replaying the recorded fixtures, so
+// that nothing reaches the internet.
+func newTestServer(t *testing.T) *httptest.Server {
+    c := overrideConfig(t)
+    c.RandomUserBaseURL = fixtureServer(t, "randomuser.json").URL
+    pokeApiFixture(t, "pikachu.json")
+
+    server := httptest.NewServer(newRouter())
+    t.Cleanup(server.Close)
+    return server
+}
+
+// TestRoutes requests every original route over HTTP, checking the
+// status, Content-Type and the top-level keys of the body.
+func TestRoutes(t *testing.T) {
+    server := newTestServer(t)
+
+    tests := []struct {
+        path string
+        keys []string
+    }{
+        {"/retornarUsuarioAleatorio", []string{"results", "info"}},
+        {"/retornarStruct", []string{"Body", "Number", "Decimal", "Validate"}},
+        {"/retornarPokemon/pikachu", []string{"name", "id", "height", "weight", "base_experience", "types", "stats"}},
+    }
+    for _, tt := range tests {
+        res, err := http.Get(server.URL + tt.path)
+        if err != nil {
+            t.Fatal(err)
+        }
+        var body map[string]json.RawMessage
+        err = json.NewDecoder(res.Body).Decode(&body)
+        res.Body.Close()
+
+        if res.StatusCode != http.StatusOK {
+            t.Errorf("GET %s status = %d, want %d", tt.path, res.StatusCode, http.StatusOK)
+        }
+        if ct := res.Header.Get("Content-Type"); ct != "application/json" {
+            t.Errorf("GET %s Content-Type = %q, want application/json", tt.path, ct)
+        }
+        if err != nil {
+            t.Errorf("GET %s body is not a JSON object: %v", tt.path, err)
+            continue
+        }
+        for _, key := range tt.keys {
+            if _, ok := body[key]; !ok {
+                t.Errorf("GET %s body has no %q key", tt.path, key)
+            }
+        }
+    }
+}
+
+// TestReturnJsonUpstreamDown points returnJson at a closed port, checking
+// that the client gets a 502 instead of the process exiting.
+func TestReturnJsonUpstreamDown(t *testing.T) {
+    fastRetries(t)
+    w := httptest.NewRecorder()
+    r := httptest.NewRequest(http.MethodGet, "/retornarUsuarioAleatorio", nil)
+
+    returnJson(closedURL(t), w, r)
+
+    if w.Code != http.StatusBadGateway {
+        t.Fatalf("returnJson status = %d, want %d", w.Code, http.StatusBadGateway)
+    }
+    var body errorResponse
+    if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+        t.Fatalf("returnJson body = %q, want JSON: %v", w.Body.String(), err)
+    }
+    if body.Error != "upstream unavailable" {
+        t.Fatalf(`returnJson error = %q, want "upstream unavailable"`, body.Error)
+    }
+}
+
+// TestCriarPokemon creates a Pokemon through POST /criarPokemon and reads
+// it back from GET /pokemons.
+func TestCriarPokemon(t *testing.T) {
+    store = newPokemonStore()
+    server := httptest.NewServer(newRouter())
+    defer server.Close()
+
+    res, err := http.Post(server.URL + "/criarPokemon", "application/json", strings.NewReader(`{"name":"pikachu","level":12}`))
+    if err != nil {
+        t.Fatal(err)
+    }
+    res.Body.Close()
+    if res.StatusCode != http.StatusCreated {
+        t.Fatalf("POST /criarPokemon status = %d, want %d", res.StatusCode, http.StatusCreated)
+    }
+
+    res, err = http.Get(server.URL + "/pokemons")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer res.Body.Close()
+    var pokemons []Pokemon
+    if err := json.NewDecoder(res.Body).Decode(&pokemons); err != nil {
+        t.Fatal(err)
+    }
+    if len(pokemons) != 1 || pokemons[0] != (Pokemon{"pikachu", 12}) {
+        t.Fatalf("GET /pokemons = %v, want [{pikachu 12}]", pokemons)
+    }
+}
+
+// TestCriarPokemonMixedCase posts keys in other cases than the JSON
+// names, checking they are stored like encoding/json decodes them.
+func TestCriarPokemonMixedCase(t *testing.T) {
+    store = newPokemonStore()
+    w := httptest.NewRecorder()
+    r := httptest.NewRequest(http.MethodPost, "/criarPokemon", strings.NewReader(`{"Name":"pikachu","Level":5}`))
+
+    criarPokemon(w, r, nil)
+
+    if w.Code != http.StatusCreated {
+        t.Fatalf("criarPokemon status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body)
+    }
+    if got := store.all(); len(got) != 1 || got[0] != (Pokemon{"pikachu", 5}) {
+        t.Errorf("stored %v, want [{pikachu 5}]", got)
+    }
+}
+
+// TestCriarPokemonInvalid posts malformed bodies, checking for a 400.
+func TestCriarPokemonInvalid(t *testing.T) {
+    store = newPokemonStore()
+    for _, body := range []string{`{"name":`, `{"level":3}`} {
+        w := httptest.NewRecorder()
+        r := httptest.NewRequest(http.MethodPost, "/criarPokemon", strings.NewReader(body))
+
+        criarPokemon(w, r, nil)
+
+        if w.Code != http.StatusBadRequest {
+            t.Fatalf("criarPokemon(%s) status = %d, want %d", body, w.Code, http.StatusBadRequest)
+        }
+    }
+}
+
+// TestPokemonJson marshals a Pokemon, checking that its fields are
+// encoded with lowercase keys.
+func TestPokemonJson(t *testing.T) {
+    b, err := json.Marshal(Pokemon{"ditto", 42})
+    if err != nil {
+        t.Fatal(err)
+    }
+    want := `{"name":"ditto","level":42}`
+    if string(b) != want {
+        t.Fatalf("json.Marshal(Pokemon) = %s, want %s", b, want)
+    }
+}
+
+// TestListenAddr checks the precedence of the -addr flag, the PORT
+// environment variable and the default port.
+func TestListenAddr(t *testing.T) {
+    tests := []struct {
+        flag, port, want string
+    }{
+        {"", "", ":10000"},
+        {"", "8080", ":8080"},
+        {"127.0.0.1:9000", "8080", "127.0.0.1:9000"},
+    }
+    for _, tt := range tests {
+        setenv(t, "PORT", tt.port)
+        var args []string
+        if tt.flag != "" {
+            args = []string{"-addr=" + tt.flag}
+        }
+        c, err := loadConfig("", parseFlags(t, args...))
+        if err != nil {
+            t.Fatal(err)
+        }
+        if got := listenAddr(c); got != tt.want {
+            t.Errorf("listen address with -addr=%q and PORT=%q = %q, want %q", tt.flag, tt.port, got, tt.want)
+        }
+    }
+
+    for _, port := range []string{"not-a-port", "70000"} {
+        setenv(t, "PORT", port)
+        if _, err := loadConfig("", nil); err == nil || !strings.Contains(err.Error(), "PORT") {
+            t.Errorf("loadConfig with PORT=%q error = %v, want one naming PORT", port, err)
+        }
+    }
+}
+
+// TestReturnJsonUpstreamTimeout points returnJson at an upstream slower
+// than the client timeout, checking for a 504.
+func TestReturnJsonUpstreamTimeout(t *testing.T) {
+    upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        select {
+        case <-time.After(time.Second):
+        case <-r.Context().Done():
+        }
+    }))
+    defer upstream.Close()
+
+    defer func(timeout time.Duration) { upstreamClient.Timeout = timeout }(upstreamClient.Timeout)
+    upstreamClient.Timeout = 50 * time.Millisecond
+
+    w := httptest.NewRecorder()
+    r := httptest.NewRequest(http.MethodGet, "/retornarPokemon/ditto", nil)
+
+    returnJson(upstream.URL, w, r)
+
+    if w.Code != http.StatusGatewayTimeout {
+        t.Fatalf("returnJson status = %d, want %d", w.Code, http.StatusGatewayTimeout)
+    }
+}
+
+// shortDrain sets drainDelay to d until the test ends.
+func shortDrain(t *testing.T, d time.Duration) {
+    saved := drainDelay
+    drainDelay = d
+    t.Cleanup(func() { drainDelay = saved })
+}
+
+// TestNewServerTimeouts checks that the server gets its timeouts from
+// config, and that the defaults leave none of them unset.
+func TestNewServerTimeouts(t *testing.T) {
+    server := newServer(http.NotFoundHandler())
+    for name, d := range map[string]time.Duration{
+        "ReadHeaderTimeout": server.ReadHeaderTimeout,
+        "ReadTimeout": server.ReadTimeout,
+        "WriteTimeout": server.WriteTimeout,
+        "IdleTimeout": server.IdleTimeout,
+    } {
+        if d <= 0 {
+            t.Errorf("%s = %s, want a default above zero", name, d)
+        }
+    }
+    if config.WriteTimeout <= config.HandlerTimeout {
+        t.Errorf("default WriteTimeout %s leaves no room for HandlerTimeout %s", config.WriteTimeout, config.HandlerTimeout)
+    }
+
+    c := overrideConfig(t)
+    c.ReadHeaderTimeout, c.ReadTimeout, c.WriteTimeout, c.IdleTimeout = time.Second, 2 * time.Second, 3 * time.Second, 4 * time.Second
+    server = newServer(http.NotFoundHandler())
+    if server.ReadHeaderTimeout != time.Second || server.ReadTimeout != 2 * time.Second || server.WriteTimeout != 3 * time.Second || server.IdleTimeout != 4 * time.Second {
+        t.Errorf("timeouts = %s %s %s %s, want those of config", server.ReadHeaderTimeout, server.ReadTimeout, server.WriteTimeout, server.IdleTimeout)
+    }
+}
+
+// TestServeSlowClient sends half a request and stalls, checking that the
+// server hangs up once ReadHeaderTimeout is over.
+func TestServeSlowClient(t *testing.T) {
+    overrideConfig(t).ReadHeaderTimeout = 50 * time.Millisecond
+    shortDrain(t, 0)
+    listener, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    stop := make(chan os.Signal, 1)
+    done := make(chan error, 1)
+    go func() { done <- serve(listener, newRouter(), "", "", stop) }()
+    defer func() {
+        stop <- os.Interrupt
+        <-done
+    }()
+
+    conn, err := net.Dial("tcp", listener.Addr().String())
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer conn.Close()
+    conn.Write([]byte("GET /healthz HTTP/1.1\r\nHost: localhost\r\n"))
+    conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+    if _, err := ioutil.ReadAll(conn); err != nil {
+        t.Fatalf("connection still open after ReadHeaderTimeout: %v", err)
+    }
+}
+
+// TestServeDrains sends the stop signal, checking that /readyz fails
+// straight away while other requests are still served until the drain
+// delay is over.
+func TestServeDrains(t *testing.T) {
+    shortDrain(t, 500 * time.Millisecond)
+    listener, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    stop := make(chan os.Signal, 1)
+    done := make(chan error, 1)
+    go func() {
+        done <- serve(listener, newRouter(), "", "", stop)
+    }()
+    base := "http://" + listener.Addr().String()
+    // Without keep-alives no spare connection is left open, which
+    // Shutdown would wait on.
+    client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+
+    stop <- syscall.SIGTERM
+    deadline := time.Now().Add(drainDelay)
+    for {
+        res, err := client.Get(base + "/readyz")
+        if err != nil {
+            t.Fatal(err)
+        }
+        res.Body.Close()
+        if res.StatusCode == http.StatusServiceUnavailable {
+            break
+        }
+        if time.Now().After(deadline) {
+            t.Fatalf("GET /readyz while draining status = %d, want %d", res.StatusCode, http.StatusServiceUnavailable)
+        }
+        time.Sleep(10 * time.Millisecond)
+    }
+
+    res, err := client.Get(base + "/healthz")
+    if err != nil {
+        t.Fatalf("GET /healthz while draining: %v", err)
+    }
+    res.Body.Close()
+    if res.StatusCode != http.StatusOK {
+        t.Errorf("GET /healthz while draining status = %d, want %d", res.StatusCode, http.StatusOK)
+    }
+
+    select {
+    case err := <-done:
+        if err != nil {
+            t.Fatalf("serve() = %v, want nil", err)
+        }
+    case <-time.After(5 * time.Second):
+        t.Fatal("serve() did not return after the drain delay")
+    }
+}
+
+// TestServeShutdown starts the server, signals it to stop and checks that
+// it shuts down cleanly.
+func TestServeShutdown(t *testing.T) {
+    shortDrain(t, 0)
+    listener, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    stop := make(chan os.Signal, 1)
+    done := make(chan error, 1)
+    go func() {
+        done <- serve(listener, newRouter(), "", "", stop)
+    }()
+
+    res, err := http.Get("http://" + listener.Addr().String() + "/retornarStruct")
+    if err != nil {
+        t.Fatal(err)
+    }
+    res.Body.Close()
+
+    stop <- syscall.SIGTERM
+    select {
+    case err := <-done:
+        if err != nil {
+            t.Fatalf("serve() = %v, want nil", err)
+        }
+    case <-time.After(5 * time.Second):
+        t.Fatal("serve() did not return after the stop signal")
+    }
+}
+
+// selfSignedCert writes a certificate for 127.0.0.1 and its key to the
+// test's temporary directory, returning their paths and the certificate.
+func selfSignedCert(t *testing.T) (certFile, keyFile string, cert *x509.Certificate) {
+    key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+    if err != nil {
+        t.Fatal(err)
+    }
+    template := &x509.Certificate{
+        SerialNumber: big.NewInt(1),
+        Subject: pkix.Name{CommonName: "127.0.0.1"},
+        IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+        NotBefore: time.Now().Add(-time.Hour),
+        NotAfter: time.Now().Add(time.Hour),
+        KeyUsage: x509.KeyUsageDigitalSignature,
+        ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+    }
+    der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if cert, err = x509.ParseCertificate(der); err != nil {
+        t.Fatal(err)
+    }
+    keyDer, err := x509.MarshalECPrivateKey(key)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    dir := t.TempDir()
+    certFile = filepath.Join(dir, "cert.pem")
+    keyFile = filepath.Join(dir, "key.pem")
+    if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+        t.Fatal(err)
+    }
+    if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+        t.Fatal(err)
+    }
+    return certFile, keyFile, cert
+}
+
+// TestServeTLS serves with a self-signed certificate, checking that an
+// HTTPS request succeeds over HTTP/2.
+func TestServeTLS(t *testing.T) {
+    shortDrain(t, 0)
+    certFile, keyFile, cert := selfSignedCert(t)
+    listener, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    stop := make(chan os.Signal, 1)
+    done := make(chan error, 1)
+    go func() {
+        done <- serve(listener, newRouter(), certFile, keyFile, stop)
+    }()
+    defer func() {
+        stop <- syscall.SIGTERM
+        if err := <-done; err != nil {
+            t.Errorf("serve() = %v, want nil", err)
+        }
+    }()
+
+    roots := x509.NewCertPool()
+    roots.AddCert(cert)
+    client := &http.Client{Transport: &http.Transport{
+        TLSClientConfig: &tls.Config{RootCAs: roots},
+        ForceAttemptHTTP2: true,
+    }}
+    res, err := client.Get("https://" + listener.Addr().String() + "/healthz")
+    if err != nil {
+        t.Fatal(err)
+    }
+    res.Body.Close()
+
+    if res.StatusCode != http.StatusOK {
+        t.Errorf("GET /healthz over HTTPS status = %d, want %d", res.StatusCode, http.StatusOK)
+    }
+    if res.ProtoMajor != 2 {
+        t.Errorf("GET /healthz over HTTPS used %s, want HTTP/2", res.Proto)
+    }
+}
+
+// TestProxyTo registers a proxy to a mock upstream, checking that the
+// remaining path and query string are forwarded and safe headers are
+// copied back.
+func TestProxyTo(t *testing.T) {
+    var gotPath, gotQuery, gotHost string
+    upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        gotPath, gotQuery, gotHost = r.URL.Path, r.URL.RawQuery, r.Host
+        w.Header().Set("ETag", `"abc"`)
+        w.Header().Set("Set-Cookie", "session=secret")
+        w.Write([]byte(`{"ok":true}`))
+    }))
+    defer upstream.Close()
+
+    router := httprouter.New()
+    router.GET("/mock/*path", proxyTo(upstream.URL + "/api/"))
+    w := httptest.NewRecorder()
+    router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/mock/pokemon/pikachu?limit=5&offset=10", nil))
+
+    if w.Code != http.StatusOK || w.Body.String() != `{"ok":true}` {
+        t.Fatalf("GET /mock/pokemon/pikachu = %d %s, want 200 {\"ok\":true}", w.Code, w.Body)
+    }
+    if gotPath != "/api/pokemon/pikachu" || gotQuery != "limit=5&offset=10" {
+        t.Errorf("upstream got %s?%s, want /api/pokemon/pikachu?limit=5&offset=10", gotPath, gotQuery)
+    }
+    if gotHost != strings.TrimPrefix(upstream.URL, "http://") {
+        t.Errorf("upstream Host = %q, want %q", gotHost, strings.TrimPrefix(upstream.URL, "http://"))
+    }
+    if w.Header().Get("ETag") != `"abc"` || w.Header().Get("Set-Cookie") != "" {
+        t.Errorf("headers = %v, want ETag copied and Set-Cookie dropped", w.Header())
+    }
+}
+
+// TestProxyToPokeApi requests /pokeapi/..., checking that it reaches
+// PokéAPI under the configured base URL.
+func TestProxyToPokeApi(t *testing.T) {
+    var gotPath string
+    upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        gotPath = r.URL.Path
+        w.Write([]byte(`{}`))
+    }))
+    defer upstream.Close()
+    overrideConfig(t).PokeAPIBaseURL = upstream.URL + "/api/v2"
+
+    w := httptest.NewRecorder()
+    newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokeapi/type/fire", nil))
+    if w.Code != http.StatusOK || gotPath != "/api/v2/type/fire" {
+        t.Errorf("GET /pokeapi/type/fire = %d, upstream path %q, want 200 and /api/v2/type/fire", w.Code, gotPath)
+    }
+}
+
+// TestReturnJsonLiteralBody serves an upstream body containing formatting
+// verbs, checking it reaches the client unchanged.
+func TestReturnJsonLiteralBody(t *testing.T) {
+    body := `{"text":"%s %d %!"}`
+    upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Write([]byte(body))
+    }))
+    defer upstream.Close()
+
+    w := httptest.NewRecorder()
+    returnJson(upstream.URL, w, httptest.NewRequest(http.MethodGet, "/retornarUsuarioAleatorio", nil))
+
+    if w.Body.String() != body {
+        t.Fatalf("returnJson body = %q, want %q", w.Body.String(), body)
+    }
+}
+
+// TestDeletarPokemon deletes a stored Pokemon and then a missing one.
+func TestDeletarPokemon(t *testing.T) {
+    store = newPokemonStore()
+    store.add(Pokemon{"pikachu", 12})
+    router := newRouter()
+
+    for _, want := range []int{http.StatusNoContent, http.StatusNotFound} {
+        w := httptest.NewRecorder()
+        router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/pokemon/pikachu", nil))
+        if w.Code != want {
+            t.Fatalf("DELETE /pokemon/pikachu status = %d, want %d", w.Code, want)
+        }
+    }
+    if got := store.all(); len(got) != 0 {
+        t.Fatalf("store after delete = %v, want empty", got)
+    }
+}
+
+// putLevel sends a PUT /pokemon/name with the given level and If-Match
+// header, if any.
+func putLevel(router http.Handler, name, level, ifMatch string) *httptest.ResponseRecorder {
+    w := httptest.NewRecorder()
+    r := httptest.NewRequest(http.MethodPut, "/pokemon/" + name, strings.NewReader(`{"level":` + level + `}`))
+    if ifMatch != "" {
+        r.Header.Set("If-Match", ifMatch)
+    }
+    router.ServeHTTP(w, r)
+    return w
+}
+
+// TestAtualizarPokemon updates a stored Pokemon, checking the new level
+// and version are returned.
+func TestAtualizarPokemon(t *testing.T) {
+    store = newPokemonStore()
+    store.add(Pokemon{"pikachu", 12})
+
+    w := putLevel(newRouter(), "pikachu", "30", `"1"`)
+
+    if w.Code != http.StatusOK || w.Body.String() != `{"name":"pikachu","level":30}` {
+        t.Fatalf("PUT /pokemon/pikachu = %d %s, want 200 with level 30", w.Code, w.Body)
+    }
+    if got := w.Header().Get("ETag"); got != `"2"` {
+        t.Fatalf(`ETag = %s, want "2"`, got)
+    }
+}
+
+// TestAtualizarPokemonMissing updates a Pokemon that is not stored,
+// checking for a 404.
+func TestAtualizarPokemonMissing(t *testing.T) {
+    store = newPokemonStore()
+
+    if w := putLevel(newRouter(), "pikachu", "30", ""); w.Code != http.StatusNotFound {
+        t.Fatalf("PUT /pokemon/pikachu = %d, want %d", w.Code, http.StatusNotFound)
+    }
+}
+
+// TestAtualizarPokemonConflict updates a Pokemon twice with the same
+// If-Match, checking the second, stale update gets a 409.
+func TestAtualizarPokemonConflict(t *testing.T) {
+    store = newPokemonStore()
+    store.add(Pokemon{"pikachu", 12})
+    router := newRouter()
+
+    if w := putLevel(router, "pikachu", "30", `"1"`); w.Code != http.StatusOK {
+        t.Fatalf("first PUT = %d, want %d", w.Code, http.StatusOK)
+    }
+    if w := putLevel(router, "pikachu", "40", `"1"`); w.Code != http.StatusConflict {
+        t.Fatalf("stale PUT = %d, want %d", w.Code, http.StatusConflict)
+    }
+    if got := store.all()[0].Level; got != 30 {
+        t.Fatalf("level after the stale PUT = %d, want 30", got)
+    }
+}
+
+// TestReturnJsonStreams proxies a large upstream body, checking it arrives
+// intact with the upstream Content-Type.
+func TestReturnJsonStreams(t *testing.T) {
+    body := `{"data":"` + strings.Repeat("0123456789", 100000) + `"}`
+    upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("Content-Type", "application/json; charset=utf-8")
+        w.Write([]byte(body))
+    }))
+    defer upstream.Close()
+
+    w := httptest.NewRecorder()
+    returnJson(upstream.URL, w, httptest.NewRequest(http.MethodGet, "/retornarUsuarioAleatorio", nil))
+
+    if got := w.Header().Get("Content-Type"); got != "application/json; charset=utf-8" {
+        t.Errorf("Content-Type = %q, want the upstream one", got)
+    }
+    if w.Body.String() != body {
+        t.Errorf("body is %d bytes, want the %d upstream bytes", w.Body.Len(), len(body))
+    }
+}
+
+// TestErrorEnvelope triggers a few different errors, checking that each
+// reply has the same {"error", "status", "code"} shape.
+func TestErrorEnvelope(t *testing.T) {
+    store = newPokemonStore()
+    store.add(Pokemon{"pikachu", 5})
+
+    tests := []struct {
+        method, url, body string
+        want errorResponse
+    }{
+        {http.MethodGet, "/naoExiste", "", errorResponse{Error: "not found", Status: http.StatusNotFound}},
+        {http.MethodDelete, "/pokemon/ditto", "", errorResponse{Error: "pokemon not found", Status: http.StatusNotFound}},
+        {http.MethodPost, "/criarPokemon", `{"name":"pikachu"}`, errorResponse{Error: "pokemon already exists", Status: http.StatusConflict, Code: "pokemon_exists"}},
+        {http.MethodPost, "/message", `{"Body":"","Validate":true}`, errorResponse{Error: "validation failed", Status: http.StatusUnprocessableEntity, Code: "validation_failed", Fields: map[string]string{"Body": "is required"}}},
+    }
+    for _, tt := range tests {
+        w := httptest.NewRecorder()
+        newRouter().ServeHTTP(w, httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body)))
+
+        var got errorResponse
+        if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+            t.Fatalf("%s %s: %v", tt.method, tt.url, err)
+        }
+        if w.Code != tt.want.Status || w.Header().Get("Content-Type") != "application/json" || !reflect.DeepEqual(got, tt.want) {
+            t.Errorf("%s %s = %d %s %+v, want %d application/json %+v", tt.method, tt.url, w.Code, w.Header().Get("Content-Type"), got, tt.want.Status, tt.want)
+        }
+    }
+}
+
+// TestCacheControl checks the Cache-Control header of the Pokemon and
+// random user routes, including a configured max-age.
+func TestCacheControl(t *testing.T) {
+    server := newTestServer(t)
+    config.PokemonMaxAge = time.Hour
+
+    tests := []struct {
+        path, want string
+    }{
+        {"/retornarPokemon/pikachu", "public, max-age=3600"},
+        {"/retornarUsuarioAleatorio", "no-store"},
+        {"/user/simple", "no-store"},
+    }
+    for _, tt := range tests {
+        res, err := http.Get(server.URL + tt.path)
+        if err != nil {
+            t.Fatal(err)
+        }
+        res.Body.Close()
+        if got := res.Header.Get("Cache-Control"); got != tt.want {
+            t.Errorf("GET %s Cache-Control = %q, want %q", tt.path, got, tt.want)
+        }
+    }
+}
+
+// TestPrettyJson requests the same data compact and with ?pretty=true,
+// checking that only the formatting differs.
+func TestPrettyJson(t *testing.T) {
+    server := newTestServer(t)
+
+    for _, path := range []string{"/retornarStruct", "/retornarPokemon/pikachu", "/user/simple", "/pokemons/compare?a=pikachu&b=pikachu"} {
+        sep := "?"
+        if strings.Contains(path, "?") {
+            sep = "&"
+        }
+        var bodies [2]string
+        for i, query := range []string{"", sep + "pretty=true"} {
+            res, err := http.Get(server.URL + path + query)
+            if err != nil {
+                t.Fatal(err)
+            }
+            b, err := ioutil.ReadAll(res.Body)
+            res.Body.Close()
+            if err != nil {
+                t.Fatal(err)
+            }
+            bodies[i] = string(b)
+        }
+        compact, pretty := bodies[0], bodies[1]
+
+        if strings.Contains(compact, "\n") {
+            t.Errorf("GET %s = %s, want compact JSON", path, compact)
+        }
+        if !strings.Contains(pretty, "{\n  \"") {
+            t.Errorf("GET %s?pretty=true = %s, want JSON indented by two spaces", path, pretty)
+        }
+        var buf bytes.Buffer
+        if err := json.Compact(&buf, []byte(pretty)); err != nil || buf.String() != compact {
+            t.Errorf("GET %s?pretty=true compacted = %s, want %s", path, buf.String(), compact)
+        }
+    }
+}
+
+// TestNotFound requests a path no route matches, checking for a JSON 404.
+func TestNotFound(t *testing.T) {
+    w := httptest.NewRecorder()
+    newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/naoExiste", nil))
+
+    if w.Code != http.StatusNotFound || w.Body.String() != `{"error":"not found","status":404}` {
+        t.Fatalf("GET /naoExiste = %d %s, want 404 {\"error\":\"not found\",\"status\":404}", w.Code, w.Body)
+    }
+}
+
+// TestMethodNotAllowed uses the wrong method on an existing path, checking
+// for a JSON 405 listing the allowed methods.
+func TestMethodNotAllowed(t *testing.T) {
+    w := httptest.NewRecorder()
+    newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/retornarStruct", nil))
+
+    if w.Code != http.StatusMethodNotAllowed || w.Body.String() != `{"error":"method not allowed","status":405}` {
+        t.Fatalf("POST /retornarStruct = %d %s, want 405 {\"error\":\"method not allowed\",\"status\":405}", w.Code, w.Body)
+    }
+    if allow := w.Header().Get("Allow"); !strings.Contains(allow, http.MethodGet) {
+        t.Fatalf("Allow = %q, want it to contain GET", allow)
+    }
+}
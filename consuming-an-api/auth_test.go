@@ -0,0 +1,74 @@
+package main
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+)
+
+// TestApiKeyAuth sends mutating requests with no key, a wrong key and a
+// valid one, checking that only the last gets through.
+func TestApiKeyAuth(t *testing.T) {
+    overrideConfig(t).APIKeys = []string{"first", "second"}
+    store = newPokemonStore()
+
+    tests := []struct {
+        key string
+        want int
+    }{
+        {"", http.StatusUnauthorized},
+        {"wrong", http.StatusForbidden},
+        {"firs", http.StatusForbidden},
+        {"second", http.StatusCreated},
+    }
+    for _, tt := range tests {
+        w := httptest.NewRecorder()
+        r := httptest.NewRequest(http.MethodPost, "/criarPokemon", strings.NewReader(`{"name":"pikachu","level":5}`))
+        if tt.key != "" {
+            r.Header.Set("X-API-Key", tt.key)
+        }
+        newRouter().ServeHTTP(w, r)
+        if w.Code != tt.want {
+            t.Errorf("POST /criarPokemon with key %q status = %d, want %d", tt.key, w.Code, tt.want)
+        }
+    }
+}
+
+// TestApiKeyAuthRoutes checks that every mutating route wants a key while
+// reads stay public.
+func TestApiKeyAuthRoutes(t *testing.T) {
+    overrideConfig(t).APIKeys = []string{"secret"}
+    store = newPokemonStore()
+
+    tests := []struct {
+        method, path string
+        want int
+    }{
+        {http.MethodPost, "/criarPokemon", http.StatusUnauthorized},
+        {http.MethodPost, "/pokemons/bulk", http.StatusUnauthorized},
+        {http.MethodPut, "/pokemon/pikachu", http.StatusUnauthorized},
+        {http.MethodDelete, "/pokemon/pikachu", http.StatusUnauthorized},
+        {http.MethodGet, "/pokemons", http.StatusOK},
+    }
+    for _, tt := range tests {
+        w := httptest.NewRecorder()
+        newRouter().ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, strings.NewReader(`{}`)))
+        if w.Code != tt.want {
+            t.Errorf("%s %s without a key status = %d, want %d", tt.method, tt.path, w.Code, tt.want)
+        }
+    }
+}
+
+// TestApiKeyAuthDisabled checks that without configured keys the
+// mutating routes need none.
+func TestApiKeyAuthDisabled(t *testing.T) {
+    overrideConfig(t).APIKeys = nil
+    store = newPokemonStore()
+
+    w := httptest.NewRecorder()
+    newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/criarPokemon", strings.NewReader(`{"name":"pikachu"}`)))
+    if w.Code != http.StatusCreated {
+        t.Errorf("POST /criarPokemon without keys configured status = %d, want %d", w.Code, http.StatusCreated)
+    }
+}
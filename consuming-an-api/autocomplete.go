@@ -0,0 +1,46 @@
+package main
+
+import (
+    "net/http"
+    "strings"
+
+    "github.com/julienschmidt/httprouter"
+)
+
+// minAutocompleteQuery is the shortest prefix /pokemons/autocomplete
+// searches for.
+const minAutocompleteQuery = 2
+
+// pokeApiList mirrors the PokéAPI list of every Pokemon.
+type pokeApiList struct {
+    Results []namedResource `json:"results"`
+}
+
+// retornarAutocompletar lists the names of the Pokemon starting with ?q=,
+// up to config.AutocompleteMax of them. PokéAPI has no prefix search, so
+// the full list of names is fetched once, cached like any Pokemon, and
+// searched here.
+func retornarAutocompletar(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+    q := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("q")))
+    if len(q) < minAutocompleteQuery {
+        writeError(w, http.StatusBadRequest, "q must be at least 2 characters")
+        return
+    }
+
+    var list pokeApiList
+    if err := fetchPokeApiJson(r.Context(), config.PokeAPIBaseURL + "/pokemon?limit=10000", &list); err != nil {
+        writeHttpError(w, err)
+        return
+    }
+
+    names := make([]string, 0, config.AutocompleteMax)
+    for _, p := range list.Results {
+        if len(names) == config.AutocompleteMax {
+            break
+        }
+        if strings.HasPrefix(p.Name, q) {
+            names = append(names, p.Name)
+        }
+    }
+    writeJsonFor(w, r, http.StatusOK, names)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "testing"
+)
+
+// TestRetornarAutocompletar searches a few prefixes, checking the matches,
+// the cap and that the list is only fetched once.
+func TestRetornarAutocompletar(t *testing.T) {
+    calls := mockPokemonList(t)
+    config.AutocompleteMax = 3
+
+    tests := []struct {
+        q, want string
+    }{
+        {"pik", `["pikachu"]`},
+        {"PI", `["pidgey","pikachu","pidgeotto"]`},
+        {"rai", `["raichu"]`},
+        {"zu", `[]`},
+    }
+    for _, tt := range tests {
+        w := httptest.NewRecorder()
+        newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemons/autocomplete?q=" + tt.q, nil))
+        if w.Code != http.StatusOK || w.Body.String() != tt.want {
+            t.Errorf("GET /pokemons/autocomplete?q=%s = %d %s, want 200 %s", tt.q, w.Code, w.Body, tt.want)
+        }
+    }
+    if *calls != 1 {
+        t.Errorf("the Pokemon list was fetched %d times, want once", *calls)
+    }
+}
+
+// TestRetornarAutocompletarShort checks that prefixes under two
+// characters are rejected.
+func TestRetornarAutocompletarShort(t *testing.T) {
+    mockPokemonList(t)
+
+    for _, q := range []string{"", "p"} {
+        w := httptest.NewRecorder()
+        newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemons/autocomplete?q=" + q, nil))
+        if w.Code != http.StatusBadRequest {
+            t.Errorf("GET /pokemons/autocomplete?q=%s status = %d, want %d", q, w.Code, http.StatusBadRequest)
+        }
+    }
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+    "net/http"
+    "strings"
+    "sync"
+
+    "github.com/julienschmidt/httprouter"
+)
+
+// batchWorkers bounds how many upstream lookups one batch request runs at
+// the same time.
+var batchWorkers = 5
+
+// maxBatchNames is the most Pokemon a single batch request may ask for.
+const maxBatchNames = 50
+
+// batchNames parses the comma-separated names query parameter, dropping
+// blanks and duplicates.
+func batchNames(r *http.Request) ([]string, error) {
+    var names []string
+    seen := make(map[string]bool)
+    for _, name := range strings.Split(r.URL.Query().Get("names"), ",") {
+        name = strings.TrimSpace(name)
+        if name == "" || seen[name] {
+            continue
+        }
+        seen[name] = true
+        names = append(names, name)
+    }
+
+    if len(names) == 0 {
+        return nil, &httpError{http.StatusBadRequest, "names is required"}
+    }
+    if len(names) > maxBatchNames {
+        return nil, &httpError{http.StatusBadRequest, "too many names"}
+    }
+    return names, nil
+}
+
+// retornarPokemonsEmLote fetches every Pokemon in ?names= concurrently.
+// The reply maps each name to its Pokemon or to an {"error": ...} object,
+// so that one bad name does not fail the whole batch.
+func retornarPokemonsEmLote(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+    names, err := batchNames(r)
+    if err != nil {
+        writeHttpError(w, err)
+        return
+    }
+
+    jobs := make(chan string)
+    var mu sync.Mutex
+    results := make(map[string]interface{}, len(names))
+
+    var wg sync.WaitGroup
+    for i := 0; i < batchWorkers && i < len(names); i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for name := range jobs {
+                var result interface{}
+                pokemon, err := fetchPokemon(r.Context(), name)
+                if err != nil {
+                    result = map[string]string{"error": err.Error()}
+                } else {
+                    result = pokemon
+                }
+
+                mu.Lock()
+                results[name] = result
+                mu.Unlock()
+            }
+        }()
+    }
+    for _, name := range names {
+        jobs <- name
+    }
+    close(jobs)
+    wg.Wait()
+
+    writeJsonFor(w, r, http.StatusOK, results)
+}
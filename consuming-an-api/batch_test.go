@@ -0,0 +1,59 @@
+package main
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+)
+
+// TestRetornarPokemonsEmLote fetches three Pokemon in one batch, checking
+// that all of them appear in the reply.
+func TestRetornarPokemonsEmLote(t *testing.T) {
+    mockPokeApi(t, "pikachu", "ditto", "eevee")
+
+    w := httptest.NewRecorder()
+    newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemons/batch?names=pikachu,ditto,eevee", nil))
+    if w.Code != http.StatusOK {
+        t.Fatalf("GET /pokemons/batch status = %d, want %d", w.Code, http.StatusOK)
+    }
+
+    var results map[string]PokemonResponse
+    if err := json.Unmarshal(w.Body.Bytes(), &results); err != nil {
+        t.Fatal(err)
+    }
+    for _, name := range []string{"pikachu", "ditto", "eevee"} {
+        if results[name].Name != name {
+            t.Errorf("batch result for %s = %+v, want that Pokemon", name, results[name])
+        }
+    }
+}
+
+// TestRetornarPokemonsEmLotePartial checks that a failing name gets an
+// error entry while the others are still returned.
+func TestRetornarPokemonsEmLotePartial(t *testing.T) {
+    mockPokeApi(t, "pikachu")
+
+    w := httptest.NewRecorder()
+    newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemons/batch?names=pikachu,missingno", nil))
+
+    var results map[string]map[string]interface{}
+    if err := json.Unmarshal(w.Body.Bytes(), &results); err != nil {
+        t.Fatal(err)
+    }
+    if results["pikachu"]["name"] != "pikachu" {
+        t.Errorf("batch result for pikachu = %v, want the Pokemon", results["pikachu"])
+    }
+    if _, ok := results["missingno"]["error"]; !ok {
+        t.Errorf("batch result for missingno = %v, want an error entry", results["missingno"])
+    }
+}
+
+// TestRetornarPokemonsEmLoteNoNames checks that an empty batch is a 400.
+func TestRetornarPokemonsEmLoteNoNames(t *testing.T) {
+    w := httptest.NewRecorder()
+    newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemons/batch?names=,", nil))
+    if w.Code != http.StatusBadRequest {
+        t.Fatalf("GET /pokemons/batch?names=, status = %d, want %d", w.Code, http.StatusBadRequest)
+    }
+}
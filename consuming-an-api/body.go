@@ -0,0 +1,28 @@
+package main
+
+import (
+    "errors"
+    "net/http"
+
+    "github.com/julienschmidt/httprouter"
+)
+
+// limitBody caps the size of the request bodies next may read at n bytes,
+// so that a huge upload cannot exhaust our memory while being decoded.
+func limitBody(n int64, next httprouter.Handle) httprouter.Handle {
+    return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+        r.Body = http.MaxBytesReader(w, r.Body, n)
+        next(w, r, ps)
+    }
+}
+
+// writeDecodeError replies to a request whose body could not be decoded,
+// with a 413 if it was over the size limit and a 400 otherwise.
+func writeDecodeError(w http.ResponseWriter, err error) {
+    var tooLarge *http.MaxBytesError
+    if errors.As(err, &tooLarge) {
+        writeError(w, http.StatusRequestEntityTooLarge, "request body too large")
+        return
+    }
+    writeError(w, http.StatusBadRequest, "invalid JSON body: " + err.Error())
+}
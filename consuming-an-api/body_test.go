@@ -0,0 +1,45 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+)
+
+// TestLimitBody posts bodies over the size limit, checking for a 413.
+func TestLimitBody(t *testing.T) {
+    overrideConfig(t).MaxBodyBytes = 64
+    store = newPokemonStore()
+    body := `{"name":"` + strings.Repeat("a", 100) + `","level":1}`
+
+    for _, path := range []string{"/criarPokemon", "/message"} {
+        w := httptest.NewRecorder()
+        newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+        if w.Code != http.StatusRequestEntityTooLarge {
+            t.Errorf("POST %s with a %d byte body = %d, want %d", path, len(body), w.Code, http.StatusRequestEntityTooLarge)
+        }
+    }
+}
+
+// TestWriteDecodeError checks that only a *http.MaxBytesError, wrapped or
+// not, is a 413, however the error reads.
+func TestWriteDecodeError(t *testing.T) {
+    tests := []struct {
+        err error
+        want int
+    }{
+        {&http.MaxBytesError{Limit: 64}, http.StatusRequestEntityTooLarge},
+        {fmt.Errorf("reading body: %w", &http.MaxBytesError{Limit: 64}), http.StatusRequestEntityTooLarge},
+        {errors.New("http: request body too large"), http.StatusBadRequest},
+    }
+    for _, tt := range tests {
+        w := httptest.NewRecorder()
+        writeDecodeError(w, tt.err)
+        if w.Code != tt.want {
+            t.Errorf("writeDecodeError(%v) = %d, want %d", tt.err, w.Code, tt.want)
+        }
+    }
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+    "context"
+    "net/http"
+    "sync"
+    "time"
+)
+
+// circuitBreaker stops calling an upstream that keeps failing. After
+// threshold consecutive failures it opens and rejects calls for cooldown,
+// then lets a single probe through: a successful probe closes it again, a
+// failed one reopens it.
+type circuitBreaker struct {
+    threshold int
+    cooldown time.Duration
+
+    mu sync.Mutex
+    failures int
+    openedAt time.Time
+    probing bool
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+    return &circuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// pokeApiBreaker guards the calls to PokéAPI.
+var pokeApiBreaker = newCircuitBreaker(defaultConfig.BreakerThreshold, defaultConfig.BreakerCooldown)
+
+var errBreakerOpen = &httpError{http.StatusServiceUnavailable, "upstream temporarily unavailable"}
+
+// allow reports whether a call may go upstream now.
+func (b *circuitBreaker) allow() bool {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+
+    if b.failures < b.threshold {
+        return true
+    }
+    if b.probing || time.Since(b.openedAt) < b.cooldown {
+        return false
+    }
+    b.probing = true
+    return true
+}
+
+// record updates the breaker with the outcome of an allowed call.
+func (b *circuitBreaker) record(success bool) {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+
+    b.probing = false
+    if success {
+        b.failures = 0
+        return
+    }
+    b.failures++
+    if b.failures >= b.threshold {
+        b.openedAt = time.Now()
+    }
+}
+
+// call runs fn through the breaker. Only server-side failures count
+// against the upstream: a 404 is a healthy answer, and a call abandoned
+// because our own client went away, or never sent because all upstream
+// slots were taken, says nothing about the upstream.
+func (b *circuitBreaker) call(ctx context.Context, fn func() error) error {
+    if !b.allow() {
+        return errBreakerOpen
+    }
+
+    err := fn()
+    if ctx.Err() != nil || err == errUpstreamBusy {
+        b.mu.Lock()
+        b.probing = false
+        b.mu.Unlock()
+        return err
+    }
+    he, ok := err.(*httpError)
+    b.record(err == nil || ok && he.status < 500)
+    return err
+}
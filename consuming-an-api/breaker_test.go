@@ -0,0 +1,66 @@
+package main
+
+import (
+    "context"
+    "net/http"
+    "net/http/httptest"
+    "sync/atomic"
+    "testing"
+    "time"
+)
+
+// TestCircuitBreakerOpens drives PokéAPI failures until the breaker opens,
+// checking that further lookups fail fast without reaching the upstream.
+func TestCircuitBreakerOpens(t *testing.T) {
+    fastRetries(t)
+    var calls int64
+    usePokeApi(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        atomic.AddInt64(&calls, 1)
+        w.WriteHeader(http.StatusInternalServerError)
+    }))
+    pokeApiBreaker = newCircuitBreaker(2, time.Minute)
+    defer func() { pokeApiBreaker = newCircuitBreaker(defaultConfig.BreakerThreshold, defaultConfig.BreakerCooldown) }()
+
+    for i := 0; i < 2; i++ {
+        if _, err := fetchPokemon(context.Background(), "pikachu"); err == nil {
+            t.Fatal("fetchPokemon against a failing upstream succeeded")
+        }
+    }
+    before := atomic.LoadInt64(&calls)
+
+    _, err := fetchPokemon(context.Background(), "pikachu")
+    if err != errBreakerOpen {
+        t.Fatalf("fetchPokemon with the breaker open = %v, want %v", err, errBreakerOpen)
+    }
+    if after := atomic.LoadInt64(&calls); after != before {
+        t.Fatalf("upstream called %d more times with the breaker open, want 0", after - before)
+    }
+
+    w := httptest.NewRecorder()
+    newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/retornarPokemon/pikachu", nil))
+    if w.Code != http.StatusServiceUnavailable {
+        t.Fatalf("GET /retornarPokemon/pikachu with the breaker open = %d, want %d", w.Code, http.StatusServiceUnavailable)
+    }
+}
+
+// TestCircuitBreakerProbe checks that after the cooldown a single probe is
+// let through and that its success closes the breaker.
+func TestCircuitBreakerProbe(t *testing.T) {
+    b := newCircuitBreaker(1, 10 * time.Millisecond)
+    b.record(false)
+    if b.allow() {
+        t.Fatal("allow() right after opening = true, want false")
+    }
+
+    time.Sleep(20 * time.Millisecond)
+    if !b.allow() {
+        t.Fatal("allow() after the cooldown = false, want a probe")
+    }
+    if b.allow() {
+        t.Fatal("allow() while probing = true, want false")
+    }
+    b.record(true)
+    if !b.allow() {
+        t.Fatal("allow() after a successful probe = false, want true")
+    }
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+    "bytes"
+    "encoding/json"
+    "net/http"
+
+    "github.com/julienschmidt/httprouter"
+)
+
+// bulkResult reports what happened to one item of a bulk create.
+type bulkResult struct {
+    Index int `json:"index"`
+    Name string `json:"name,omitempty"`
+    Status int `json:"status"`
+    Error string `json:"error,omitempty"`
+    // Fields lists what is wrong with each field of an item that does
+    // not match pokemonSchema.
+    Fields map[string]string `json:"fields,omitempty"`
+}
+
+// criarPokemonsEmLote creates every Pokemon of a JSON array. Items are
+// checked against pokemonSchema and stored independently, so the reply
+// is a 207 Multi-Status listing the outcome of each one in order.
+func criarPokemonsEmLote(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+    var items []json.RawMessage
+    if err := json.NewDecoder(r.Body).Decode(&items); err != nil {
+        writeDecodeError(w, err)
+        return
+    }
+    if len(items) == 0 {
+        writeError(w, http.StatusBadRequest, "no pokemon given")
+        return
+    }
+
+    results := make([]bulkResult, len(items))
+    for i, item := range items {
+        var p Pokemon
+        failed, err := decodeValidated(bytes.NewReader(item), pokemonSchema, &p)
+        results[i] = bulkResult{Index: i, Name: p.Name, Status: http.StatusCreated}
+        switch {
+        case err != nil:
+            results[i].Status = http.StatusBadRequest
+            results[i].Error = err.Error()
+        case len(failed) > 0:
+            results[i].Status = http.StatusUnprocessableEntity
+            results[i].Error = "validation failed"
+            results[i].Fields = failed
+        default:
+            if err := validatePokemon(p); err != nil {
+                results[i].Status = http.StatusBadRequest
+                results[i].Error = err.Error()
+            } else if err := store.add(p); err != nil {
+                results[i].Status = http.StatusConflict
+                results[i].Error = err.Error()
+            }
+        }
+    }
+    writeJsonFor(w, r, http.StatusMultiStatus, results)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+// postBulk sends body to POST /pokemons/bulk and decodes the results.
+func postBulk(t *testing.T, body string) (int, []bulkResult) {
+    w := httptest.NewRecorder()
+    newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/pokemons/bulk", strings.NewReader(body)))
+
+    var results []bulkResult
+    if w.Code == http.StatusMultiStatus {
+        if err := json.Unmarshal(w.Body.Bytes(), &results); err != nil {
+            t.Fatal(err)
+        }
+    }
+    return w.Code, results
+}
+
+// TestCriarPokemonsEmLote creates an all-valid array, checking every item
+// is reported and stored.
+func TestCriarPokemonsEmLote(t *testing.T) {
+    store = newPokemonStore()
+
+    status, results := postBulk(t, `[{"name":"bulbasaur","level":5},{"name":"charmander","level":6}]`)
+
+    want := []bulkResult{
+        {Index: 0, Name: "bulbasaur", Status: http.StatusCreated},
+        {Index: 1, Name: "charmander", Status: http.StatusCreated},
+    }
+    if status != http.StatusMultiStatus || !reflect.DeepEqual(results, want) {
+        t.Fatalf("POST /pokemons/bulk = %d %+v, want 207 %+v", status, results, want)
+    }
+    if n := len(store.all()); n != 2 {
+        t.Fatalf("store has %d Pokemon, want 2", n)
+    }
+}
+
+// TestCriarPokemonsEmLoteMixed creates an array with invalid items,
+// checking that only the valid ones are stored.
+func TestCriarPokemonsEmLoteMixed(t *testing.T) {
+    store = newPokemonStore()
+    store.add(Pokemon{"pikachu", 1})
+
+    status, results := postBulk(t, `[{"name":"eevee","level":3},{"level":4},{"name":"pikachu"}]`)
+
+    want := []bulkResult{
+        {Index: 0, Name: "eevee", Status: http.StatusCreated},
+        {Index: 1, Status: http.StatusBadRequest, Error: errNameRequired.Error()},
+        {Index: 2, Name: "pikachu", Status: http.StatusConflict, Error: errPokemonExists.Error()},
+    }
+    if status != http.StatusMultiStatus || !reflect.DeepEqual(results, want) {
+        t.Fatalf("POST /pokemons/bulk = %d %+v, want 207 %+v", status, results, want)
+    }
+    if n := len(store.all()); n != 2 {
+        t.Fatalf("store has %d Pokemon, want 2", n)
+    }
+}
+
+// TestCriarPokemonsEmLoteSchema creates an array with items breaking
+// pokemonSchema, checking that each is reported with its fields while the
+// rest of the batch is stored.
+func TestCriarPokemonsEmLoteSchema(t *testing.T) {
+    store = newPokemonStore()
+
+    long := strings.Repeat("a", 100)
+    status, results := postBulk(t, `[{"name":"eevee","level":300},{"name":"` + long + `"},{"name":"mew","shiny":true},{"name":"ditto","level":1}]`)
+
+    want := []bulkResult{
+        {Index: 0, Name: "eevee", Status: http.StatusUnprocessableEntity, Error: "validation failed", Fields: map[string]string{"level": "must be at most 127"}},
+        {Index: 1, Name: long, Status: http.StatusUnprocessableEntity, Error: "validation failed", Fields: map[string]string{"name": "must be at most 64 characters"}},
+        {Index: 2, Name: "mew", Status: http.StatusUnprocessableEntity, Error: "validation failed", Fields: map[string]string{"shiny": "is not allowed"}},
+        {Index: 3, Name: "ditto", Status: http.StatusCreated},
+    }
+    if status != http.StatusMultiStatus || !reflect.DeepEqual(results, want) {
+        t.Fatalf("POST /pokemons/bulk = %d %+v, want 207 %+v", status, results, want)
+    }
+    if all := store.all(); len(all) != 1 || all[0].Name != "ditto" {
+        t.Fatalf("store = %v, want only ditto", all)
+    }
+}
+
+// TestCriarPokemonsEmLoteEmpty posts an empty array, checking for a 400.
+func TestCriarPokemonsEmLoteEmpty(t *testing.T) {
+    if status, _ := postBulk(t, `[]`); status != http.StatusBadRequest {
+        t.Fatalf("POST /pokemons/bulk [] = %d, want %d", status, http.StatusBadRequest)
+    }
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+    "context"
+    "net/http"
+    "strconv"
+
+    "github.com/julienschmidt/httprouter"
+)
+
+// namedResource is a reference to another PokéAPI resource.
+type namedResource struct {
+    Name string `json:"name"`
+}
+
+// pokeApiType mirrors the parts of the PokéAPI type payload we decode.
+type pokeApiType struct {
+    Pokemon []struct {
+        Pokemon namedResource `json:"pokemon"`
+    } `json:"pokemon"`
+    DamageRelations struct {
+        DoubleDamageTo []namedResource `json:"double_damage_to"`
+        HalfDamageTo []namedResource `json:"half_damage_to"`
+        NoDamageTo []namedResource `json:"no_damage_to"`
+    } `json:"damage_relations"`
+}
+
+// fetchType looks up the named type on PokéAPI.
+func fetchType(ctx context.Context, name string) (pokeApiType, error) {
+    var t pokeApiType
+    err := fetchPokeApiJson(ctx, config.PokeAPIBaseURL + "/type/" + name, &t)
+    if he, ok := err.(*httpError); ok && he.status == http.StatusNotFound {
+        return pokeApiType{}, &httpError{http.StatusNotFound, "type not found"}
+    }
+    return t, err
+}
+
+// queryLimit parses the optional ?limit= of a listing, returning -1 when
+// there is none.
+func queryLimit(r *http.Request) (int, error) {
+    s := r.URL.Query().Get("limit")
+    if s == "" {
+        return -1, nil
+    }
+    n, err := strconv.Atoi(s)
+    if err != nil || n < 0 {
+        return 0, &httpError{http.StatusBadRequest, "limit must be a non-negative integer"}
+    }
+    return n, nil
+}
+
+// retornarPokemonsPorTipo lists the names of every Pokemon of a type,
+// capped at ?limit= when given.
+func retornarPokemonsPorTipo(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+    limit, err := queryLimit(r)
+    if err != nil {
+        writeHttpError(w, err)
+        return
+    }
+
+    raw, err := fetchType(r.Context(), ps.ByName("type"))
+    if err != nil {
+        writeHttpError(w, err)
+        return
+    }
+
+    names := make([]string, 0, len(raw.Pokemon))
+    for _, p := range raw.Pokemon {
+        if limit >= 0 && len(names) == limit {
+            break
+        }
+        names = append(names, p.Pokemon.Name)
+    }
+    writeJsonFor(w, r, http.StatusOK, names)
+}
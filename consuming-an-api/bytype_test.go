@@ -0,0 +1,54 @@
+package main
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "testing"
+)
+
+// TestRetornarPokemonsPorTipo lists a recorded type payload, with and
+// without a limit.
+func TestRetornarPokemonsPorTipo(t *testing.T) {
+    pokeApiFixture(t, "type-electric.json")
+
+    tests := []struct {
+        url string
+        want string
+    }{
+        {"/pokemons/by-type/electric", `["pikachu","raichu","magnemite"]`},
+        {"/pokemons/by-type/electric?limit=2", `["pikachu","raichu"]`},
+        {"/pokemons/by-type/electric?limit=10", `["pikachu","raichu","magnemite"]`},
+        {"/pokemons/by-type/electric?limit=0", `[]`},
+    }
+    for _, tt := range tests {
+        w := httptest.NewRecorder()
+        newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.url, nil))
+        if w.Code != http.StatusOK || w.Body.String() != tt.want {
+            t.Errorf("GET %s = %d %s, want %d %s", tt.url, w.Code, w.Body, http.StatusOK, tt.want)
+        }
+    }
+}
+
+// TestRetornarPokemonsPorTipoBadLimit checks that a malformed limit is
+// rejected.
+func TestRetornarPokemonsPorTipoBadLimit(t *testing.T) {
+    pokeApiFixture(t, "type-electric.json")
+
+    w := httptest.NewRecorder()
+    newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemons/by-type/electric?limit=-1", nil))
+    if w.Code != http.StatusBadRequest {
+        t.Errorf("GET with limit=-1 status = %d, want %d", w.Code, http.StatusBadRequest)
+    }
+}
+
+// TestRetornarPokemonsPorTipoUnknown checks that a type PokéAPI does not
+// know is a 404.
+func TestRetornarPokemonsPorTipoUnknown(t *testing.T) {
+    usePokeApi(t, http.NotFoundHandler())
+
+    w := httptest.NewRecorder()
+    newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemons/by-type/shadow", nil))
+    if w.Code != http.StatusNotFound {
+        t.Errorf("GET /pokemons/by-type/shadow status = %d, want %d", w.Code, http.StatusNotFound)
+    }
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+    "container/list"
+    "net/http"
+    "sort"
+    "sync"
+    "sync/atomic"
+    "time"
+
+    "github.com/julienschmidt/httprouter"
+)
+
+const defaultCacheTTL = time.Hour
+
+// responseCache holds raw upstream response bodies for a fixed time. With
+// a max above zero it holds at most max entries, evicting the least
+// recently used one to make room; expired entries stay until evicted or
+// replaced, so that they can still be served stale.
+type responseCache struct {
+    // hits and misses are accessed atomically and kept first so they are
+    // 64-bit aligned on 32-bit platforms.
+    hits int64
+    misses int64
+
+    ttl time.Duration
+    max int
+    mu sync.Mutex
+    entries map[string]*list.Element
+    // order holds the *cacheEntry values, most recently used first.
+    order *list.List
+}
+
+type cacheEntry struct {
+    key string
+    data []byte
+    stored time.Time
+    expires time.Time
+}
+
+// cacheStats is the JSON representation of the cache counters.
+type cacheStats struct {
+    Hits int64 `json:"hits"`
+    Misses int64 `json:"misses"`
+}
+
+func newResponseCache(ttl time.Duration, max int) *responseCache {
+    return &responseCache{ttl: ttl, max: max, entries: make(map[string]*list.Element), order: list.New()}
+}
+
+// pokemonCache caches PokéAPI responses keyed by Pokemon name.
+var pokemonCache = newResponseCache(defaultCacheTTL, defaultConfig.CacheMaxEntries)
+
+// get returns the data stored under key if it has not expired yet.
+func (c *responseCache) get(key string) ([]byte, bool) {
+    c.mu.Lock()
+    var entry *cacheEntry
+    if e, ok := c.entries[key]; ok {
+        entry = e.Value.(*cacheEntry)
+        c.order.MoveToFront(e)
+    }
+    c.mu.Unlock()
+
+    if entry == nil || time.Now().After(entry.expires) {
+        atomic.AddInt64(&c.misses, 1)
+        return nil, false
+    }
+    atomic.AddInt64(&c.hits, 1)
+    return entry.data, true
+}
+
+// getStale returns the data stored under key even if it has expired. It
+// is the fallback for when the upstream cannot be reached, so it leaves
+// the hit and miss counters alone.
+func (c *responseCache) getStale(key string) ([]byte, bool) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    e, ok := c.entries[key]
+    if !ok {
+        return nil, false
+    }
+    c.order.MoveToFront(e)
+    return e.Value.(*cacheEntry).data, true
+}
+
+// set stores data under key for the cache's TTL, evicting the least
+// recently used entry if the cache is full.
+func (c *responseCache) set(key string, data []byte) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    now := time.Now()
+    entry := &cacheEntry{key, data, now, now.Add(c.ttl)}
+    if e, ok := c.entries[key]; ok {
+        e.Value = entry
+        c.order.MoveToFront(e)
+        return
+    }
+    c.entries[key] = c.order.PushFront(entry)
+    if c.max > 0 && c.order.Len() > c.max {
+        oldest := c.order.Back()
+        c.order.Remove(oldest)
+        delete(c.entries, oldest.Value.(*cacheEntry).key)
+    }
+}
+
+// size returns how many entries the cache holds, expired ones included.
+func (c *responseCache) size() int {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    return c.order.Len()
+}
+
+// cachedKey describes a live cache entry for /debug/cache, without its
+// data.
+type cachedKey struct {
+    Key string `json:"key"`
+    StoredAt time.Time `json:"stored_at"`
+    TTLSeconds float64 `json:"ttl_seconds"`
+}
+
+// keys lists the entries that have not expired, sorted by key.
+func (c *responseCache) keys() []cachedKey {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    now := time.Now()
+    keys := make([]cachedKey, 0, len(c.entries))
+    for e := c.order.Front(); e != nil; e = e.Next() {
+        entry := e.Value.(*cacheEntry)
+        if now.After(entry.expires) {
+            continue
+        }
+        keys = append(keys, cachedKey{entry.key, entry.stored, entry.expires.Sub(now).Seconds()})
+    }
+    sort.Slice(keys, func(i, j int) bool { return keys[i].Key < keys[j].Key })
+    return keys
+}
+
+// retornarCacheDebug lists what the PokéAPI cache holds and for how much
+// longer. Keys are Pokemon names, or the URL of other PokéAPI resources.
+func retornarCacheDebug(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+    writeJsonFor(w, r, http.StatusOK, pokemonCache.keys())
+}
+
+func (c *responseCache) stats() cacheStats {
+    return cacheStats{
+        Hits: atomic.LoadInt64(&c.hits),
+        Misses: atomic.LoadInt64(&c.misses),
+    }
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync/atomic"
+    "testing"
+    "time"
+)
+
+// TestCacheExpiry checks that entries are served until their TTL passes.
+func TestCacheExpiry(t *testing.T) {
+    c := newResponseCache(20 * time.Millisecond, 0)
+    c.set("ditto", []byte(`{}`))
+
+    if _, ok := c.get("ditto"); !ok {
+        t.Fatal("get(ditto) right after set = miss, want hit")
+    }
+    time.Sleep(30 * time.Millisecond)
+    if _, ok := c.get("ditto"); ok {
+        t.Fatal("get(ditto) after TTL = hit, want miss")
+    }
+    if s := c.stats(); s.Hits != 1 || s.Misses != 1 {
+        t.Fatalf("stats() = %+v, want 1 hit and 1 miss", s)
+    }
+}
+
+// TestRetornarPokemonCached requests the same Pokemon twice, checking that
+// the upstream is only called once.
+func TestRetornarPokemonCached(t *testing.T) {
+    var calls int64
+    usePokeApi(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        atomic.AddInt64(&calls, 1)
+        w.Write([]byte(`{"name":"pikachu","id":25}`))
+    }))
+
+    router := newRouter()
+    for i := 0; i < 2; i++ {
+        w := httptest.NewRecorder()
+        router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/retornarPokemon/pikachu", nil))
+        if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"name":"pikachu"`) {
+            t.Fatalf("request %d = %d %q, want 200 with pikachu", i, w.Code, w.Body.String())
+        }
+    }
+
+    if calls != 1 {
+        t.Fatalf("upstream called %d times, want 1", calls)
+    }
+    if s := pokemonCache.stats(); s.Hits != 1 || s.Misses != 1 {
+        t.Fatalf("stats() = %+v, want 1 hit and 1 miss", s)
+    }
+}
+
+// TestRetornarPokemonStale caches a Pokemon, lets it expire and makes
+// PokéAPI fail, checking that the stale copy is served with a Warning.
+func TestRetornarPokemonStale(t *testing.T) {
+    fastRetries(t)
+    failing := int32(0)
+    usePokeApi(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if atomic.LoadInt32(&failing) == 1 {
+            http.Error(w, "down", http.StatusInternalServerError)
+            return
+        }
+        w.Write([]byte(`{"name":"ditto","id":132}`))
+    }))
+    usePokemonCache(t, 10 * time.Millisecond)
+    pokeApiBreaker = newCircuitBreaker(defaultConfig.BreakerThreshold, defaultConfig.BreakerCooldown)
+
+    get := func() *httptest.ResponseRecorder {
+        w := httptest.NewRecorder()
+        newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/retornarPokemon/ditto", nil))
+        return w
+    }
+    if w := get(); w.Code != http.StatusOK || w.Header().Get("Warning") != "" {
+        t.Fatalf("first GET = %d, Warning %q, want a fresh 200", w.Code, w.Header().Get("Warning"))
+    }
+    time.Sleep(20 * time.Millisecond)
+    atomic.StoreInt32(&failing, 1)
+
+    w := get()
+    if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"ditto"`) {
+        t.Fatalf("GET with PokéAPI down = %d %s, want the stale ditto", w.Code, w.Body)
+    }
+    if warning := w.Header().Get("Warning"); !strings.HasPrefix(warning, "110 ") {
+        t.Errorf("Warning = %q, want a 110 warning", warning)
+    }
+
+    config.StaleIfError = false
+    if w := get(); w.Code != http.StatusBadGateway {
+        t.Errorf("GET with PokéAPI down and StaleIfError off = %d, want %d", w.Code, http.StatusBadGateway)
+    }
+}
+
+// TestRetornarCacheDebug fills the cache, checking that /debug/cache lists
+// the live keys with time left and leaves out the expired ones.
+func TestRetornarCacheDebug(t *testing.T) {
+    overrideConfig(t).APIKeys = []string{"secret"}
+    usePokemonCache(t, time.Minute)
+    pokemonCache.set("pikachu", []byte(`{}`))
+    pokemonCache.set("ditto", []byte(`{}`))
+    pokemonCache.set("mew", []byte(`{}`))
+    pokemonCache.entries["mew"].Value.(*cacheEntry).expires = time.Now().Add(-time.Minute)
+
+    r := httptest.NewRequest(http.MethodGet, "/debug/cache", nil)
+    w := httptest.NewRecorder()
+    newRouter().ServeHTTP(w, r)
+    if w.Code != http.StatusUnauthorized {
+        t.Fatalf("GET /debug/cache without a key = %d, want %d", w.Code, http.StatusUnauthorized)
+    }
+
+    r.Header.Set("X-API-Key", "secret")
+    w = httptest.NewRecorder()
+    newRouter().ServeHTTP(w, r)
+    var keys []cachedKey
+    if err := json.Unmarshal(w.Body.Bytes(), &keys); err != nil {
+        t.Fatalf("GET /debug/cache = %d %s: %v", w.Code, w.Body, err)
+    }
+    if len(keys) != 2 || keys[0].Key != "ditto" || keys[1].Key != "pikachu" {
+        t.Fatalf("keys = %+v, want ditto and pikachu", keys)
+    }
+    for _, k := range keys {
+        if k.TTLSeconds <= 0 || k.TTLSeconds > 60 || k.StoredAt.IsZero() {
+            t.Errorf("%s: stored_at %s, ttl_seconds %v, want a stored time and up to a minute left", k.Key, k.StoredAt, k.TTLSeconds)
+        }
+    }
+    if strings.Contains(w.Body.String(), "data") {
+        t.Errorf("body %s includes the cached data", w.Body)
+    }
+}
+
+// TestCacheEviction fills a cache of two past its size, checking that
+// the least recently used entry is the one evicted.
+func TestCacheEviction(t *testing.T) {
+    c := newResponseCache(time.Minute, 2)
+    c.set("pikachu", []byte(`{}`))
+    c.set("ditto", []byte(`{}`))
+    c.get("pikachu")
+    c.set("mew", []byte(`{}`))
+
+    if _, ok := c.get("ditto"); ok {
+        t.Error("ditto is still cached, want it evicted as the least recently used")
+    }
+    for _, key := range []string{"pikachu", "mew"} {
+        if _, ok := c.get(key); !ok {
+            t.Errorf("%s was evicted, want it kept", key)
+        }
+    }
+    if n := c.size(); n != 2 {
+        t.Errorf("size = %d, want 2", n)
+    }
+}
+
+// TestCacheEntriesMetric checks that /metrics reports the cache size.
+func TestCacheEntriesMetric(t *testing.T) {
+    usePokemonCache(t, time.Minute)
+    pokemonCache.set("pikachu", []byte(`{}`))
+
+    w := httptest.NewRecorder()
+    newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+    var s metricsSnapshot
+    if err := json.Unmarshal(w.Body.Bytes(), &s); err != nil {
+        t.Fatal(err)
+    }
+    if s.CacheEntries != 1 {
+        t.Errorf("cache_entries = %d, want 1", s.CacheEntries)
+    }
+}
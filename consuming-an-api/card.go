@@ -0,0 +1,100 @@
+package main
+
+import (
+    "bytes"
+    _ "embed"
+    "encoding/xml"
+    "net/http"
+    "strings"
+    "text/template"
+
+    "github.com/julienschmidt/httprouter"
+)
+
+// maxBaseStat is the highest base stat a Pokemon has, which fills a
+// whole bar on the card.
+const maxBaseStat = 255
+
+// cardBarWidth is the width of a full stat bar, in pixels.
+const cardBarWidth = 130
+
+//go:embed card.svg
+var cardSVG string
+
+// cardTemplate renders /pokemon/:nome/card.svg. text/template does not
+// escape, so every string from PokéAPI goes through xml.
+var cardTemplate = template.Must(template.New("card").Funcs(template.FuncMap{"xml": xmlEscape}).Parse(cardSVG))
+
+// xmlEscape escapes s for use in XML text and attributes.
+func xmlEscape(s string) string {
+    var b strings.Builder
+    xml.EscapeText(&b, []byte(s))
+    return b.String()
+}
+
+// cardStat is a stat bar on the card, laid out in pixels.
+type cardStat struct {
+    Name string
+    Value int
+    Y int
+    BarY int
+    Width int
+    LabelX int
+}
+
+// cardData is what cardTemplate renders.
+type cardData struct {
+    Name string
+    ID int
+    Types []string
+    Stats []cardStat
+    Height int
+    Inner int
+}
+
+// newCardData lays out the card of pokemon.
+func newCardData(pokemon pokeApiPokemon) cardData {
+    card := cardData{Name: pokemon.Name, ID: pokemon.ID}
+    for _, t := range pokemon.Types {
+        card.Types = append(card.Types, t.Type.Name)
+    }
+    y := 100
+    for _, s := range pokemon.Stats {
+        value := s.BaseStat
+        if value > maxBaseStat {
+            value = maxBaseStat
+        }
+        width := value * cardBarWidth / maxBaseStat
+        card.Stats = append(card.Stats, cardStat{
+            Name: s.Stat.Name,
+            Value: s.BaseStat,
+            Y: y,
+            BarY: y - 9,
+            Width: width,
+            LabelX: 126 + width,
+        })
+        y += 22
+    }
+    card.Height = y
+    card.Inner = y - 4
+    return card
+}
+
+// retornarCarta renders a Pokemon as an SVG trading card with its name,
+// id, types and base stat bars.
+func retornarCarta(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+    raw, err := fetchRawPokemon(r.Context(), ps.ByName("nome"))
+    if err != nil {
+        writeHttpError(w, err)
+        return
+    }
+
+    var buf bytes.Buffer
+    if err := cardTemplate.Execute(&buf, newCardData(raw)); err != nil {
+        logErrorf("rendering card: %v", err)
+        writeError(w, http.StatusInternalServerError, "could not render card")
+        return
+    }
+    w.Header().Set("Content-Type", "image/svg+xml")
+    w.Write(buf.Bytes())
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+    "encoding/xml"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+)
+
+// TestRetornarCarta renders the card of a recorded payload, checking that
+// it is well-formed XML naming the Pokemon.
+func TestRetornarCarta(t *testing.T) {
+    pokeApiFixture(t, "pikachu.json")
+
+    w := httptest.NewRecorder()
+    newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemon/pikachu/card.svg", nil))
+    if w.Code != http.StatusOK {
+        t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+    }
+    if ct := w.Header().Get("Content-Type"); ct != "image/svg+xml" {
+        t.Errorf("Content-Type = %q, want image/svg+xml", ct)
+    }
+    if !strings.Contains(w.Body.String(), ">pikachu<") {
+        t.Errorf("card does not name pikachu:\n%s", w.Body)
+    }
+
+    decoder := xml.NewDecoder(strings.NewReader(w.Body.String()))
+    for {
+        _, err := decoder.Token()
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            t.Fatalf("card is not valid XML: %v\n%s", err, w.Body)
+        }
+    }
+}
+
+func TestRetornarCartaMissing(t *testing.T) {
+    mockPokeApi(t, "ditto")
+
+    w := httptest.NewRecorder()
+    newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemon/missingno/card.svg", nil))
+    if w.Code != http.StatusNotFound {
+        t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
+    }
+}
+
+func TestXmlEscape(t *testing.T) {
+    if got := xmlEscape(`<mr. "mime" & co>`); got != "&lt;mr. &#34;mime&#34; &amp; co&gt;" {
+        t.Errorf("xmlEscape = %q", got)
+    }
+}
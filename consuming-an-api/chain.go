@@ -0,0 +1,17 @@
+package main
+
+import (
+    "github.com/julienschmidt/httprouter"
+)
+
+// Middleware wraps a handler with behaviour of its own.
+type Middleware func(httprouter.Handle) httprouter.Handle
+
+// Chain wraps h in mws, the first of them outermost: the first middleware
+// sees the request first and the response last.
+func Chain(h httprouter.Handle, mws ...Middleware) httprouter.Handle {
+    for i := len(mws) - 1; i >= 0; i-- {
+        h = mws[i](h)
+    }
+    return h
+}
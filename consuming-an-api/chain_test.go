@@ -0,0 +1,44 @@
+package main
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "reflect"
+    "testing"
+
+    "github.com/julienschmidt/httprouter"
+)
+
+// TestChain chains recording middlewares, checking that the first one
+// listed runs outermost.
+func TestChain(t *testing.T) {
+    var calls []string
+    record := func(name string) Middleware {
+        return func(next httprouter.Handle) httprouter.Handle {
+            return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+                calls = append(calls, name + " in")
+                next(w, r, ps)
+                calls = append(calls, name + " out")
+            }
+        }
+    }
+    h := Chain(func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+        calls = append(calls, "handler")
+    }, record("recovery"), record("cors"), record("auth"))
+
+    h(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil), nil)
+
+    want := []string{"recovery in", "cors in", "auth in", "handler", "auth out", "cors out", "recovery out"}
+    if !reflect.DeepEqual(calls, want) {
+        t.Errorf("calls = %v, want %v", calls, want)
+    }
+}
+
+// TestChainEmpty checks that a chain without middlewares is the handler.
+func TestChainEmpty(t *testing.T) {
+    called := false
+    Chain(func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) { called = true })(nil, nil, nil)
+    if !called {
+        t.Error("Chain with no middlewares did not call the handler")
+    }
+}
@@ -0,0 +1,189 @@
+// Package client calls the consuming-an-api service from other Go
+// programs, decoding its replies into typed values and its error
+// envelopes into *Error.
+package client
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// Pokemon is a Pokemon kept by the service, as posted to /criarPokemon.
+type Pokemon struct {
+    Name string `json:"name"`
+    Level int8 `json:"level"`
+}
+
+// PokemonResponse is a PokéAPI Pokemon trimmed by the service.
+type PokemonResponse struct {
+    Name string `json:"name"`
+    ID int `json:"id"`
+    Height int `json:"height"`
+    Weight int `json:"weight"`
+    BaseExperience int `json:"base_experience"`
+    Types []string `json:"types"`
+    Stats map[string]int `json:"stats,omitempty"`
+}
+
+// RandomUser is a randomuser.me user flattened by the service.
+type RandomUser struct {
+    FirstName string `json:"first_name"`
+    LastName string `json:"last_name"`
+    Email string `json:"email"`
+    Country string `json:"country"`
+}
+
+// The kinds of failure an *Error can be matched against with errors.Is.
+var (
+    ErrNotFound = errors.New("not found")
+    ErrConflict = errors.New("conflict")
+    ErrUnauthorized = errors.New("unauthorized")
+    ErrRateLimited = errors.New("rate limited")
+    ErrUnavailable = errors.New("unavailable")
+)
+
+// Error is a non-2xx reply of the service.
+type Error struct {
+    StatusCode int
+    // Message and Code come from the JSON error envelope of the service;
+    // Message is the status text when there was none.
+    Message string
+    Code string
+    // Fields lists, for a validation failure, what is wrong with each
+    // field.
+    Fields map[string]string
+    // RetryAfter is how long to wait before trying again, when the
+    // service said.
+    RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+    if e.Code != "" {
+        return fmt.Sprintf("client: %d %s: %s", e.StatusCode, e.Code, e.Message)
+    }
+    return fmt.Sprintf("client: %d: %s", e.StatusCode, e.Message)
+}
+
+// Is matches e against the kind of failure its status stands for.
+func (e *Error) Is(target error) bool {
+    switch target {
+    case ErrNotFound:
+        return e.StatusCode == http.StatusNotFound
+    case ErrConflict:
+        return e.StatusCode == http.StatusConflict
+    case ErrUnauthorized:
+        return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+    case ErrRateLimited:
+        return e.StatusCode == http.StatusTooManyRequests
+    case ErrUnavailable:
+        return e.StatusCode == http.StatusBadGateway || e.StatusCode == http.StatusServiceUnavailable || e.StatusCode == http.StatusGatewayTimeout
+    }
+    return false
+}
+
+// Client calls the service at BaseURL.
+type Client struct {
+    BaseURL string
+    // HTTPClient makes the requests; http.DefaultClient when nil.
+    HTTPClient *http.Client
+    // APIKey is sent as X-API-Key, for the routes that need one.
+    APIKey string
+}
+
+// New returns a client for the service at baseURL.
+func New(baseURL string) *Client {
+    return &Client{BaseURL: strings.TrimRight(baseURL, "/"), HTTPClient: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// GetPokemon returns the named Pokemon, from /retornarPokemon/:nome.
+func (c *Client) GetPokemon(ctx context.Context, name string) (PokemonResponse, error) {
+    var p PokemonResponse
+    err := c.do(ctx, http.MethodGet, "/retornarPokemon/" + url.PathEscape(name), nil, &p)
+    return p, err
+}
+
+// GetRandomUser returns a random user, from /user/simple.
+func (c *Client) GetRandomUser(ctx context.Context) (RandomUser, error) {
+    var u RandomUser
+    err := c.do(ctx, http.MethodGet, "/user/simple", nil, &u)
+    return u, err
+}
+
+// CreatePokemon stores p, through /criarPokemon. Storing a name twice
+// fails with an error matching ErrConflict.
+func (c *Client) CreatePokemon(ctx context.Context, p Pokemon) (Pokemon, error) {
+    var created Pokemon
+    err := c.do(ctx, http.MethodPost, "/criarPokemon", p, &created)
+    return created, err
+}
+
+// do sends body, when not nil, as JSON to path and decodes the reply
+// into v.
+func (c *Client) do(ctx context.Context, method, path string, body, v interface{}) error {
+    var reader io.Reader
+    if body != nil {
+        data, err := json.Marshal(body)
+        if err != nil {
+            return err
+        }
+        reader = bytes.NewReader(data)
+    }
+    request, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(c.BaseURL, "/") + path, reader)
+    if err != nil {
+        return err
+    }
+    request.Header.Set("Accept", "application/json")
+    if body != nil {
+        request.Header.Set("Content-Type", "application/json")
+    }
+    if c.APIKey != "" {
+        request.Header.Set("X-API-Key", c.APIKey)
+    }
+
+    httpClient := c.HTTPClient
+    if httpClient == nil {
+        httpClient = http.DefaultClient
+    }
+    response, err := httpClient.Do(request)
+    if err != nil {
+        return err
+    }
+    defer response.Body.Close()
+
+    if response.StatusCode < 200 || response.StatusCode > 299 {
+        return responseError(response)
+    }
+    if err := json.NewDecoder(response.Body).Decode(v); err != nil {
+        return fmt.Errorf("client: decoding %s %s: %v", method, path, err)
+    }
+    return nil
+}
+
+// maxErrorBody is how much of an error reply is read for its envelope.
+const maxErrorBody = 64 << 10
+
+// responseError reads the error envelope of a non-2xx response.
+func responseError(response *http.Response) *Error {
+    e := &Error{StatusCode: response.StatusCode, Message: http.StatusText(response.StatusCode)}
+    var envelope struct {
+        Error string `json:"error"`
+        Code string `json:"code"`
+        Fields map[string]string `json:"fields"`
+    }
+    if json.NewDecoder(io.LimitReader(response.Body, maxErrorBody)).Decode(&envelope) == nil && envelope.Error != "" {
+        e.Message, e.Code, e.Fields = envelope.Error, envelope.Code, envelope.Fields
+    }
+    if seconds, err := strconv.Atoi(response.Header.Get("Retry-After")); err == nil && seconds > 0 {
+        e.RetryAfter = time.Duration(seconds) * time.Second
+    }
+    return e
+}
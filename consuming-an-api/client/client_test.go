@@ -0,0 +1,39 @@
+package client
+
+import (
+    "errors"
+    "io/ioutil"
+    "net/http"
+    "strings"
+    "testing"
+    "time"
+)
+
+// The calls themselves are tested against the service's router, in
+// ../client_test.go; these cover replies the service only sends when
+// something in front of it fails.
+
+// TestResponseError decodes error replies with and without an envelope.
+func TestResponseError(t *testing.T) {
+    reply := func(status int, header http.Header, body string) *http.Response {
+        return &http.Response{StatusCode: status, Header: header, Body: ioutil.NopCloser(strings.NewReader(body))}
+    }
+
+    e := responseError(reply(http.StatusConflict, http.Header{}, `{"error":"pokemon already exists","status":409,"code":"pokemon_exists"}`))
+    if e.Message != "pokemon already exists" || e.Code != "pokemon_exists" || !errors.Is(e, ErrConflict) {
+        t.Errorf("responseError(409 envelope) = %+v, want the envelope's message and code", e)
+    }
+
+    e = responseError(reply(http.StatusBadGateway, http.Header{}, `<html>bad gateway</html>`))
+    if e.Message != "Bad Gateway" || e.Code != "" || !errors.Is(e, ErrUnavailable) {
+        t.Errorf("responseError(502 HTML) = %+v, want the status text", e)
+    }
+
+    e = responseError(reply(http.StatusTooManyRequests, http.Header{"Retry-After": {"7"}}, ``))
+    if e.RetryAfter != 7 * time.Second || !errors.Is(e, ErrRateLimited) {
+        t.Errorf("responseError(429) = %+v, want a 7s RetryAfter", e)
+    }
+    if errors.Is(e, ErrNotFound) {
+        t.Errorf("responseError(429) matches ErrNotFound")
+    }
+}
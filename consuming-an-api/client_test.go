@@ -0,0 +1,122 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "net/http/httptest"
+    "reflect"
+    "strings"
+    "testing"
+    "time"
+
+    "example.com/consuming-an-api/client"
+)
+
+// newClientServer runs the router on a real listener for the client
+// package to call, like newTestServer, storing into an empty store.
+func newClientServer(t *testing.T) *client.Client {
+    server := newTestServer(t)
+    store = newPokemonStore()
+    return client.New(server.URL)
+}
+
+// TestClientTypes checks that the types of the client package keep the
+// fields and JSON names of the ones the service encodes.
+func TestClientTypes(t *testing.T) {
+    tests := []struct {
+        service, client interface{}
+    }{
+        {Pokemon{}, client.Pokemon{}},
+        {PokemonResponse{}, client.PokemonResponse{}},
+        {RandomUser{}, client.RandomUser{}},
+    }
+    for _, tt := range tests {
+        service, mirror := reflect.TypeOf(tt.service), reflect.TypeOf(tt.client)
+        if service.NumField() != mirror.NumField() {
+            t.Errorf("%v has %d fields, client.%s has %d", service, service.NumField(), mirror.Name(), mirror.NumField())
+            continue
+        }
+        for i := 0; i < service.NumField(); i++ {
+            a, b := service.Field(i), mirror.Field(i)
+            if a.Name != b.Name || a.Type != b.Type || a.Tag != b.Tag {
+                t.Errorf("%v field %s %v `%s`, client.%s has %s %v `%s`", service, a.Name, a.Type, a.Tag, mirror.Name(), b.Name, b.Type, b.Tag)
+            }
+        }
+    }
+}
+
+func TestClientGetPokemon(t *testing.T) {
+    c := newClientServer(t)
+
+    p, err := c.GetPokemon(context.Background(), "pikachu")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if p.Name != "pikachu" || p.ID != 25 || !reflect.DeepEqual(p.Types, []string{"electric"}) || p.Stats["hp"] != 35 {
+        t.Errorf("GetPokemon(pikachu) = %+v, want pikachu #25, electric, 35 hp", p)
+    }
+}
+
+func TestClientGetPokemonNotFound(t *testing.T) {
+    c := newClientServer(t)
+    mockPokeApi(t, "pikachu")
+
+    _, err := c.GetPokemon(context.Background(), "missingno")
+    var e *client.Error
+    if !errors.Is(err, client.ErrNotFound) || !errors.As(err, &e) || e.Message != "pokemon not found" {
+        t.Errorf("GetPokemon(missingno) error = %v, want a not found *client.Error", err)
+    }
+
+    overrideConfig(t).PokeAPIBaseURL = closedURL(t)
+    usePokemonCache(t, time.Minute)
+    fastRetries(t)
+    if _, err := c.GetPokemon(context.Background(), "eevee"); !errors.Is(err, client.ErrUnavailable) {
+        t.Errorf("GetPokemon with PokéAPI down error = %v, want unavailable", err)
+    }
+}
+
+func TestClientGetRandomUser(t *testing.T) {
+    c := newClientServer(t)
+
+    u, err := c.GetRandomUser(context.Background())
+    if err != nil || u.FirstName == "" || u.Email == "" {
+        t.Errorf("GetRandomUser() = %+v, %v, want a user from the fixture", u, err)
+    }
+
+    cfg := overrideConfig(t)
+    cfg.RateLimit, cfg.RateBurst = 0.001, 1
+    limited := httptest.NewServer(newRouter())
+    defer limited.Close()
+    c.BaseURL = limited.URL
+    c.GetRandomUser(context.Background())
+    _, err = c.GetRandomUser(context.Background())
+    var e *client.Error
+    if !errors.Is(err, client.ErrRateLimited) || !errors.As(err, &e) || e.RetryAfter <= 0 {
+        t.Errorf("GetRandomUser() while limited error = %v, want rate limited with a Retry-After", err)
+    }
+}
+
+func TestClientCreatePokemon(t *testing.T) {
+    overrideConfig(t).APIKeys = []string{"secret"}
+    c := newClientServer(t)
+
+    if _, err := c.CreatePokemon(context.Background(), client.Pokemon{Name: "pikachu", Level: 12}); !errors.Is(err, client.ErrUnauthorized) {
+        t.Errorf("CreatePokemon without a key error = %v, want unauthorized", err)
+    }
+
+    c.APIKey = "secret"
+    p, err := c.CreatePokemon(context.Background(), client.Pokemon{Name: "pikachu", Level: 12})
+    if err != nil || p != (client.Pokemon{Name: "pikachu", Level: 12}) {
+        t.Fatalf("CreatePokemon() = %+v, %v, want pikachu", p, err)
+    }
+    _, err = c.CreatePokemon(context.Background(), client.Pokemon{Name: "pikachu", Level: 12})
+    if !errors.Is(err, client.ErrConflict) {
+        t.Errorf("second CreatePokemon() error = %v, want a conflict", err)
+    }
+
+    _, err = c.CreatePokemon(context.Background(), client.Pokemon{Name: strings.Repeat("a", 100), Level: 5})
+    var e *client.Error
+    if !errors.As(err, &e) || e.StatusCode != 422 || len(e.Fields) == 0 {
+        t.Errorf("CreatePokemon(invalid) error = %v, want a 422 listing the fields", err)
+    }
+}
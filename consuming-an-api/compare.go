@@ -0,0 +1,86 @@
+package main
+
+import (
+    "fmt"
+    "net/http"
+
+    "github.com/julienschmidt/httprouter"
+    "golang.org/x/sync/errgroup"
+)
+
+// statComparison holds one stat of the two compared Pokemon. Higher is
+// the name of the Pokemon with the larger value, or "tie".
+type statComparison struct {
+    A int `json:"a"`
+    B int `json:"b"`
+    Higher string `json:"higher"`
+}
+
+// pokemonComparison is the reply of /pokemons/compare.
+type pokemonComparison struct {
+    A string `json:"a"`
+    B string `json:"b"`
+    Stats map[string]statComparison `json:"stats"`
+    Total statComparison `json:"total"`
+}
+
+// compareStat fills in which of a and b is higher.
+func compareStat(a, b int, nameA, nameB string) statComparison {
+    c := statComparison{A: a, B: b, Higher: "tie"}
+    if a > b {
+        c.Higher = nameA
+    } else if b > a {
+        c.Higher = nameB
+    }
+    return c
+}
+
+// comparePokemon compares the base stats of a and b, one by one and in
+// total. A stat only one of them has counts as 0 for the other.
+func comparePokemon(a, b PokemonResponse) pokemonComparison {
+    c := pokemonComparison{A: a.Name, B: b.Name, Stats: make(map[string]statComparison)}
+    var totalA, totalB int
+    for name, value := range a.Stats {
+        c.Stats[name] = compareStat(value, b.Stats[name], a.Name, b.Name)
+        totalA += value
+    }
+    for name, value := range b.Stats {
+        if _, ok := a.Stats[name]; !ok {
+            c.Stats[name] = compareStat(0, value, a.Name, b.Name)
+        }
+        totalB += value
+    }
+    c.Total = compareStat(totalA, totalB, a.Name, b.Name)
+    return c
+}
+
+// compararPokemons fetches ?a= and ?b= concurrently and compares their
+// base stats.
+func compararPokemons(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+    query := r.URL.Query()
+    names := [2]string{query.Get("a"), query.Get("b")}
+    if names[0] == "" || names[1] == "" {
+        writeError(w, http.StatusBadRequest, "a and b are required")
+        return
+    }
+
+    var pokemons [2]PokemonResponse
+    g, ctx := errgroup.WithContext(r.Context())
+    for i := range names {
+        i := i
+        g.Go(func() error {
+            p, err := fetchPokemon(ctx, names[i])
+            if he, ok := err.(*httpError); ok && he.status == http.StatusNotFound {
+                return &httpError{http.StatusNotFound, fmt.Sprintf("pokemon %q not found", names[i])}
+            }
+            pokemons[i] = p
+            return err
+        })
+    }
+    if err := g.Wait(); err != nil {
+        writeHttpError(w, err)
+        return
+    }
+
+    writeJsonFor(w, r, http.StatusOK, comparePokemon(pokemons[0], pokemons[1]))
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+)
+
+// TestCompararPokemons compares two mocked Pokemon, checking every stat
+// and the totals.
+func TestCompararPokemons(t *testing.T) {
+    mockComparePokeApi(t)
+
+    w := httptest.NewRecorder()
+    newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemons/compare?a=pikachu&b=raichu", nil))
+    if w.Code != http.StatusOK {
+        t.Fatalf("GET /pokemons/compare status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+    }
+
+    var got pokemonComparison
+    if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+        t.Fatal(err)
+    }
+    want := map[string]statComparison{
+        "hp": {35, 60, "raichu"},
+        "attack": {55, 90, "raichu"},
+        "speed": {90, 90, "tie"},
+    }
+    if got.A != "pikachu" || got.B != "raichu" || len(got.Stats) != len(want) {
+        t.Fatalf("GET /pokemons/compare = %+v, want pikachu vs raichu with %d stats", got, len(want))
+    }
+    for name, stat := range want {
+        if got.Stats[name] != stat {
+            t.Errorf("stat %s = %+v, want %+v", name, got.Stats[name], stat)
+        }
+    }
+    if wantTotal := (statComparison{180, 240, "raichu"}); got.Total != wantTotal {
+        t.Errorf("total = %+v, want %+v", got.Total, wantTotal)
+    }
+}
+
+// TestCompararPokemonsNotFound compares against an unknown Pokemon,
+// checking that the 404 names it.
+func TestCompararPokemonsNotFound(t *testing.T) {
+    mockComparePokeApi(t)
+
+    w := httptest.NewRecorder()
+    newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemons/compare?a=pikachu&b=missingno", nil))
+    if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), `missingno`) {
+        t.Errorf("GET /pokemons/compare with an unknown b = %d %s, want 404 naming missingno", w.Code, w.Body)
+    }
+}
+
+// TestCompararPokemonsMissingName leaves out b, checking for a 400.
+func TestCompararPokemonsMissingName(t *testing.T) {
+    w := httptest.NewRecorder()
+    newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemons/compare?a=pikachu", nil))
+    if w.Code != http.StatusBadRequest {
+        t.Errorf("GET /pokemons/compare without b status = %d, want %d", w.Code, http.StatusBadRequest)
+    }
+}
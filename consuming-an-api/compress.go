@@ -0,0 +1,170 @@
+package main
+
+import (
+    "compress/gzip"
+    "io"
+    "net/http"
+    "strings"
+
+    "github.com/julienschmidt/httprouter"
+)
+
+// minGzipSize is the smallest body worth compressing; below it the gzip
+// framing costs more than it saves.
+const minGzipSize = 1024
+
+// gzipResponses compresses the responses of next for clients that accept
+// gzip. Small bodies and content that is already compressed are sent
+// unchanged.
+func gzipResponses(next httprouter.Handle) httprouter.Handle {
+    return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+        w.Header().Add("Vary", "Accept-Encoding")
+        if !acceptsGzip(r) {
+            next(w, r, ps)
+            return
+        }
+
+        gw := &gzipResponseWriter{ResponseWriter: w, status: http.StatusOK}
+        defer gw.Close()
+        next(gw, r, ps)
+    }
+}
+
+// acceptsGzip reports whether the request's Accept-Encoding allows gzip.
+func acceptsGzip(r *http.Request) bool {
+    for _, encoding := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+        parts := strings.Split(encoding, ";")
+        if strings.TrimSpace(parts[0]) != "gzip" {
+            continue
+        }
+        if len(parts) > 1 && strings.Replace(strings.TrimSpace(parts[1]), " ", "", -1) == "q=0" {
+            return false
+        }
+        return true
+    }
+    return false
+}
+
+// gzipResponseWriter holds back the status and the first minGzipSize
+// bytes of the body until it knows whether compressing is worthwhile.
+type gzipResponseWriter struct {
+    http.ResponseWriter
+    status int
+    wroteHeader bool
+    buf []byte
+    decided bool
+    gz *gzip.Writer
+}
+
+func (w *gzipResponseWriter) WriteHeader(status int) {
+    if !w.wroteHeader {
+        w.status = status
+        w.wroteHeader = true
+    }
+}
+
+func (w *gzipResponseWriter) Write(p []byte) (int, error) {
+    if w.decided {
+        if w.gz != nil {
+            return w.gz.Write(p)
+        }
+        return w.ResponseWriter.Write(p)
+    }
+
+    w.buf = append(w.buf, p...)
+    if len(w.buf) >= minGzipSize {
+        if err := w.decide(); err != nil {
+            return 0, err
+        }
+    }
+    return len(p), nil
+}
+
+// decide sends the held back status and body, compressing from now on if
+// the body is large enough and not already compressed.
+func (w *gzipResponseWriter) decide() error {
+    w.decided = true
+    header := w.Header()
+    if len(w.buf) >= minGzipSize && header.Get("Content-Encoding") == "" && compressible(header.Get("Content-Type")) {
+        header.Set("Content-Encoding", "gzip")
+        header.Del("Content-Length")
+        w.ResponseWriter.WriteHeader(w.status)
+        w.gz = gzip.NewWriter(w.ResponseWriter)
+        _, err := w.gz.Write(w.buf)
+        return err
+    }
+
+    w.ResponseWriter.WriteHeader(w.status)
+    _, err := w.ResponseWriter.Write(w.buf)
+    return err
+}
+
+// Close flushes whatever is still buffered and terminates the gzip
+// stream.
+func (w *gzipResponseWriter) Close() error {
+    if !w.decided {
+        if err := w.decide(); err != nil {
+            return err
+        }
+    }
+    if w.gz != nil {
+        return w.gz.Close()
+    }
+    return nil
+}
+
+// Unwrap lets http.ResponseController reach the ResponseWriter behind w.
+func (w *gzipResponseWriter) Unwrap() http.ResponseWriter {
+    return w.ResponseWriter
+}
+
+// compressible reports whether a body of the given content type benefits
+// from gzip. Images, archives and the like are compressed already.
+func compressible(contentType string) bool {
+    for _, prefix := range []string{"image/", "video/", "audio/", "application/zip", "application/gzip"} {
+        if strings.HasPrefix(contentType, prefix) {
+            return false
+        }
+    }
+    return true
+}
+
+// gzipBody reads a gzip-encoded upstream body decompressed, closing the
+// underlying body along with the decompressor.
+type gzipBody struct {
+    *gzip.Reader
+    body io.ReadCloser
+}
+
+func (b *gzipBody) Close() error {
+    b.Reader.Close()
+    return b.body.Close()
+}
+
+// decodeUpstreamBody makes response's body plain, whatever the upstream
+// encoded it with. Upstream bodies are always handed on decompressed, and
+// compressed again for our clients by gzipResponses when they accept it,
+// so Content-Encoding is never proxied. net/http already does this when
+// it asked for gzip itself, but not when the upstream compresses
+// unasked. Encodings other than gzip are refused.
+func decodeUpstreamBody(response *http.Response) error {
+    encoding := strings.ToLower(strings.TrimSpace(response.Header.Get("Content-Encoding")))
+    switch encoding {
+    case "", "identity":
+        return nil
+    case "gzip", "x-gzip":
+    default:
+        return &httpError{http.StatusBadGateway, "unsupported upstream encoding " + encoding}
+    }
+
+    gz, err := gzip.NewReader(response.Body)
+    if err != nil {
+        return &httpError{http.StatusBadGateway, "invalid upstream response"}
+    }
+    response.Body = &gzipBody{gz, response.Body}
+    response.Header.Del("Content-Encoding")
+    response.Header.Del("Content-Length")
+    response.ContentLength = -1
+    response.Uncompressed = true
+    return nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+    "compress/gzip"
+    "context"
+    "io/ioutil"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/julienschmidt/httprouter"
+)
+
+// jsonHandler answers every request with body as JSON.
+func jsonHandler(body string) httprouter.Handle {
+    return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+        w.Header().Set("Content-Type", "application/json")
+        w.Write([]byte(body))
+    }
+}
+
+// TestGzipResponses requests a large JSON body with Accept-Encoding: gzip,
+// checking that it decompresses to the original JSON.
+func TestGzipResponses(t *testing.T) {
+    body := `{"names":["` + strings.Repeat("pikachu", 500) + `"]}`
+    w := httptest.NewRecorder()
+    r := httptest.NewRequest(http.MethodGet, "/", nil)
+    r.Header.Set("Accept-Encoding", "gzip, deflate")
+
+    gzipResponses(jsonHandler(body))(w, r, nil)
+
+    if got := w.Header().Get("Content-Encoding"); got != "gzip" {
+        t.Fatalf("Content-Encoding = %q, want gzip", got)
+    }
+    zr, err := gzip.NewReader(w.Body)
+    if err != nil {
+        t.Fatal(err)
+    }
+    got, err := ioutil.ReadAll(zr)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if string(got) != body {
+        t.Fatalf("decompressed body = %.40q..., want %.40q...", got, body)
+    }
+}
+
+// TestGzipResponsesSkipped checks that small bodies, already compressed
+// content and clients without gzip support get the body unchanged.
+func TestGzipResponsesSkipped(t *testing.T) {
+    large := strings.Repeat("x", 2 * minGzipSize)
+    tests := []struct {
+        name, acceptEncoding, contentType, body string
+    }{
+        {"small body", "gzip", "application/json", `{"ok":true}`},
+        {"image", "gzip", "image/png", large},
+        {"no gzip support", "", "application/json", large},
+        {"gzip refused", "gzip;q=0", "application/json", large},
+    }
+    for _, tt := range tests {
+        handler := func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+            w.Header().Set("Content-Type", tt.contentType)
+            w.Write([]byte(tt.body))
+        }
+        w := httptest.NewRecorder()
+        r := httptest.NewRequest(http.MethodGet, "/", nil)
+        r.Header.Set("Accept-Encoding", tt.acceptEncoding)
+
+        gzipResponses(handler)(w, r, nil)
+
+        if w.Header().Get("Content-Encoding") != "" || w.Body.String() != tt.body {
+            t.Errorf("%s: got Content-Encoding %q and a %d byte body, want the body unchanged", tt.name, w.Header().Get("Content-Encoding"), w.Body.Len())
+        }
+    }
+}
+
+// gzipUpstream answers every request with body gzip-encoded, whether or
+// not it was asked to.
+func gzipUpstream(t *testing.T, body string) *httptest.Server {
+    upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("Content-Type", "application/json")
+        w.Header().Set("Content-Encoding", "gzip")
+        gz := gzip.NewWriter(w)
+        gz.Write([]byte(body))
+        gz.Close()
+    }))
+    t.Cleanup(upstream.Close)
+    return upstream
+}
+
+// TestReturnJsonGzipUpstream proxies an upstream that gzips unasked,
+// checking that the client gets the plain JSON without a stale
+// Content-Encoding.
+func TestReturnJsonGzipUpstream(t *testing.T) {
+    upstream := gzipUpstream(t, `{"results":[]}`)
+
+    w := httptest.NewRecorder()
+    returnJson(upstream.URL, w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+    if w.Code != http.StatusOK || w.Body.String() != `{"results":[]}` {
+        t.Fatalf("returnJson = %d %q, want the decompressed JSON", w.Code, w.Body)
+    }
+    if ce := w.Header().Get("Content-Encoding"); ce != "" {
+        t.Errorf("Content-Encoding = %q, want none", ce)
+    }
+}
+
+// TestFetchPokemonGzipUpstream checks that decoded upstream calls read
+// gzip-encoded payloads too.
+func TestFetchPokemonGzipUpstream(t *testing.T) {
+    overrideConfig(t).PokeAPIBaseURL = gzipUpstream(t, `{"name":"ditto","id":132}`).URL
+    usePokemonCache(t, time.Minute)
+
+    pokemon, err := fetchPokemon(context.Background(), "ditto")
+    if err != nil || pokemon.Name != "ditto" {
+        t.Fatalf("fetchPokemon = %+v, %v, want ditto", pokemon, err)
+    }
+}
+
+func TestReturnJsonUnsupportedEncoding(t *testing.T) {
+    upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("Content-Encoding", "br")
+        w.Write([]byte("\x0b\x02\x80{}\x03"))
+    }))
+    defer upstream.Close()
+
+    w := httptest.NewRecorder()
+    returnJson(upstream.URL, w, httptest.NewRequest(http.MethodGet, "/", nil))
+    if w.Code != http.StatusBadGateway {
+        t.Errorf("status = %d, want %d", w.Code, http.StatusBadGateway)
+    }
+}
@@ -0,0 +1,565 @@
+package main
+
+import (
+    "encoding/json"
+    "flag"
+    "fmt"
+    "io/ioutil"
+    "net/url"
+    "os"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// Config holds the settings that can be changed without touching code.
+// Each field notes the environment variable that overrides it.
+type Config struct {
+    // Addr is the address the server listens on, e.g. 127.0.0.1:9000
+    // ($ADDR, -addr flag). When it is empty the server listens on Port on
+    // every interface ($PORT).
+    Addr string
+    Port int
+    // TLSCert and TLSKey are the certificate and key files the server
+    // serves HTTPS with; they are given together or not at all ($TLS_CERT,
+    // -tls-cert flag, $TLS_KEY, -tls-key flag).
+    TLSCert string
+    TLSKey string
+    // StoreFile is the JSON file the created Pokemon are saved to and
+    // loaded from; when empty they are kept in memory only ($STORE_FILE,
+    // -store-file flag).
+    StoreFile string
+    // LogLevel is the least severe level logged ($LOG_LEVEL, -verbose
+    // flag for debug).
+    LogLevel logLevel
+
+    // RandomUserBaseURL is the randomuser.me API root, without a trailing
+    // slash ($RANDOMUSER_BASE_URL).
+    RandomUserBaseURL string
+    // PokeAPIBaseURL is the PokéAPI v2 root, without a trailing slash
+    // ($POKEAPI_BASE_URL).
+    PokeAPIBaseURL string
+    // PokeAPIMirrors are tried in order when PokeAPIBaseURL cannot be
+    // reached or fails while fetching a Pokemon, written like it
+    // ($POKEAPI_MIRRORS, comma-separated).
+    PokeAPIMirrors []string
+    // MaxPokemonID is the highest id /pokemons/random picks from
+    // ($MAX_POKEMON_ID).
+    MaxPokemonID int
+    // PokemonMaxAge is how long clients and CDNs may cache a Pokemon from
+    // /retornarPokemon ($POKEMON_MAX_AGE).
+    PokemonMaxAge time.Duration
+    // AutocompleteMax is the most names /pokemons/autocomplete returns
+    // ($AUTOCOMPLETE_MAX).
+    AutocompleteMax int
+    // CacheTTL is how long PokéAPI responses are cached ($CACHE_TTL,
+    // -cache-ttl flag).
+    CacheTTL time.Duration
+    // StaleIfError serves an expired cached Pokemon when PokéAPI fails,
+    // rather than an error ($STALE_IF_ERROR).
+    StaleIfError bool
+    // CacheMaxEntries is how many PokéAPI responses are cached at most
+    // before the least recently used are evicted; zero removes the limit
+    // ($CACHE_MAX_ENTRIES).
+    CacheMaxEntries int
+    // UserStreamInterval is how often /ws/users pushes a random user
+    // ($USER_STREAM_INTERVAL).
+    UserStreamInterval time.Duration
+
+    // RateLimit is how many requests per second each client may send;
+    // zero disables rate limiting ($RATE_LIMIT_RPS). RateBurst is how many
+    // it may send at once ($RATE_LIMIT_BURST).
+    RateLimit float64
+    RateBurst int
+    // TrustProxy makes the rate limiter identify clients by their
+    // X-Forwarded-For header ($TRUST_PROXY, -trust-proxy flag).
+    TrustProxy bool
+
+    // CORSAllowedOrigins are the browser origins allowed to call the API;
+    // "*" allows any origin ($CORS_ALLOWED_ORIGINS, comma-separated).
+    CORSAllowedOrigins []string
+
+    // APIKeys are the keys accepted in X-API-Key by the routes that change
+    // stored Pokemon; when empty those routes are open to everyone
+    // ($API_KEYS, comma-separated).
+    APIKeys []string
+    // IdempotencyTTL is how long the response to a request sent with an
+    // Idempotency-Key is kept for replaying ($IDEMPOTENCY_TTL).
+    IdempotencyTTL time.Duration
+
+    // BreakerThreshold is how many consecutive PokéAPI failures open the
+    // circuit breaker ($BREAKER_THRESHOLD), and BreakerCooldown how long
+    // it stays open before letting a probe through ($BREAKER_COOLDOWN).
+    BreakerThreshold int
+    BreakerCooldown time.Duration
+
+    // MaxBodyBytes is the largest request body we read ($MAX_BODY_BYTES).
+    MaxBodyBytes int64
+    // LogBodyBytes is how much of each request and response body is
+    // logged at the debug level ($LOG_BODY_BYTES).
+    LogBodyBytes int
+    // HandlerTimeout bounds how long a handler may take as a whole, upstream
+    // calls included; zero disables it ($HANDLER_TIMEOUT).
+    HandlerTimeout time.Duration
+    // ReadHeaderTimeout bounds how long a client may take to send the
+    // request headers ($READ_HEADER_TIMEOUT), and ReadTimeout the whole
+    // request, body included ($READ_TIMEOUT). WriteTimeout bounds the
+    // time from the end of the request headers to the end of the response
+    // ($WRITE_TIMEOUT), which streams are exempt from; it should leave
+    // room for HandlerTimeout. IdleTimeout is how long a keep-alive
+    // connection may wait for its next request ($IDLE_TIMEOUT). Together
+    // they stop slow clients from holding connections open; zero disables
+    // any of them.
+    ReadHeaderTimeout time.Duration
+    ReadTimeout time.Duration
+    WriteTimeout time.Duration
+    IdleTimeout time.Duration
+
+    // UpstreamTimeout bounds a whole upstream call, including reading the
+    // body ($UPSTREAM_TIMEOUT).
+    UpstreamTimeout time.Duration
+    // UpstreamUserAgent is the User-Agent sent on upstream calls
+    // ($UPSTREAM_USER_AGENT).
+    UpstreamUserAgent string
+    // MaxUpstreamConcurrency is how many upstream calls may be in flight
+    // at once across all requests; zero removes the limit
+    // ($MAX_UPSTREAM_CONCURRENCY).
+    MaxUpstreamConcurrency int
+    // UpstreamMaxIdleConns is how many idle upstream connections are kept
+    // open in total ($UPSTREAM_MAX_IDLE_CONNS), and
+    // UpstreamMaxIdleConnsPerHost how many per upstream host
+    // ($UPSTREAM_MAX_IDLE_CONNS_PER_HOST).
+    UpstreamMaxIdleConns int
+    UpstreamMaxIdleConnsPerHost int
+    // UpstreamIdleConnTimeout is how long an idle upstream connection is
+    // kept before being closed ($UPSTREAM_IDLE_CONN_TIMEOUT).
+    UpstreamIdleConnTimeout time.Duration
+    // UpstreamRetries is how many times a failed upstream GET is retried
+    // ($UPSTREAM_RETRIES, -upstream-retries flag).
+    UpstreamRetries int
+    // UpstreamCheckInterval is how often every upstream is health-checked
+    // for /upstreams ($UPSTREAM_CHECK_INTERVAL).
+    UpstreamCheckInterval time.Duration
+
+    // TraceExporter is where request and upstream spans go: "none" drops
+    // them, "stdout" prints them as JSON and "otlp" sends them to the
+    // collector at $OTEL_EXPORTER_OTLP_ENDPOINT ($TRACE_EXPORTER).
+    TraceExporter string
+
+    // Features switches the routes tagged with a feature flag on or off,
+    // e.g. FEATURES=batch=false,ws=true; features not listed are on
+    // ($FEATURES).
+    Features map[string]bool
+}
+
+// defaultConfig points at the public upstream APIs.
+var defaultConfig = Config{
+    Port: defaultPort,
+    LogLevel: levelInfo,
+    RandomUserBaseURL: "https://randomuser.me/api",
+    PokeAPIBaseURL: "https://pokeapi.co/api/v2",
+    MaxPokemonID: 1010,
+    PokemonMaxAge: 24 * time.Hour,
+    AutocompleteMax: 10,
+    CacheTTL: defaultCacheTTL,
+    StaleIfError: true,
+    CacheMaxEntries: 1000,
+    UserStreamInterval: 5 * time.Second,
+    RateLimit: 10,
+    RateBurst: 20,
+    CORSAllowedOrigins: []string{"*"},
+    IdempotencyTTL: 24 * time.Hour,
+    BreakerThreshold: 5,
+    BreakerCooldown: 30 * time.Second,
+    MaxBodyBytes: 1 << 20,
+    LogBodyBytes: 1024,
+    HandlerTimeout: 15 * time.Second,
+    ReadHeaderTimeout: 5 * time.Second,
+    ReadTimeout: 30 * time.Second,
+    WriteTimeout: 30 * time.Second,
+    IdleTimeout: 2 * time.Minute,
+    UpstreamTimeout: 10 * time.Second,
+    UpstreamUserAgent: "go-learn/" + version,
+    MaxUpstreamConcurrency: 50,
+    // We only talk to a couple of upstream hosts, so most idle connections
+    // can go to them instead of the 2 per host net/http keeps by default.
+    UpstreamMaxIdleConns: 100,
+    UpstreamMaxIdleConnsPerHost: 32,
+    UpstreamIdleConnTimeout: 90 * time.Second,
+    UpstreamRetries: 3,
+    UpstreamCheckInterval: 30 * time.Second,
+    TraceExporter: "none",
+}
+
+// config is the configuration used by the handlers.
+var config = defaultConfig
+
+// loadConfig builds the configuration from, in increasing precedence,
+// defaultConfig, the config file at path when given, the environment
+// variables and the configFlags set on the command line parsed by fs,
+// when given. Every source goes through apply, so a setting with an
+// invalid value is an error wherever it comes from rather than ignored.
+//
+// The config file is a JSON object keyed by the environment variable
+// names, e.g. {"POKEAPI_BASE_URL": "http://localhost:8000", "RATE_LIMIT_RPS": 5,
+// "API_KEYS": ["a", "b"], "FEATURES": {"batch": false}, "PORT": 8080}.
+func loadConfig(path string, fs *flag.FlagSet) (Config, error) {
+    c := defaultConfig
+    if path != "" {
+        file, err := readConfigFile(path)
+        if err != nil {
+            return Config{}, err
+        }
+        if err := c.apply(func(name string) string { return file[name] }); err != nil {
+            return Config{}, fmt.Errorf("config file %s: %v", path, err)
+        }
+    }
+    if err := c.apply(os.Getenv); err != nil {
+        return Config{}, fmt.Errorf("environment: %v", err)
+    }
+    if fs != nil {
+        if err := applyFlags(&c, fs); err != nil {
+            return Config{}, err
+        }
+    }
+    if (c.TLSCert == "") != (c.TLSKey == "") {
+        return Config{}, fmt.Errorf("TLS_CERT and TLS_KEY must be given together")
+    }
+    return c, nil
+}
+
+// readConfigFile reads the settings in the JSON config file at path as
+// the strings they would be given as environment variables.
+func readConfigFile(path string) (map[string]string, error) {
+    data, err := ioutil.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+    var raw map[string]interface{}
+    if err := json.Unmarshal(data, &raw); err != nil {
+        return nil, fmt.Errorf("config file %s: %v", path, err)
+    }
+
+    known := make(map[string]bool)
+    (&Config{}).apply(func(name string) string {
+        known[name] = true
+        return ""
+    })
+    settings := make(map[string]string, len(raw))
+    for name, value := range raw {
+        if !known[name] {
+            return nil, fmt.Errorf("config file %s: unknown setting %s", path, name)
+        }
+        switch value := value.(type) {
+        case string:
+            settings[name] = value
+        case float64:
+            settings[name] = strconv.FormatFloat(value, 'f', -1, 64)
+        case bool:
+            settings[name] = strconv.FormatBool(value)
+        case map[string]interface{}:
+            pairs := make([]string, 0, len(value))
+            for key, item := range value {
+                pairs = append(pairs, key + "=" + fmt.Sprint(item))
+            }
+            sort.Strings(pairs)
+            settings[name] = strings.Join(pairs, ",")
+        case []interface{}:
+            items := make([]string, len(value))
+            for i, item := range value {
+                items[i] = fmt.Sprint(item)
+            }
+            settings[name] = strings.Join(items, ",")
+        default:
+            return nil, fmt.Errorf("config file %s: %s must be a string, number, boolean, list or object", path, name)
+        }
+    }
+    return settings, nil
+}
+
+// settingsReader parses the settings returned by get into Config fields,
+// remembering the first one that is set to an invalid value. Unset
+// settings leave their field alone.
+type settingsReader struct {
+    get func(string) string
+    err error
+}
+
+func (s *settingsReader) fail(name, value, want string) {
+    if s.err == nil {
+        s.err = fmt.Errorf("invalid %s %q, want %s", name, value, want)
+    }
+}
+
+// isHTTPURL reports whether value is an absolute http or https URL.
+func isHTTPURL(value string) bool {
+    u, err := url.Parse(value)
+    return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func (s *settingsReader) url(name string, dst *string) {
+    value := s.get(name)
+    if value == "" {
+        return
+    }
+    if !isHTTPURL(value) {
+        s.fail(name, value, "an http or https URL")
+        return
+    }
+    *dst = strings.TrimRight(value, "/")
+}
+
+func (s *settingsReader) urls(name string, dst *[]string) {
+    items := splitList(s.get(name))
+    if len(items) == 0 {
+        return
+    }
+    urls := make([]string, len(items))
+    for i, item := range items {
+        if !isHTTPURL(item) {
+            s.fail(name, item, "a list of http or https URLs")
+            return
+        }
+        urls[i] = strings.TrimRight(item, "/")
+    }
+    *dst = urls
+}
+
+func (s *settingsReader) list(name string, dst *[]string) {
+    if items := splitList(s.get(name)); len(items) > 0 {
+        *dst = items
+    }
+}
+
+func (s *settingsReader) text(name string, dst *string) {
+    if value := s.get(name); value != "" {
+        *dst = value
+    }
+}
+
+// choice reads one of options.
+func (s *settingsReader) choice(name string, options []string, dst *string) {
+    value := s.get(name)
+    if value == "" {
+        return
+    }
+    for _, option := range options {
+        if value == option {
+            *dst = value
+            return
+        }
+    }
+    s.fail(name, value, "one of " + strings.Join(options, ", "))
+}
+
+// flags reads a list of name=bool pairs, each name one of known.
+func (s *settingsReader) flags(name string, known []string, dst *map[string]bool) {
+    items := splitList(s.get(name))
+    if len(items) == 0 {
+        return
+    }
+    want := "a list of name=true or name=false, the names among " + strings.Join(known, ", ")
+    flags := make(map[string]bool, len(items))
+    for _, item := range items {
+        i := strings.Index(item, "=")
+        if i < 0 {
+            s.fail(name, item, want)
+            return
+        }
+        flag := strings.TrimSpace(item[:i])
+        on, err := strconv.ParseBool(strings.TrimSpace(item[i + 1:]))
+        j := sort.SearchStrings(known, flag)
+        if err != nil || j == len(known) || known[j] != flag {
+            s.fail(name, item, want)
+            return
+        }
+        flags[flag] = on
+    }
+    *dst = flags
+}
+
+// integer reads an int of at least min.
+func (s *settingsReader) integer(name string, min int, dst *int) {
+    value := s.get(name)
+    if value == "" {
+        return
+    }
+    n, err := strconv.Atoi(value)
+    if err != nil || n < min {
+        s.fail(name, value, "an integer of at least " + strconv.Itoa(min))
+        return
+    }
+    *dst = n
+}
+
+// port reads a TCP port number.
+func (s *settingsReader) port(name string, dst *int) {
+    value := s.get(name)
+    if value == "" {
+        return
+    }
+    n, err := strconv.Atoi(value)
+    if err != nil || n < 1 || n > 65535 {
+        s.fail(name, value, "a port number from 1 to 65535")
+        return
+    }
+    *dst = n
+}
+
+// integer64 reads an int64 of at least min.
+func (s *settingsReader) integer64(name string, min int64, dst *int64) {
+    value := s.get(name)
+    if value == "" {
+        return
+    }
+    n, err := strconv.ParseInt(value, 10, 64)
+    if err != nil || n < min {
+        s.fail(name, value, "an integer of at least " + strconv.FormatInt(min, 10))
+        return
+    }
+    *dst = n
+}
+
+// number reads a non-negative float.
+func (s *settingsReader) number(name string, dst *float64) {
+    value := s.get(name)
+    if value == "" {
+        return
+    }
+    n, err := strconv.ParseFloat(value, 64)
+    if err != nil || n < 0 {
+        s.fail(name, value, "a non-negative number")
+        return
+    }
+    *dst = n
+}
+
+func (s *settingsReader) boolean(name string, dst *bool) {
+    value := s.get(name)
+    if value == "" {
+        return
+    }
+    b, err := strconv.ParseBool(value)
+    if err != nil {
+        s.fail(name, value, "true or false")
+        return
+    }
+    *dst = b
+}
+
+// level reads a log level name.
+func (s *settingsReader) level(name string, dst *logLevel) {
+    value := s.get(name)
+    if value == "" {
+        return
+    }
+    level, ok := parseLogLevel(value)
+    if !ok {
+        s.fail(name, value, "debug, info or error")
+        return
+    }
+    *dst = level
+}
+
+// duration reads a duration such as 1m30s, which must be positive unless
+// zero is allowed.
+func (s *settingsReader) duration(name string, zeroOK bool, dst *time.Duration) {
+    value := s.get(name)
+    if value == "" {
+        return
+    }
+    d, err := time.ParseDuration(value)
+    if err != nil || d < 0 || d == 0 && !zeroOK {
+        want := "a positive duration such as 30s"
+        if zeroOK {
+            want = "a duration such as 30s, or 0"
+        }
+        s.fail(name, value, want)
+        return
+    }
+    *dst = d
+}
+
+// apply overrides the fields of c with the settings returned by get,
+// keyed by the environment variable names noted on each field.
+func (c *Config) apply(get func(string) string) error {
+    s := &settingsReader{get: get}
+    s.text("ADDR", &c.Addr)
+    s.port("PORT", &c.Port)
+    s.text("TLS_CERT", &c.TLSCert)
+    s.text("TLS_KEY", &c.TLSKey)
+    s.text("STORE_FILE", &c.StoreFile)
+    s.level("LOG_LEVEL", &c.LogLevel)
+    s.url("RANDOMUSER_BASE_URL", &c.RandomUserBaseURL)
+    s.url("POKEAPI_BASE_URL", &c.PokeAPIBaseURL)
+    s.urls("POKEAPI_MIRRORS", &c.PokeAPIMirrors)
+    s.integer("MAX_POKEMON_ID", 1, &c.MaxPokemonID)
+    s.duration("POKEMON_MAX_AGE", true, &c.PokemonMaxAge)
+    s.integer("AUTOCOMPLETE_MAX", 1, &c.AutocompleteMax)
+    s.duration("CACHE_TTL", false, &c.CacheTTL)
+    s.boolean("STALE_IF_ERROR", &c.StaleIfError)
+    s.integer("CACHE_MAX_ENTRIES", 0, &c.CacheMaxEntries)
+    s.duration("USER_STREAM_INTERVAL", false, &c.UserStreamInterval)
+    s.number("RATE_LIMIT_RPS", &c.RateLimit)
+    s.integer("RATE_LIMIT_BURST", 1, &c.RateBurst)
+    s.boolean("TRUST_PROXY", &c.TrustProxy)
+    s.list("CORS_ALLOWED_ORIGINS", &c.CORSAllowedOrigins)
+    s.list("API_KEYS", &c.APIKeys)
+    s.duration("IDEMPOTENCY_TTL", false, &c.IdempotencyTTL)
+    s.integer("BREAKER_THRESHOLD", 1, &c.BreakerThreshold)
+    s.duration("BREAKER_COOLDOWN", false, &c.BreakerCooldown)
+    s.integer64("MAX_BODY_BYTES", 1, &c.MaxBodyBytes)
+    s.integer("LOG_BODY_BYTES", 0, &c.LogBodyBytes)
+    s.duration("HANDLER_TIMEOUT", true, &c.HandlerTimeout)
+    s.duration("READ_HEADER_TIMEOUT", true, &c.ReadHeaderTimeout)
+    s.duration("READ_TIMEOUT", true, &c.ReadTimeout)
+    s.duration("WRITE_TIMEOUT", true, &c.WriteTimeout)
+    s.duration("IDLE_TIMEOUT", true, &c.IdleTimeout)
+    s.duration("UPSTREAM_TIMEOUT", false, &c.UpstreamTimeout)
+    s.text("UPSTREAM_USER_AGENT", &c.UpstreamUserAgent)
+    s.integer("MAX_UPSTREAM_CONCURRENCY", 0, &c.MaxUpstreamConcurrency)
+    s.integer("UPSTREAM_MAX_IDLE_CONNS", 1, &c.UpstreamMaxIdleConns)
+    s.integer("UPSTREAM_MAX_IDLE_CONNS_PER_HOST", 1, &c.UpstreamMaxIdleConnsPerHost)
+    s.duration("UPSTREAM_IDLE_CONN_TIMEOUT", false, &c.UpstreamIdleConnTimeout)
+    s.integer("UPSTREAM_RETRIES", 0, &c.UpstreamRetries)
+    s.duration("UPSTREAM_CHECK_INTERVAL", false, &c.UpstreamCheckInterval)
+    s.choice("TRACE_EXPORTER", []string{"none", "stdout", "otlp"}, &c.TraceExporter)
+    s.flags("FEATURES", featureNames(), &c.Features)
+    return s.err
+}
+
+// configFlags maps the flags that override a Config field to the
+// setting they stand for.
+var configFlags = map[string]string{
+    "addr": "ADDR",
+    "tls-cert": "TLS_CERT",
+    "tls-key": "TLS_KEY",
+    "store-file": "STORE_FILE",
+    "cache-ttl": "CACHE_TTL",
+    "trust-proxy": "TRUST_PROXY",
+    "upstream-retries": "UPSTREAM_RETRIES",
+}
+
+// applyFlags overrides the fields of c with the configFlags given on the
+// command line parsed by fs, each validated as the setting it stands for;
+// flags left unset change nothing.
+func applyFlags(c *Config, fs *flag.FlagSet) error {
+    var err error
+    fs.Visit(func(f *flag.Flag) {
+        name, ok := configFlags[f.Name]
+        if !ok || err != nil {
+            return
+        }
+        value := f.Value.String()
+        if applyErr := c.apply(func(setting string) string {
+            if setting == name {
+                return value
+            }
+            return ""
+        }); applyErr != nil {
+            err = fmt.Errorf("-%s: %v", f.Name, applyErr)
+        }
+    })
+    return err
+}
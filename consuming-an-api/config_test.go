@@ -0,0 +1,232 @@
+package main
+
+import (
+    "flag"
+    "io/ioutil"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "testing"
+    "time"
+)
+
+// overrideConfig returns the global config for the test to modify and
+// restores it when the test ends.
+func overrideConfig(t *testing.T) *Config {
+    saved := config
+    t.Cleanup(func() { config = saved })
+    return &config
+}
+
+// TestConfigFromEnv points both upstreams at a mock server through the
+// environment, checking that the handlers call it.
+func TestConfigFromEnv(t *testing.T) {
+    var paths []string
+    upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        paths = append(paths, r.URL.Path)
+        w.Write([]byte(`{}`))
+    }))
+    defer upstream.Close()
+
+    defer os.Setenv("RANDOMUSER_BASE_URL", os.Getenv("RANDOMUSER_BASE_URL"))
+    defer os.Setenv("POKEAPI_BASE_URL", os.Getenv("POKEAPI_BASE_URL"))
+    os.Setenv("RANDOMUSER_BASE_URL", upstream.URL + "/users/")
+    os.Setenv("POKEAPI_BASE_URL", upstream.URL + "/pokeapi")
+
+    c, err := loadConfig("", nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    *overrideConfig(t) = c
+    if config.RandomUserBaseURL != upstream.URL + "/users" {
+        t.Fatalf("RandomUserBaseURL = %q, want %q", config.RandomUserBaseURL, upstream.URL + "/users")
+    }
+    usePokemonCache(t, defaultCacheTTL)
+
+    router := newRouter()
+    for _, path := range []string{"/retornarUsuarioAleatorio", "/retornarPokemon/ditto"} {
+        w := httptest.NewRecorder()
+        router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+        if w.Code != http.StatusOK {
+            t.Fatalf("GET %s = %d, want %d", path, w.Code, http.StatusOK)
+        }
+    }
+
+    want := []string{"/users/", "/pokeapi/pokemon/ditto"}
+    if len(paths) != 2 || paths[0] != want[0] || paths[1] != want[1] {
+        t.Fatalf("upstream paths = %v, want %v", paths, want)
+    }
+}
+
+// setenv sets the environment variable name until the test ends.
+func setenv(t *testing.T, name, value string) {
+    saved, ok := os.LookupEnv(name)
+    os.Setenv(name, value)
+    t.Cleanup(func() {
+        if ok {
+            os.Setenv(name, saved)
+        } else {
+            os.Unsetenv(name)
+        }
+    })
+}
+
+// writeConfigFile writes a config file with content for the test.
+func writeConfigFile(t *testing.T, content string) string {
+    path := filepath.Join(t.TempDir(), "config.json")
+    if err := ioutil.WriteFile(path, []byte(content), 0o600); err != nil {
+        t.Fatal(err)
+    }
+    return path
+}
+
+// parseFlags parses args with the configFlags main defines.
+func parseFlags(t *testing.T, args ...string) *flag.FlagSet {
+    fs := flag.NewFlagSet("test", flag.ContinueOnError)
+    fs.String("addr", "", "")
+    fs.String("tls-cert", "", "")
+    fs.String("tls-key", "", "")
+    fs.String("store-file", "", "")
+    fs.Duration("cache-ttl", defaultCacheTTL, "")
+    fs.Bool("trust-proxy", false, "")
+    fs.Int("upstream-retries", defaultConfig.UpstreamRetries, "")
+    if err := fs.Parse(args); err != nil {
+        t.Fatal(err)
+    }
+    return fs
+}
+
+// TestLoadConfigPrecedence sets settings in the file, the environment
+// and the flags, checking that flags beat the environment, which beats
+// the file, which beats the defaults.
+func TestLoadConfigPrecedence(t *testing.T) {
+    path := writeConfigFile(t, `{
+        "CACHE_TTL": "1m",
+        "MAX_POKEMON_ID": 151,
+        "RATE_LIMIT_RPS": 7,
+        "API_KEYS": ["a", "b"]
+    }`)
+    setenv(t, "CACHE_TTL", "2m")
+    setenv(t, "MAX_POKEMON_ID", "251")
+
+    c, err := loadConfig(path, parseFlags(t, "-cache-ttl=3m"))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if c.CacheTTL != 3 * time.Minute {
+        t.Errorf("CacheTTL = %s, want the flag's 3m", c.CacheTTL)
+    }
+    if c.MaxPokemonID != 251 {
+        t.Errorf("MaxPokemonID = %d, want the environment's 251", c.MaxPokemonID)
+    }
+    if c.RateLimit != 7 || !reflect.DeepEqual(c.APIKeys, []string{"a", "b"}) {
+        t.Errorf("RateLimit = %v, APIKeys = %v, want the file's 7 and [a b]", c.RateLimit, c.APIKeys)
+    }
+    if c.RateBurst != defaultConfig.RateBurst || c.TrustProxy {
+        t.Errorf("RateBurst = %d, TrustProxy = %v, want the defaults", c.RateBurst, c.TrustProxy)
+    }
+}
+
+// TestLoadConfigFeatures reads feature flags from the environment and, as
+// an object, from the config file.
+func TestLoadConfigFeatures(t *testing.T) {
+    setenv(t, "FEATURES", "batch=false, ws=true")
+    c, err := loadConfig("", nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if want := map[string]bool{"batch": false, "ws": true}; !reflect.DeepEqual(c.Features, want) {
+        t.Errorf("Features from the environment = %v, want %v", c.Features, want)
+    }
+
+    setenv(t, "FEATURES", "")
+    c, err = loadConfig(writeConfigFile(t, `{"FEATURES": {"stream": false}}`), nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if want := map[string]bool{"stream": false}; !reflect.DeepEqual(c.Features, want) {
+        t.Errorf("Features from the file = %v, want %v", c.Features, want)
+    }
+}
+
+// TestLoadConfigInvalid checks that bad settings fail with a message
+// naming them.
+func TestLoadConfigInvalid(t *testing.T) {
+    tests := []struct {
+        file string
+        want string
+    }{
+        {`{"MAX_POKEMON_ID": 0}`, "MAX_POKEMON_ID"},
+        {`{"POKEAPI_BASE_URL": "pokeapi.co"}`, "POKEAPI_BASE_URL"},
+        {`{"CACHE_TTL": "soon"}`, "CACHE_TTL"},
+        {`{"NO_SUCH_SETTING": 1}`, "NO_SUCH_SETTING"},
+        {`{"RATE_LIMIT_RPS": {"per": "second"}}`, "RATE_LIMIT_RPS"},
+        {`{"FEATURES": "batch=maybe"}`, "FEATURES"},
+        {`{"FEATURES": {"no-such-feature": false}}`, "FEATURES"},
+        {`not json`, "config file"},
+    }
+    for _, tt := range tests {
+        _, err := loadConfig(writeConfigFile(t, tt.file), nil)
+        if err == nil || !strings.Contains(err.Error(), tt.want) {
+            t.Errorf("loadConfig(%s) error = %v, want one naming %s", tt.file, err, tt.want)
+        }
+    }
+
+    setenv(t, "RATE_LIMIT_BURST", "-1")
+    if _, err := loadConfig("", nil); err == nil || !strings.Contains(err.Error(), "RATE_LIMIT_BURST") {
+        t.Errorf("loadConfig with RATE_LIMIT_BURST=-1 error = %v, want one naming it", err)
+    }
+}
+
+// TestLoadConfigInvalidFlags checks that flags are held to the same rules
+// as the settings they stand for.
+func TestLoadConfigInvalidFlags(t *testing.T) {
+    tests := []struct {
+        args []string
+        want string
+    }{
+        {[]string{"-cache-ttl=0s"}, "-cache-ttl"},
+        {[]string{"-cache-ttl=-1m"}, "-cache-ttl"},
+        {[]string{"-upstream-retries=-1"}, "-upstream-retries"},
+        {[]string{"-tls-cert=cert.pem"}, "TLS_KEY"},
+    }
+    for _, tt := range tests {
+        _, err := loadConfig("", parseFlags(t, tt.args...))
+        if err == nil || !strings.Contains(err.Error(), tt.want) {
+            t.Errorf("loadConfig with %v error = %v, want one naming %s", tt.args, err, tt.want)
+        }
+    }
+}
+
+// TestLoadConfigServerSettings reads the listen port, TLS files, store
+// file, retries, log level and proxy trust from the config file.
+func TestLoadConfigServerSettings(t *testing.T) {
+    c, err := loadConfig(writeConfigFile(t, `{
+        "PORT": 8080,
+        "TLS_CERT": "cert.pem",
+        "TLS_KEY": "key.pem",
+        "STORE_FILE": "pokemons.json",
+        "UPSTREAM_RETRIES": 1,
+        "LOG_LEVEL": "debug",
+        "TRUST_PROXY": true
+    }`), nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if listenAddr(c) != ":8080" || c.TLSCert != "cert.pem" || c.TLSKey != "key.pem" || c.StoreFile != "pokemons.json" {
+        t.Errorf("listen address %q, TLS %q %q, store file %q, want the file's", listenAddr(c), c.TLSCert, c.TLSKey, c.StoreFile)
+    }
+    if c.UpstreamRetries != 1 || c.LogLevel != levelDebug || !c.TrustProxy {
+        t.Errorf("UpstreamRetries = %d, LogLevel = %v, TrustProxy = %v, want the file's", c.UpstreamRetries, c.LogLevel, c.TrustProxy)
+    }
+
+    for _, file := range []string{`{"PORT": 70000}`, `{"LOG_LEVEL": "loud"}`, `{"UPSTREAM_RETRIES": -1}`} {
+        if _, err := loadConfig(writeConfigFile(t, file), nil); err == nil {
+            t.Errorf("loadConfig(%s) succeeded, want an error", file)
+        }
+    }
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+    "net/http"
+    "strings"
+
+    "github.com/julienschmidt/httprouter"
+)
+
+// corsAllowedHeaders are the request headers browsers may send on
+// cross-origin requests.
+const corsAllowedHeaders = "Content-Type, Accept, Accept-Language, X-API-Key, Idempotency-Key, If-Match, If-None-Match, X-Request-ID"
+
+// corsExposedHeaders are the response headers that scripts on the allowed
+// origins may read, besides the ones browsers always let through.
+const corsExposedHeaders = "ETag, X-Request-ID"
+
+// corsPolicy lets browsers on the allowed origins call the API. An origin
+// of "*" allows every origin.
+type corsPolicy struct {
+    origins []string
+}
+
+// allow sets Access-Control-Allow-Origin on h when origin is allowed and
+// reports whether it was.
+func (c corsPolicy) allow(h http.Header, origin string) bool {
+    if origin == "" {
+        return false
+    }
+    for _, allowed := range c.origins {
+        if allowed == "*" {
+            h.Set("Access-Control-Allow-Origin", "*")
+            return true
+        }
+        if allowed == origin {
+            h.Set("Access-Control-Allow-Origin", origin)
+            h.Add("Vary", "Origin")
+            return true
+        }
+    }
+    return false
+}
+
+// handle adds the CORS headers to the responses of next.
+func (c corsPolicy) handle(next httprouter.Handle) httprouter.Handle {
+    return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+        if c.allow(w.Header(), r.Header.Get("Origin")) {
+            w.Header().Set("Access-Control-Expose-Headers", corsExposedHeaders)
+        }
+        next(w, r, ps)
+    }
+}
+
+// preflight answers OPTIONS requests. It is meant to be the router's
+// GlobalOPTIONS handler, which runs after the router has filled in the
+// Allow header with the methods registered for the path.
+func (c corsPolicy) preflight(w http.ResponseWriter, r *http.Request) {
+    header := w.Header()
+    if r.Header.Get("Access-Control-Request-Method") != "" && c.allow(header, r.Header.Get("Origin")) {
+        header.Set("Access-Control-Allow-Methods", header.Get("Allow"))
+        header.Set("Access-Control-Allow-Headers", corsAllowedHeaders)
+    }
+    w.WriteHeader(http.StatusNoContent)
+}
+
+// splitList splits a comma-separated list, dropping blank entries.
+func splitList(s string) []string {
+    var items []string
+    for _, item := range strings.Split(s, ",") {
+        if item = strings.TrimSpace(item); item != "" {
+            items = append(items, item)
+        }
+    }
+    return items
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+)
+
+// TestCorsSimpleRequest sends a GET with an Origin, checking the allowed
+// origin is reported.
+func TestCorsSimpleRequest(t *testing.T) {
+    tests := []struct {
+        origins []string
+        origin, want string
+    }{
+        {[]string{"*"}, "https://example.com", "*"},
+        {[]string{"https://example.com"}, "https://example.com", "https://example.com"},
+        {[]string{"https://example.com"}, "https://evil.example", ""},
+    }
+    for _, tt := range tests {
+        overrideConfig(t).CORSAllowedOrigins = tt.origins
+        w := httptest.NewRecorder()
+        r := httptest.NewRequest(http.MethodGet, "/retornarStruct", nil)
+        r.Header.Set("Origin", tt.origin)
+
+        newRouter().ServeHTTP(w, r)
+
+        if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.want {
+            t.Errorf("origins %v, Origin %s: Access-Control-Allow-Origin = %q, want %q", tt.origins, tt.origin, got, tt.want)
+        }
+    }
+}
+
+// TestCorsPreflight sends an OPTIONS preflight, checking for a 204 with
+// the allowed methods and headers.
+func TestCorsPreflight(t *testing.T) {
+    w := httptest.NewRecorder()
+    r := httptest.NewRequest(http.MethodOptions, "/criarPokemon", nil)
+    r.Header.Set("Origin", "https://example.com")
+    r.Header.Set("Access-Control-Request-Method", "POST")
+
+    newRouter().ServeHTTP(w, r)
+
+    if w.Code != http.StatusNoContent {
+        t.Fatalf("OPTIONS /criarPokemon status = %d, want %d", w.Code, http.StatusNoContent)
+    }
+    if got := w.Header().Get("Access-Control-Allow-Methods"); !strings.Contains(got, "POST") {
+        t.Errorf("Access-Control-Allow-Methods = %q, want it to contain POST", got)
+    }
+    if got := w.Header().Get("Access-Control-Allow-Headers"); !strings.Contains(got, "Content-Type") {
+        t.Errorf("Access-Control-Allow-Headers = %q, want it to contain Content-Type", got)
+    }
+    if got := w.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+        t.Errorf("Access-Control-Allow-Origin = %q, want *", got)
+    }
+}
+
+// TestCorsPreflightIdempotencyKey sends the preflight a browser makes
+// before a POST with an Idempotency-Key, checking the header is allowed.
+func TestCorsPreflightIdempotencyKey(t *testing.T) {
+    w := httptest.NewRecorder()
+    r := httptest.NewRequest(http.MethodOptions, "/criarPokemon", nil)
+    r.Header.Set("Origin", "https://example.com")
+    r.Header.Set("Access-Control-Request-Method", "POST")
+    r.Header.Set("Access-Control-Request-Headers", "content-type,idempotency-key")
+
+    newRouter().ServeHTTP(w, r)
+
+    if got := w.Header().Get("Access-Control-Allow-Headers"); !strings.Contains(got, "Idempotency-Key") {
+        t.Errorf("Access-Control-Allow-Headers = %q, want it to contain Idempotency-Key", got)
+    }
+}
+
+// TestCorsConditionalHeaders checks that the headers of conditional
+// requests and request IDs may be sent cross-origin, and that the ETag
+// and request ID of the reply can be read.
+func TestCorsConditionalHeaders(t *testing.T) {
+    router := newRouter()
+
+    w := httptest.NewRecorder()
+    r := httptest.NewRequest(http.MethodOptions, "/pokemon/pikachu", nil)
+    r.Header.Set("Origin", "https://example.com")
+    r.Header.Set("Access-Control-Request-Method", "PUT")
+    r.Header.Set("Access-Control-Request-Headers", "if-match,if-none-match,x-request-id")
+    router.ServeHTTP(w, r)
+    allowed := w.Header().Get("Access-Control-Allow-Headers")
+    for _, name := range []string{"If-Match", "If-None-Match", "X-Request-ID"} {
+        if !strings.Contains(allowed, name) {
+            t.Errorf("Access-Control-Allow-Headers = %q, want it to contain %s", allowed, name)
+        }
+    }
+
+    w = httptest.NewRecorder()
+    r = httptest.NewRequest(http.MethodGet, "/retornarStruct", nil)
+    r.Header.Set("Origin", "https://example.com")
+    router.ServeHTTP(w, r)
+    exposed := w.Header().Get("Access-Control-Expose-Headers")
+    for _, name := range []string{"ETag", "X-Request-ID"} {
+        if !strings.Contains(exposed, name) {
+            t.Errorf("Access-Control-Expose-Headers = %q, want it to contain %s", exposed, name)
+        }
+    }
+}
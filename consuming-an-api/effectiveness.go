@@ -0,0 +1,43 @@
+package main
+
+import (
+    "net/http"
+
+    "github.com/julienschmidt/httprouter"
+)
+
+// typeEffectiveness lists the types an attacking type is strong, weak and
+// useless against.
+type typeEffectiveness struct {
+    Type string `json:"type"`
+    DoubleDamageTo []string `json:"double_damage_to"`
+    HalfDamageTo []string `json:"half_damage_to"`
+    NoDamageTo []string `json:"no_damage_to"`
+}
+
+// resourceNames returns the names of rs, never nil so that an empty list
+// is [] in JSON.
+func resourceNames(rs []namedResource) []string {
+    names := make([]string, 0, len(rs))
+    for _, r := range rs {
+        names = append(names, r.Name)
+    }
+    return names
+}
+
+// retornarEfetividade replies with the damage relations of a type.
+func retornarEfetividade(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+    name := ps.ByName("name")
+    t, err := fetchType(r.Context(), name)
+    if err != nil {
+        writeHttpError(w, err)
+        return
+    }
+
+    writeJsonFor(w, r, http.StatusOK, typeEffectiveness{
+        Type: name,
+        DoubleDamageTo: resourceNames(t.DamageRelations.DoubleDamageTo),
+        HalfDamageTo: resourceNames(t.DamageRelations.HalfDamageTo),
+        NoDamageTo: resourceNames(t.DamageRelations.NoDamageTo),
+    })
+}
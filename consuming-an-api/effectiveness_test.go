@@ -0,0 +1,45 @@
+package main
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "reflect"
+    "testing"
+)
+
+// TestRetornarEfetividade reads the damage relations of a recorded type
+// payload.
+func TestRetornarEfetividade(t *testing.T) {
+    pokeApiFixture(t, "type-electric.json")
+
+    w := httptest.NewRecorder()
+    newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/type/electric/effectiveness", nil))
+    if w.Code != http.StatusOK {
+        t.Fatalf("GET /type/electric/effectiveness status = %d, want %d", w.Code, http.StatusOK)
+    }
+    var got typeEffectiveness
+    if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+        t.Fatal(err)
+    }
+    want := typeEffectiveness{
+        Type: "electric",
+        DoubleDamageTo: []string{"flying", "water"},
+        HalfDamageTo: []string{"grass", "electric", "dragon"},
+        NoDamageTo: []string{"ground"},
+    }
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("GET /type/electric/effectiveness = %+v, want %+v", got, want)
+    }
+}
+
+// TestRetornarEfetividadeUnknown checks that an unknown type is a 404.
+func TestRetornarEfetividadeUnknown(t *testing.T) {
+    usePokeApi(t, http.NotFoundHandler())
+
+    w := httptest.NewRecorder()
+    newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/type/shadow/effectiveness", nil))
+    if w.Code != http.StatusNotFound {
+        t.Errorf("GET /type/shadow/effectiveness status = %d, want %d", w.Code, http.StatusNotFound)
+    }
+}
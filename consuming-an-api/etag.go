@@ -0,0 +1,59 @@
+package main
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "net/http"
+    "strconv"
+    "strings"
+)
+
+// writeJsonWithETag replies with v encoded as JSON and an ETag derived
+// from the encoding. When the request's If-None-Match already names that
+// ETag the client's copy is current, and a bodyless 304 is sent instead.
+func writeJsonWithETag(w http.ResponseWriter, r *http.Request, v interface{}) {
+    b, err := marshalJson(r, v)
+    if err != nil {
+        logErrorf("%v", err)
+        writeError(w, http.StatusInternalServerError, "could not encode response")
+        return
+    }
+
+    sum := sha256.Sum256(b)
+    etag := `"` + hex.EncodeToString(sum[:]) + `"`
+    w.Header().Set("ETag", etag)
+    if etagMatches(r.Header.Get("If-None-Match"), etag) {
+        w.WriteHeader(http.StatusNotModified)
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    w.Write(b)
+}
+
+// versionETag formats a store version as an ETag.
+func versionETag(version int) string {
+    return `"` + strconv.Itoa(version) + `"`
+}
+
+// parseVersionETag parses an ETag made by versionETag.
+func parseVersionETag(etag string) (int, bool) {
+    version, err := strconv.Atoi(strings.Trim(strings.TrimSpace(etag), `"`))
+    if err != nil || version < 1 {
+        return 0, false
+    }
+    return version, true
+}
+
+// etagMatches reports whether the If-None-Match header value ifNoneMatch
+// names etag. Weak validators match too, as If-None-Match uses the weak
+// comparison.
+func etagMatches(ifNoneMatch, etag string) bool {
+    for _, candidate := range strings.Split(ifNoneMatch, ",") {
+        candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+        if candidate == "*" || candidate == etag {
+            return true
+        }
+    }
+    return false
+}
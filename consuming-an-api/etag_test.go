@@ -0,0 +1,49 @@
+package main
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "testing"
+)
+
+// TestRetornarPokemonETag fetches a Pokemon, then repeats the request with
+// its ETag in If-None-Match, checking for a bodyless 304.
+func TestRetornarPokemonETag(t *testing.T) {
+    mockPokeApi(t, "pikachu")
+    router := newRouter()
+
+    w := httptest.NewRecorder()
+    router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/retornarPokemon/pikachu", nil))
+    etag := w.Header().Get("ETag")
+    if w.Code != http.StatusOK || etag == "" {
+        t.Fatalf("first request = %d with ETag %q, want 200 with an ETag", w.Code, etag)
+    }
+
+    w = httptest.NewRecorder()
+    r := httptest.NewRequest(http.MethodGet, "/retornarPokemon/pikachu", nil)
+    r.Header.Set("If-None-Match", etag)
+    router.ServeHTTP(w, r)
+    if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+        t.Fatalf("conditional request = %d with %d body bytes, want 304 with none", w.Code, w.Body.Len())
+    }
+}
+
+// TestEtagMatches checks the If-None-Match forms that match an ETag.
+func TestEtagMatches(t *testing.T) {
+    tests := []struct {
+        header string
+        want bool
+    }{
+        {`"abc"`, true},
+        {`W/"abc"`, true},
+        {`"xyz", "abc"`, true},
+        {`*`, true},
+        {`"xyz"`, false},
+        {``, false},
+    }
+    for _, tt := range tests {
+        if got := etagMatches(tt.header, `"abc"`); got != tt.want {
+            t.Errorf(`etagMatches(%q, "abc") = %v, want %v`, tt.header, got, tt.want)
+        }
+    }
+}
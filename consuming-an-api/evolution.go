@@ -0,0 +1,97 @@
+package main
+
+import (
+    "context"
+    "net/http"
+
+    "github.com/julienschmidt/httprouter"
+)
+
+// pokeApiSpecies mirrors the parts of the PokéAPI species payload we
+// decode.
+type pokeApiSpecies struct {
+    EvolutionChain struct {
+        URL string `json:"url"`
+    } `json:"evolution_chain"`
+    FlavorTextEntries []flavorTextEntry `json:"flavor_text_entries"`
+}
+
+// chainLink is one stage of a PokéAPI evolution chain.
+type chainLink struct {
+    Species struct {
+        Name string `json:"name"`
+    } `json:"species"`
+    EvolvesTo []chainLink `json:"evolves_to"`
+}
+
+// pokeApiEvolutionChain mirrors the PokéAPI evolution chain payload.
+type pokeApiEvolutionChain struct {
+    Chain chainLink `json:"chain"`
+}
+
+// fetchPokeApiJson decodes the PokéAPI resource at url into v. Like
+// Pokemon, these resources never change, so they are cached by URL.
+func fetchPokeApiJson(ctx context.Context, url string, v interface{}) error {
+    responseData, ok := pokemonCache.get(url)
+    if !ok {
+        err := pokeApiBreaker.call(ctx, func() error {
+            var err error
+            responseData, err = fetchUpstream(ctx, url)
+            return err
+        })
+        if err != nil {
+            return err
+        }
+        pokemonCache.set(url, responseData)
+    }
+
+    return decodeUpstreamJSON(responseData, v)
+}
+
+// evolutionStages lists the species in chain stage by stage, so that
+// every branch of a stage comes before the next stage.
+func evolutionStages(chain chainLink) []string {
+    var names []string
+    stage := []chainLink{chain}
+    for len(stage) > 0 {
+        var next []chainLink
+        for _, link := range stage {
+            names = append(names, link.Species.Name)
+            next = append(next, link.EvolvesTo...)
+        }
+        stage = next
+    }
+    return names
+}
+
+// retornarEvolucao follows a Pokemon to its species and from there to its
+// evolution chain, replying with the species names in evolution order.
+func retornarEvolucao(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+    pokemon, err := fetchRawPokemon(r.Context(), ps.ByName("nome"))
+    if err != nil {
+        writeHttpError(w, err)
+        return
+    }
+    if pokemon.Species.URL == "" {
+        writeError(w, http.StatusBadGateway, "invalid upstream response")
+        return
+    }
+
+    var species pokeApiSpecies
+    if err := fetchPokeApiJson(r.Context(), pokemon.Species.URL, &species); err != nil {
+        writeHttpError(w, err)
+        return
+    }
+    if species.EvolutionChain.URL == "" {
+        // Some species are not part of any chain.
+        writeJsonFor(w, r, http.StatusOK, []string{pokemon.Name})
+        return
+    }
+
+    var chain pokeApiEvolutionChain
+    if err := fetchPokeApiJson(r.Context(), species.EvolutionChain.URL, &chain); err != nil {
+        writeHttpError(w, err)
+        return
+    }
+    writeJsonFor(w, r, http.StatusOK, evolutionStages(chain.Chain))
+}
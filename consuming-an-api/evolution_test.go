@@ -0,0 +1,47 @@
+package main
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "testing"
+)
+
+// TestRetornarEvolucao follows linear and branching chains, checking the
+// stage list.
+func TestRetornarEvolucao(t *testing.T) {
+    tests := []struct {
+        name, chain, want string
+    }{
+        {
+            "linear",
+            `{"chain":{"species":{"name":"pichu"},"evolves_to":[{"species":{"name":"pikachu"},"evolves_to":[{"species":{"name":"raichu"},"evolves_to":[]}]}]}}`,
+            `["pichu","pikachu","raichu"]`,
+        },
+        {
+            "branching",
+            `{"chain":{"species":{"name":"eevee"},"evolves_to":[{"species":{"name":"vaporeon"},"evolves_to":[]},{"species":{"name":"jolteon"},"evolves_to":[]}]}}`,
+            `["eevee","vaporeon","jolteon"]`,
+        },
+    }
+    for _, tt := range tests {
+        mockEvolutionPokeApi(t, tt.chain)
+
+        w := httptest.NewRecorder()
+        newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemons/evolution/pikachu", nil))
+        if w.Code != http.StatusOK || w.Body.String() != tt.want {
+            t.Errorf("%s: GET /pokemons/evolution/pikachu = %d %s, want 200 %s", tt.name, w.Code, w.Body, tt.want)
+        }
+    }
+}
+
+// TestRetornarEvolucaoNotFound asks for an unknown Pokemon, checking for
+// a 404.
+func TestRetornarEvolucaoNotFound(t *testing.T) {
+    mockPokeApi(t)
+
+    w := httptest.NewRecorder()
+    newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemons/evolution/missingno", nil))
+    if w.Code != http.StatusNotFound {
+        t.Errorf("GET /pokemons/evolution/missingno status = %d, want %d", w.Code, http.StatusNotFound)
+    }
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+    "net/http"
+    "sort"
+    "sync"
+
+    "github.com/julienschmidt/httprouter"
+)
+
+// featureFlags switches the routes tagged with a feature on and off.
+// Features are on unless switched off.
+type featureFlags struct {
+    mu sync.RWMutex
+    off map[string]bool
+}
+
+// newFeatureFlags starts with the features listed in states, such as
+// config.Features.
+func newFeatureFlags(states map[string]bool) *featureFlags {
+    f := &featureFlags{off: make(map[string]bool)}
+    for name, on := range states {
+        f.set(name, on)
+    }
+    return f
+}
+
+// features holds the state of every feature, as set by $FEATURES and
+// listed at /debug/features.
+var features = newFeatureFlags(nil)
+
+func (f *featureFlags) enabled(name string) bool {
+    f.mu.RLock()
+    defer f.mu.RUnlock()
+
+    return !f.off[name]
+}
+
+func (f *featureFlags) set(name string, on bool) {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+
+    if on {
+        delete(f.off, name)
+    } else {
+        f.off[name] = true
+    }
+}
+
+// states returns whether each known feature is on.
+func (f *featureFlags) states() map[string]bool {
+    states := make(map[string]bool)
+    for _, name := range featureNames() {
+        states[name] = f.enabled(name)
+    }
+    return states
+}
+
+// featureNames lists the features the routes are tagged with.
+func featureNames() []string {
+    var names []string
+    seen := make(map[string]bool)
+    for _, rt := range routes() {
+        if rt.Feature != "" && !seen[rt.Feature] {
+            seen[rt.Feature] = true
+            names = append(names, rt.Feature)
+        }
+    }
+    sort.Strings(names)
+    return names
+}
+
+// requireFeature answers 503 to requests to next while the feature name
+// is switched off.
+func requireFeature(name string, next httprouter.Handle) httprouter.Handle {
+    return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+        if !features.enabled(name) {
+            writeErrorCode(w, http.StatusServiceUnavailable, "feature_disabled", name + " is disabled")
+            return
+        }
+        next(w, r, ps)
+    }
+}
+
+// retornarFeatures lists whether each feature is on.
+func retornarFeatures(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+    writeJsonFor(w, r, http.StatusOK, features.states())
+}
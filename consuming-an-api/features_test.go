@@ -0,0 +1,70 @@
+package main
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+// useFeatures sets the feature flags to states until the test ends.
+func useFeatures(t *testing.T, states map[string]bool) {
+    saved := features
+    features = newFeatureFlags(states)
+    t.Cleanup(func() { features = saved })
+}
+
+// TestRequireFeature switches the batch endpoint off, checking that it is
+// refused while the other routes still work.
+func TestRequireFeature(t *testing.T) {
+    mockPokeApi(t, "pikachu")
+    useFeatures(t, map[string]bool{"batch": false})
+    router := newRouter()
+
+    w := httptest.NewRecorder()
+    router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemons/batch?names=pikachu", nil))
+    if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "feature_disabled") {
+        t.Errorf("GET /pokemons/batch while off = %d %s, want a 503 feature_disabled", w.Code, w.Body)
+    }
+
+    for _, path := range []string{"/retornarPokemon/pikachu", "/pokemons/stream?names=pikachu"} {
+        w := httptest.NewRecorder()
+        router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+        if w.Code != http.StatusOK {
+            t.Errorf("GET %s = %d %s, want %d", path, w.Code, w.Body, http.StatusOK)
+        }
+    }
+}
+
+// TestRetornarFeatures checks that /debug/features lists every feature,
+// with the ones switched off through the config as off, and that they
+// cannot be switched over HTTP.
+func TestRetornarFeatures(t *testing.T) {
+    useFeatures(t, map[string]bool{"batch": false})
+    router := newRouter()
+
+    w := httptest.NewRecorder()
+    router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/features", nil))
+    var states map[string]bool
+    if err := json.Unmarshal(w.Body.Bytes(), &states); err != nil {
+        t.Fatalf("GET /debug/features = %d %s: %v", w.Code, w.Body, err)
+    }
+    want := make(map[string]bool)
+    for _, name := range featureNames() {
+        want[name] = name != "batch"
+    }
+    if !reflect.DeepEqual(states, want) {
+        t.Errorf("/debug/features = %v, want %v", states, want)
+    }
+
+    w = httptest.NewRecorder()
+    router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/debug/features/batch", strings.NewReader(`{"enabled":true}`)))
+    if w.Code != http.StatusNotFound && w.Code != http.StatusMethodNotAllowed {
+        t.Errorf("PUT /debug/features/batch = %d, want it refused", w.Code)
+    }
+    if features.enabled("batch") {
+        t.Error("PUT /debug/features/batch switched batch back on")
+    }
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+    "encoding/json"
+    "net/http"
+    "reflect"
+    "sort"
+    "strings"
+)
+
+// jsonFields returns the JSON names of the fields of the struct v.
+func jsonFields(v interface{}) []string {
+    t := reflect.TypeOf(v)
+    var names []string
+    for i := 0; i < t.NumField(); i++ {
+        name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+        if name != "" && name != "-" {
+            names = append(names, name)
+        }
+    }
+    sort.Strings(names)
+    return names
+}
+
+// pokemonFields are the fields that ?fields= may pick from a Pokemon.
+var pokemonFields = jsonFields(PokemonResponse{})
+
+// parseFields reads the comma-separated fields query parameter, checking
+// each name against known. It returns nil when no fields were asked for.
+func parseFields(r *http.Request, known []string) ([]string, error) {
+    value := r.URL.Query().Get("fields")
+    if value == "" {
+        return nil, nil
+    }
+    var fields []string
+    for _, field := range strings.Split(value, ",") {
+        field = strings.TrimSpace(field)
+        if field == "" {
+            continue
+        }
+        i := sort.SearchStrings(known, field)
+        if i == len(known) || known[i] != field {
+            return nil, &httpError{http.StatusBadRequest, "unknown field " + field + ", want one of " + strings.Join(known, ", ")}
+        }
+        fields = append(fields, field)
+    }
+    return fields, nil
+}
+
+// project returns v as a JSON object holding only fields, or v itself
+// when fields is nil.
+func project(v interface{}, fields []string) (interface{}, error) {
+    if fields == nil {
+        return v, nil
+    }
+    all, err := jsonObject(v)
+    if err != nil {
+        return nil, err
+    }
+    picked := make(map[string]json.RawMessage, len(fields))
+    for _, field := range fields {
+        if value, ok := all[field]; ok {
+            picked[field] = value
+        }
+    }
+    return picked, nil
+}
+
+// jsonObject encodes v, which must encode as a JSON object, and returns
+// the encoded value of each key.
+func jsonObject(v interface{}) (map[string]json.RawMessage, error) {
+    b, err := json.Marshal(v)
+    if err != nil {
+        return nil, err
+    }
+    var object map[string]json.RawMessage
+    if err := json.Unmarshal(b, &object); err != nil {
+        return nil, err
+    }
+    return object, nil
+}
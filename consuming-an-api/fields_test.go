@@ -0,0 +1,47 @@
+package main
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "reflect"
+    "testing"
+)
+
+// TestRetornarPokemonFields asks for the name and id only, checking that
+// no other key comes back.
+func TestRetornarPokemonFields(t *testing.T) {
+    pokeApiFixture(t, "pikachu.json")
+
+    w := httptest.NewRecorder()
+    newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/retornarPokemon/pikachu?fields=name,id", nil))
+    if w.Code != http.StatusOK {
+        t.Fatalf("status = %d %s, want %d", w.Code, w.Body, http.StatusOK)
+    }
+    var body map[string]interface{}
+    if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+        t.Fatal(err)
+    }
+    if want := map[string]interface{}{"name": "pikachu", "id": 25.0}; !reflect.DeepEqual(body, want) {
+        t.Errorf("body = %v, want %v", body, want)
+    }
+}
+
+// TestRetornarPokemonUnknownField checks that a field we do not have is a
+// 400, given before calling upstream.
+func TestRetornarPokemonUnknownField(t *testing.T) {
+    overrideConfig(t).PokeAPIBaseURL = closedURL(t)
+
+    w := httptest.NewRecorder()
+    newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/retornarPokemon/pikachu?fields=name,moves", nil))
+    if w.Code != http.StatusBadRequest {
+        t.Errorf("status = %d %s, want %d", w.Code, w.Body, http.StatusBadRequest)
+    }
+}
+
+func TestJsonFields(t *testing.T) {
+    want := []string{"base_experience", "height", "id", "name", "stats", "types", "weight"}
+    if got := jsonFields(PokemonResponse{}); !reflect.DeepEqual(got, want) {
+        t.Errorf("jsonFields = %v, want %v", got, want)
+    }
+}
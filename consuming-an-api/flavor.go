@@ -0,0 +1,80 @@
+package main
+
+import (
+    "net/http"
+    "strings"
+
+    "github.com/julienschmidt/httprouter"
+)
+
+// defaultFlavorLanguage is the language of the flavor text when none is
+// asked for, or when the one asked for has none.
+const defaultFlavorLanguage = "en"
+
+// flavorTextEntry is one localized Pokédex entry of a PokéAPI species.
+type flavorTextEntry struct {
+    FlavorText string `json:"flavor_text"`
+    Language struct {
+        Name string `json:"name"`
+    } `json:"language"`
+}
+
+// flavorResponse is the reply of /pokemon/:nome/flavor.
+type flavorResponse struct {
+    Name string `json:"name"`
+    Language string `json:"language"`
+    Flavor string `json:"flavor"`
+}
+
+// cleanFlavorText undoes the layout of the game text boxes kept in
+// PokéAPI flavor text: soft hyphens at line ends, and line and page
+// breaks in place of spaces.
+func cleanFlavorText(text string) string {
+    text = strings.ReplaceAll(text, "\u00ad\n", "")
+    return strings.Join(strings.Fields(text), " ")
+}
+
+// flavorText returns the first entry of entries in lang, falling back to
+// defaultFlavorLanguage, and the language it is in.
+func flavorText(entries []flavorTextEntry, lang string) (string, string, bool) {
+    for _, want := range []string{lang, defaultFlavorLanguage} {
+        for _, entry := range entries {
+            if entry.Language.Name == want {
+                return cleanFlavorText(entry.FlavorText), want, true
+            }
+        }
+    }
+    return "", "", false
+}
+
+// retornarFlavor follows a Pokemon to its species and replies with its
+// Pokédex text in ?lang=, English by default or when there is none in
+// that language.
+func retornarFlavor(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+    lang := strings.ToLower(r.URL.Query().Get("lang"))
+    if lang == "" {
+        lang = defaultFlavorLanguage
+    }
+
+    pokemon, err := fetchRawPokemon(r.Context(), ps.ByName("nome"))
+    if err != nil {
+        writeHttpError(w, err)
+        return
+    }
+    if pokemon.Species.URL == "" {
+        writeError(w, http.StatusBadGateway, "invalid upstream response")
+        return
+    }
+    var species pokeApiSpecies
+    if err := fetchPokeApiJson(r.Context(), pokemon.Species.URL, &species); err != nil {
+        writeHttpError(w, err)
+        return
+    }
+
+    flavor, language, ok := flavorText(species.FlavorTextEntries, lang)
+    if !ok {
+        writeError(w, http.StatusNotFound, "no flavor text")
+        return
+    }
+    writeJsonFor(w, r, http.StatusOK, flavorResponse{Name: pokemon.Name, Language: language, Flavor: flavor})
+}
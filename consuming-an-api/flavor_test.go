@@ -0,0 +1,40 @@
+package main
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+)
+
+func TestRetornarFlavor(t *testing.T) {
+    mockFlavorPokeApi(t)
+
+    tests := []struct {
+        query string
+        want flavorResponse
+    }{
+        {"", flavorResponse{"pikachu", "en", "When several of these POKéMON gather, their electricity could build."}},
+        {"?lang=es", flavorResponse{"pikachu", "es", "Cuanto más potente es la energía eléctrica."}},
+        {"?lang=ja", flavorResponse{"pikachu", "en", "When several of these POKéMON gather, their electricity could build."}},
+    }
+    for _, tt := range tests {
+        w := httptest.NewRecorder()
+        newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemon/pikachu/flavor" + tt.query, nil))
+        var got flavorResponse
+        if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &got) != nil {
+            t.Fatalf("GET /pokemon/pikachu/flavor%s = %d %s, want 200", tt.query, w.Code, w.Body)
+        }
+        if got != tt.want {
+            t.Errorf("GET /pokemon/pikachu/flavor%s = %+v, want %+v", tt.query, got, tt.want)
+        }
+    }
+}
+
+func TestFlavorTextNone(t *testing.T) {
+    var entries []flavorTextEntry
+    json.Unmarshal([]byte(`[{"flavor_text":"x","language":{"name":"fr"}}]`), &entries)
+    if _, _, ok := flavorText(entries, "de"); ok {
+        t.Error("flavorText without de or en entries found one")
+    }
+}
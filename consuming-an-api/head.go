@@ -0,0 +1,51 @@
+package main
+
+import (
+    "net/http"
+    "strconv"
+
+    "github.com/julienschmidt/httprouter"
+)
+
+// headResponseWriter discards the body written through it, holding back
+// the status until the handler is done so that the Content-Length of the
+// body it would have sent can still be set.
+type headResponseWriter struct {
+    http.ResponseWriter
+    status int
+    wroteHeader bool
+    length int
+}
+
+func (w *headResponseWriter) WriteHeader(status int) {
+    if !w.wroteHeader {
+        w.status = status
+        w.wroteHeader = true
+    }
+}
+
+func (w *headResponseWriter) Write(b []byte) (int, error) {
+    w.WriteHeader(http.StatusOK)
+    w.length += len(b)
+    return len(b), nil
+}
+
+// finish sends the status, with a Content-Length unless the handler set
+// one itself or the status has no body.
+func (w *headResponseWriter) finish() {
+    header := w.ResponseWriter.Header()
+    if header.Get("Content-Length") == "" && w.status >= 200 && w.status != http.StatusNoContent && w.status != http.StatusNotModified {
+        header.Set("Content-Length", strconv.Itoa(w.length))
+    }
+    w.ResponseWriter.WriteHeader(w.status)
+}
+
+// headAsGet answers a HEAD request with the headers next sends for the
+// matching GET, without the body.
+func headAsGet(next httprouter.Handle) httprouter.Handle {
+    return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+        hw := &headResponseWriter{ResponseWriter: w, status: http.StatusOK}
+        next(hw, r, ps)
+        hw.finish()
+    }
+}
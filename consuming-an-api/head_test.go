@@ -0,0 +1,45 @@
+package main
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "strconv"
+    "testing"
+)
+
+// TestHeadAsGet sends HEAD to a Pokemon, checking that it gets the GET's
+// headers, Content-Length included, and no body.
+func TestHeadAsGet(t *testing.T) {
+    pokeApiFixture(t, "pikachu.json")
+    router := newRouter()
+
+    get := httptest.NewRecorder()
+    router.ServeHTTP(get, httptest.NewRequest(http.MethodGet, "/retornarPokemon/pikachu", nil))
+    head := httptest.NewRecorder()
+    router.ServeHTTP(head, httptest.NewRequest(http.MethodHead, "/retornarPokemon/pikachu", nil))
+
+    if head.Code != http.StatusOK {
+        t.Fatalf("HEAD status = %d, want %d", head.Code, http.StatusOK)
+    }
+    if head.Body.Len() != 0 {
+        t.Errorf("HEAD body = %q, want none", head.Body)
+    }
+    if got, want := head.Header().Get("Content-Length"), strconv.Itoa(get.Body.Len()); got != want {
+        t.Errorf("HEAD Content-Length = %q, want the GET's %s", got, want)
+    }
+    for _, name := range []string{"Content-Type", "ETag", "Cache-Control"} {
+        if got, want := head.Header().Get(name), get.Header().Get(name); got != want || got == "" {
+            t.Errorf("HEAD %s = %q, want the GET's %q", name, got, want)
+        }
+    }
+}
+
+func TestHeadAsGetNotFound(t *testing.T) {
+    mockPokeApi(t, "ditto")
+
+    w := httptest.NewRecorder()
+    newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodHead, "/retornarPokemon/missingno", nil))
+    if w.Code != http.StatusNotFound || w.Body.Len() != 0 {
+        t.Errorf("HEAD status = %d, body %q, want a bodiless %d", w.Code, w.Body, http.StatusNotFound)
+    }
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+    "context"
+    "net/http"
+    "sync/atomic"
+    "time"
+
+    "github.com/julienschmidt/httprouter"
+)
+
+// readinessTimeout bounds the upstream check done by /readyz.
+const readinessTimeout = 2 * time.Second
+
+// draining is set to 1 once shutdown starts, making /readyz fail while
+// the remaining requests are served.
+var draining int32
+
+// healthz reports that the process is alive without touching upstreams.
+func healthz(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+    writeJsonFor(w, r, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// readyz reports whether PokéAPI can be reached, so that a load balancer
+// stops routing to us while it cannot.
+func readyz(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+    if atomic.LoadInt32(&draining) == 1 {
+        writeJsonFor(w, r, http.StatusServiceUnavailable, map[string]string{"status": "shutting down"})
+        return
+    }
+
+    ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+    defer cancel()
+
+    if err := checkUpstream(ctx, config.PokeAPIBaseURL + "/"); err != nil {
+        logErrorf("readiness check failed: %v", err)
+        writeJsonFor(w, r, http.StatusServiceUnavailable, map[string]string{"status": "unavailable"})
+        return
+    }
+    writeJsonFor(w, r, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// checkUpstream sends a HEAD request to url, failing when it cannot be
+// reached or answers with a server error.
+func checkUpstream(ctx context.Context, url string) error {
+    request, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+    if err != nil {
+        return err
+    }
+    response, err := upstreamClient.Do(request)
+    if err != nil {
+        return err
+    }
+    response.Body.Close()
+
+    if response.StatusCode >= 500 {
+        return &httpError{http.StatusBadGateway, "upstream returned " + response.Status}
+    }
+    return nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "testing"
+)
+
+// TestHealthz checks that /healthz answers 200 {"status":"ok"}.
+func TestHealthz(t *testing.T) {
+    w := httptest.NewRecorder()
+    newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+    if w.Code != http.StatusOK || w.Body.String() != `{"status":"ok"}` {
+        t.Fatalf("GET /healthz = %d %q, want 200 {\"status\":\"ok\"}", w.Code, w.Body.String())
+    }
+}
+
+// TestReadyz checks /readyz against a reachable and an unreachable
+// PokéAPI.
+func TestReadyz(t *testing.T) {
+    upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+    defer upstream.Close()
+    c := overrideConfig(t)
+
+    tests := []struct {
+        url string
+        want int
+    }{
+        {upstream.URL, http.StatusOK},
+        {closedURL(t), http.StatusServiceUnavailable},
+    }
+    for _, tt := range tests {
+        c.PokeAPIBaseURL = tt.url
+        w := httptest.NewRecorder()
+        newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+        if w.Code != tt.want {
+            t.Errorf("GET /readyz with PokéAPI at %s = %d, want %d", tt.url, w.Code, tt.want)
+        }
+    }
+}
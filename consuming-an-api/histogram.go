@@ -0,0 +1,57 @@
+package main
+
+import (
+    "fmt"
+    "io"
+    "sync/atomic"
+    "time"
+)
+
+// latencyBuckets are the upper bounds of the upstream latency histogram
+// buckets.
+var latencyBuckets = []time.Duration{
+    10 * time.Millisecond,
+    50 * time.Millisecond,
+    100 * time.Millisecond,
+    500 * time.Millisecond,
+    time.Second,
+    5 * time.Second,
+}
+
+// latencyHistogram counts durations into latencyBuckets. Its fields are
+// only accessed atomically, so observing needs no lock.
+type latencyHistogram struct {
+    // counts[i] counts the durations that fell into bucket i only; the
+    // last entry is the +Inf bucket.
+    counts []int64
+    count int64
+    sum int64
+}
+
+func newLatencyHistogram() *latencyHistogram {
+    return &latencyHistogram{counts: make([]int64, len(latencyBuckets) + 1)}
+}
+
+func (h *latencyHistogram) observe(d time.Duration) {
+    i := 0
+    for i < len(latencyBuckets) && d > latencyBuckets[i] {
+        i++
+    }
+    atomic.AddInt64(&h.counts[i], 1)
+    atomic.AddInt64(&h.count, 1)
+    atomic.AddInt64(&h.sum, int64(d))
+}
+
+// writePrometheus writes the histogram in the Prometheus text format as
+// the name series with the given label. Buckets are cumulative there.
+func (h *latencyHistogram) writePrometheus(w io.Writer, name, label string) {
+    var cumulative int64
+    for i, bound := range latencyBuckets {
+        cumulative += atomic.LoadInt64(&h.counts[i])
+        fmt.Fprintf(w, "%s_bucket{%s,le=\"%g\"} %d\n", name, label, bound.Seconds(), cumulative)
+    }
+    cumulative += atomic.LoadInt64(&h.counts[len(latencyBuckets)])
+    fmt.Fprintf(w, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, label, cumulative)
+    fmt.Fprintf(w, "%s_sum{%s} %g\n", name, label, time.Duration(atomic.LoadInt64(&h.sum)).Seconds())
+    fmt.Fprintf(w, "%s_count{%s} %d\n", name, label, atomic.LoadInt64(&h.count))
+}
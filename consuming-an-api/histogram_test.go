@@ -0,0 +1,73 @@
+package main
+
+import (
+    "bytes"
+    "context"
+    "net/http"
+    "net/http/httptest"
+    "net/url"
+    "strings"
+    "testing"
+    "time"
+)
+
+// TestLatencyHistogram observes durations on bucket edges, checking the
+// cumulative buckets, sum and count.
+func TestLatencyHistogram(t *testing.T) {
+    h := newLatencyHistogram()
+    for _, d := range []time.Duration{5 * time.Millisecond, 10 * time.Millisecond, 75 * time.Millisecond, 10 * time.Second} {
+        h.observe(d)
+    }
+
+    var buf bytes.Buffer
+    h.writePrometheus(&buf, "latency", `host="x"`)
+    want := `latency_bucket{host="x",le="0.01"} 2
+latency_bucket{host="x",le="0.05"} 2
+latency_bucket{host="x",le="0.1"} 3
+latency_bucket{host="x",le="0.5"} 3
+latency_bucket{host="x",le="1"} 3
+latency_bucket{host="x",le="5"} 3
+latency_bucket{host="x",le="+Inf"} 4
+latency_sum{host="x"} 10.09
+latency_count{host="x"} 4
+`
+    if buf.String() != want {
+        t.Fatalf("writePrometheus() =\n%s\nwant\n%s", buf.String(), want)
+    }
+}
+
+// TestUpstreamLatencyMetrics calls a fast and a slow mock upstream,
+// checking that /metrics puts each host's call in the right bucket.
+func TestUpstreamLatencyMetrics(t *testing.T) {
+    fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+    defer fast.Close()
+    slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        time.Sleep(150 * time.Millisecond)
+    }))
+    defer slow.Close()
+    useMetrics(t)
+
+    for _, server := range []*httptest.Server{fast, slow} {
+        if _, err := fetchUpstream(context.Background(), server.URL); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    w := httptest.NewRecorder()
+    r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+    r.Header.Set("Accept", "text/plain;version=0.0.4")
+    retornarMetricas(w, r, nil)
+
+    fastHost, _ := url.Parse(fast.URL)
+    slowHost, _ := url.Parse(slow.URL)
+    for _, want := range []string{
+        `api_upstream_request_duration_seconds_bucket{host="` + fastHost.Host + `",le="0.1"} 1`,
+        `api_upstream_request_duration_seconds_bucket{host="` + slowHost.Host + `",le="0.1"} 0`,
+        `api_upstream_request_duration_seconds_bucket{host="` + slowHost.Host + `",le="0.5"} 1`,
+        `api_upstream_request_duration_seconds_count{host="` + slowHost.Host + `"} 1`,
+    } {
+        if !strings.Contains(w.Body.String(), want + "\n") {
+            t.Errorf("metrics output does not contain %q:\n%s", want, w.Body)
+        }
+    }
+}
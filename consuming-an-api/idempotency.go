@@ -0,0 +1,172 @@
+package main
+
+import (
+    "bytes"
+    "crypto/sha256"
+    "io/ioutil"
+    "net/http"
+    "sync"
+    "time"
+
+    "github.com/julienschmidt/httprouter"
+)
+
+// maxIdempotencyKey is the longest Idempotency-Key we accept.
+const maxIdempotencyKey = 255
+
+// replayedHeaders are the response headers stored with an idempotent
+// response and sent again when it is replayed. The others, such as
+// Content-Encoding, belong to the request that got the original.
+var replayedHeaders = []string{"Content-Type", "ETag", "Location"}
+
+// idempotentResponse is the response given to the first request with a
+// key, or a placeholder while that request is still being handled.
+type idempotentResponse struct {
+    fingerprint [sha256.Size]byte
+    done bool
+    status int
+    header http.Header
+    body []byte
+    expires time.Time
+}
+
+// idempotencyStore remembers the responses to requests sent with an
+// Idempotency-Key until they expire.
+type idempotencyStore struct {
+    mu sync.Mutex
+    entries map[string]*idempotentResponse
+}
+
+func newIdempotencyStore() *idempotencyStore {
+    return &idempotencyStore{entries: make(map[string]*idempotentResponse)}
+}
+
+// idempotencyKeys holds the keys of every idempotent route.
+var idempotencyKeys = newIdempotencyStore()
+
+// begin looks up scope. When it is unknown or has expired, a placeholder
+// fingerprinted with fingerprint is stored in its place and begin returns
+// nil, true: the caller handles the request and then calls finish or
+// abandon. Otherwise it returns the stored entry, or nil, false when the
+// scope is taken by a request with another body.
+func (s *idempotencyStore) begin(scope string, fingerprint [sha256.Size]byte) (*idempotentResponse, bool) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    now := time.Now()
+    for k, entry := range s.entries {
+        if entry.done && now.After(entry.expires) {
+            delete(s.entries, k)
+        }
+    }
+    if entry, ok := s.entries[scope]; ok {
+        if entry.fingerprint != fingerprint {
+            return nil, false
+        }
+        copied := *entry
+        return &copied, true
+    }
+    s.entries[scope] = &idempotentResponse{fingerprint: fingerprint}
+    return nil, true
+}
+
+// finish stores the response to the request that began scope.
+func (s *idempotencyStore) finish(scope string, status int, header http.Header, body []byte, ttl time.Duration) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if entry, ok := s.entries[scope]; ok {
+        entry.done, entry.status, entry.header, entry.body = true, status, header, body
+        entry.expires = time.Now().Add(ttl)
+    }
+}
+
+// abandon forgets scope so that the request can be tried again.
+func (s *idempotencyStore) abandon(scope string) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    delete(s.entries, scope)
+}
+
+// responseRecorder keeps the status and body written through it.
+type responseRecorder struct {
+    http.ResponseWriter
+    status int
+    body bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+    if rec.status == 0 {
+        rec.status = status
+    }
+    rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+    if rec.status == 0 {
+        rec.status = http.StatusOK
+    }
+    rec.body.Write(b)
+    return rec.ResponseWriter.Write(b)
+}
+
+// idempotent makes the requests to route that carry an Idempotency-Key
+// safe to retry. The first response to a key, unless it is a 5xx, is kept
+// for config.IdempotencyTTL and replayed, with Idempotent-Replayed set, to
+// later requests with the same key and body; a different body gets a 409.
+// Keys are scoped to the route and to the API key of the caller.
+func idempotent(route string, next httprouter.Handle) httprouter.Handle {
+    return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+        key := r.Header.Get("Idempotency-Key")
+        if key == "" {
+            next(w, r, ps)
+            return
+        }
+        if len(key) > maxIdempotencyKey {
+            writeError(w, http.StatusBadRequest, "Idempotency-Key too long")
+            return
+        }
+        body, err := ioutil.ReadAll(r.Body)
+        if err != nil {
+            writeDecodeError(w, err)
+            return
+        }
+        r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+        scope := r.Header.Get("X-API-Key") + "\x00" + r.Method + " " + route + "\x00" + key
+        entry, ok := idempotencyKeys.begin(scope, sha256.Sum256(body))
+        switch {
+        case !ok:
+            writeErrorCode(w, http.StatusConflict, "idempotency_key_reused", "Idempotency-Key already used with a different body")
+            return
+        case entry != nil && !entry.done:
+            writeErrorCode(w, http.StatusConflict, "idempotency_key_in_progress", "a request with this Idempotency-Key is still being handled")
+            return
+        case entry != nil:
+            for name, values := range entry.header {
+                w.Header()[name] = values
+            }
+            w.Header().Set("Idempotent-Replayed", "true")
+            w.WriteHeader(entry.status)
+            w.Write(entry.body)
+            return
+        }
+
+        rec := &responseRecorder{ResponseWriter: w}
+        defer func() {
+            if rec.status == 0 || rec.status >= 500 {
+                idempotencyKeys.abandon(scope)
+                return
+            }
+            header := make(http.Header)
+            for _, name := range replayedHeaders {
+                if values := w.Header().Values(name); len(values) > 0 {
+                    header[http.CanonicalHeaderKey(name)] = values
+                }
+            }
+            idempotencyKeys.finish(scope, rec.status, header, rec.body.Bytes(), config.IdempotencyTTL)
+        }()
+        next(rec, r, ps)
+    }
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+)
+
+// postIdempotent posts body to /criarPokemon with the Idempotency-Key key.
+func postIdempotent(router http.Handler, key, body string) *httptest.ResponseRecorder {
+    r := httptest.NewRequest(http.MethodPost, "/criarPokemon", strings.NewReader(body))
+    r.Header.Set("Idempotency-Key", key)
+    w := httptest.NewRecorder()
+    router.ServeHTTP(w, r)
+    return w
+}
+
+// useIdempotencyKeys starts the test with no idempotency keys stored.
+func useIdempotencyKeys(t *testing.T) {
+    saved := idempotencyKeys
+    idempotencyKeys = newIdempotencyStore()
+    t.Cleanup(func() { idempotencyKeys = saved })
+}
+
+// TestIdempotentReplay repeats a create with the same key, checking that
+// the original response comes back instead of a duplicate conflict.
+func TestIdempotentReplay(t *testing.T) {
+    useIdempotencyKeys(t)
+    store = newPokemonStore()
+    router := newRouter()
+
+    first := postIdempotent(router, "abc", `{"name":"pikachu","level":12}`)
+    if first.Code != http.StatusCreated {
+        t.Fatalf("first POST = %d %s, want %d", first.Code, first.Body, http.StatusCreated)
+    }
+    again := postIdempotent(router, "abc", `{"name":"pikachu","level":12}`)
+    if again.Code != http.StatusCreated || again.Body.String() != first.Body.String() {
+        t.Fatalf("repeated POST = %d %s, want %d %s", again.Code, again.Body, first.Code, first.Body)
+    }
+    if again.Header().Get("Idempotent-Replayed") != "true" || again.Header().Get("ETag") != first.Header().Get("ETag") {
+        t.Errorf("repeated POST headers = %v, want the original ETag, replayed", again.Header())
+    }
+    if n := len(store.all()); n != 1 {
+        t.Errorf("stored %d Pokemon, want 1", n)
+    }
+
+    // Without the key, the duplicate is refused as usual.
+    if w := postIdempotent(router, "", `{"name":"pikachu","level":12}`); w.Code != http.StatusConflict {
+        t.Errorf("POST without a key = %d, want %d", w.Code, http.StatusConflict)
+    }
+}
+
+// TestIdempotentDifferentBody reuses a key for another Pokemon, checking
+// for a 409 that stores nothing.
+func TestIdempotentDifferentBody(t *testing.T) {
+    useIdempotencyKeys(t)
+    store = newPokemonStore()
+    router := newRouter()
+
+    postIdempotent(router, "abc", `{"name":"pikachu","level":12}`)
+    w := postIdempotent(router, "abc", `{"name":"bulbasaur","level":5}`)
+    if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), "idempotency_key_reused") {
+        t.Fatalf("POST reusing the key = %d %s, want a 409 idempotency_key_reused", w.Code, w.Body)
+    }
+    if n := len(store.all()); n != 1 {
+        t.Errorf("stored %d Pokemon, want 1", n)
+    }
+}
+
+// TestIdempotentScopeAndExpiry checks that keys are scoped to the API key
+// and forgotten once config.IdempotencyTTL has passed.
+func TestIdempotentScopeAndExpiry(t *testing.T) {
+    useIdempotencyKeys(t)
+    store = newPokemonStore()
+    overrideConfig(t).APIKeys = []string{"alice", "bob"}
+    config.IdempotencyTTL = 20 * time.Millisecond
+    router := newRouter()
+
+    post := func(apiKey, body string) int {
+        r := httptest.NewRequest(http.MethodPost, "/criarPokemon", strings.NewReader(body))
+        r.Header.Set("Idempotency-Key", "abc")
+        r.Header.Set("X-API-Key", apiKey)
+        w := httptest.NewRecorder()
+        router.ServeHTTP(w, r)
+        return w.Code
+    }
+    if code := post("alice", `{"name":"pikachu","level":12}`); code != http.StatusCreated {
+        t.Fatalf("alice POST = %d, want %d", code, http.StatusCreated)
+    }
+    if code := post("bob", `{"name":"bulbasaur","level":5}`); code != http.StatusCreated {
+        t.Errorf("bob POST with alice's key = %d, want %d", code, http.StatusCreated)
+    }
+
+    time.Sleep(30 * time.Millisecond)
+    if code := post("alice", `{"name":"charmander","level":7}`); code != http.StatusCreated {
+        t.Errorf("alice POST after expiry = %d, want %d", code, http.StatusCreated)
+    }
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+    "encoding/csv"
+    "encoding/json"
+    "errors"
+    "io"
+    "net/http"
+    "sort"
+    "strconv"
+    "strings"
+
+    "github.com/julienschmidt/httprouter"
+)
+
+// importFile is the form field /pokemons/import reads the CSV from.
+const importFile = "file"
+
+// importSkip is a CSV row that was not imported, and why.
+type importSkip struct {
+    Line int `json:"line"`
+    Name string `json:"name,omitempty"`
+    Reason string `json:"reason"`
+}
+
+// importSummary is the reply of /pokemons/import.
+type importSummary struct {
+    Inserted int `json:"inserted"`
+    Skipped int `json:"skipped"`
+    SkippedRows []importSkip `json:"skipped_rows"`
+}
+
+// parseImportRow turns a name,level CSV row into a Pokemon, checking it
+// against pokemonSchema as if it had been posted to /criarPokemon.
+func parseImportRow(record []string) (Pokemon, error) {
+    if len(record) != 2 {
+        return Pokemon{}, errors.New("want 2 fields, name and level, got " + strconv.Itoa(len(record)))
+    }
+    name := strings.TrimSpace(record[0])
+    level, err := strconv.Atoi(strings.TrimSpace(record[1]))
+    if err != nil {
+        return Pokemon{}, errors.New("level: must be an integer")
+    }
+
+    failed := pokemonSchema.validate(map[string]interface{}{"name": name, "level": json.Number(strconv.Itoa(level))})
+    if len(failed) > 0 {
+        reasons := make([]string, 0, len(failed))
+        for field, msg := range failed {
+            reasons = append(reasons, field + ": " + msg)
+        }
+        sort.Strings(reasons)
+        return Pokemon{}, errors.New(strings.Join(reasons, "; "))
+    }
+    p := Pokemon{Name: name, Level: int8(level)}
+    if err := validatePokemon(p); err != nil {
+        return Pokemon{}, err
+    }
+    return p, nil
+}
+
+// isHeader reports whether record is the optional name,level header.
+func isHeader(record []string) bool {
+    return len(record) == 2 && strings.EqualFold(strings.TrimSpace(record[0]), "name") && strings.EqualFold(strings.TrimSpace(record[1]), "level")
+}
+
+// importarPokemons stores the Pokemon of a name,level CSV uploaded as the
+// file field of a multipart form. Like /pokemons/bulk, each row stands on
+// its own: the reply counts the rows inserted and lists those skipped
+// with the reason. The upload is read as it arrives, within the body size
+// limit.
+func importarPokemons(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+    mr, err := r.MultipartReader()
+    if err != nil {
+        writeError(w, http.StatusUnsupportedMediaType, "want a multipart/form-data upload")
+        return
+    }
+    var file io.Reader
+    for {
+        part, err := mr.NextPart()
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            writeImportError(w, err)
+            return
+        }
+        if part.FormName() == importFile {
+            file = part
+            break
+        }
+    }
+    if file == nil {
+        writeError(w, http.StatusBadRequest, "no " + importFile + " field in the upload")
+        return
+    }
+
+    reader := csv.NewReader(file)
+    reader.FieldsPerRecord = -1
+    reader.TrimLeadingSpace = true
+    summary := importSummary{SkippedRows: []importSkip{}}
+    for first := true; ; first = false {
+        record, err := reader.Read()
+        if err == io.EOF {
+            break
+        }
+        var parseErr *csv.ParseError
+        if errors.As(err, &parseErr) {
+            summary.SkippedRows = append(summary.SkippedRows, importSkip{Line: parseErr.Line, Reason: parseErr.Err.Error()})
+            continue
+        }
+        if err != nil {
+            writeImportError(w, err)
+            return
+        }
+        if first && isHeader(record) {
+            continue
+        }
+        line, _ := reader.FieldPos(0)
+
+        p, err := parseImportRow(record)
+        if err == nil {
+            err = store.add(p)
+        }
+        if err != nil {
+            skip := importSkip{Line: line, Reason: err.Error()}
+            if len(record) > 0 {
+                skip.Name = strings.TrimSpace(record[0])
+            }
+            summary.SkippedRows = append(summary.SkippedRows, skip)
+            continue
+        }
+        summary.Inserted++
+    }
+    summary.Skipped = len(summary.SkippedRows)
+    writeJsonFor(w, r, http.StatusOK, summary)
+}
+
+// writeImportError replies to an upload that could not be read, with a
+// 413 if it was over the size limit and a 400 otherwise.
+func writeImportError(w http.ResponseWriter, err error) {
+    var tooLarge *http.MaxBytesError
+    if errors.As(err, &tooLarge) {
+        writeError(w, http.StatusRequestEntityTooLarge, "request body too large")
+        return
+    }
+    writeError(w, http.StatusBadRequest, "invalid upload: " + err.Error())
+}
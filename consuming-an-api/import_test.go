@@ -0,0 +1,80 @@
+package main
+
+import (
+    "bytes"
+    "encoding/json"
+    "mime/multipart"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+)
+
+// postImport uploads csv as the file field of a multipart form.
+func postImport(t *testing.T, csv string) *httptest.ResponseRecorder {
+    var body bytes.Buffer
+    form := multipart.NewWriter(&body)
+    part, err := form.CreateFormFile("file", "pokemon.csv")
+    if err != nil {
+        t.Fatal(err)
+    }
+    part.Write([]byte(csv))
+    form.Close()
+
+    r := httptest.NewRequest(http.MethodPost, "/pokemons/import", &body)
+    r.Header.Set("Content-Type", form.FormDataContentType())
+    w := httptest.NewRecorder()
+    newRouter().ServeHTTP(w, r)
+    return w
+}
+
+// TestImportarPokemons uploads a CSV with a valid and an invalid row,
+// checking the summary and that only the valid one was stored.
+func TestImportarPokemons(t *testing.T) {
+    store = newPokemonStore()
+
+    w := postImport(t, "name,level\npikachu,12\nbulbasaur,300\n")
+    var summary importSummary
+    if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &summary) != nil {
+        t.Fatalf("POST /pokemons/import = %d %s, want 200", w.Code, w.Body)
+    }
+    if summary.Inserted != 1 || summary.Skipped != 1 || len(summary.SkippedRows) != 1 {
+        t.Fatalf("summary = %+v, want 1 inserted and 1 skipped", summary)
+    }
+    if skip := summary.SkippedRows[0]; skip.Line != 3 || skip.Name != "bulbasaur" || !strings.Contains(skip.Reason, "level") {
+        t.Errorf("skipped row = %+v, want line 3, bulbasaur, for its level", skip)
+    }
+    if all := store.all(); len(all) != 1 || all[0] != (Pokemon{"pikachu", 12}) {
+        t.Errorf("stored = %v, want [{pikachu 12}]", all)
+    }
+}
+
+// TestImportarPokemonsRows checks the reasons rows are skipped for.
+func TestImportarPokemonsRows(t *testing.T) {
+    store = newPokemonStore()
+    store.add(Pokemon{"ditto", 1})
+
+    w := postImport(t, "eevee,5\nditto,3\n,4\nmew,high\nonly-name\n\"open,7\n")
+    var summary importSummary
+    if err := json.Unmarshal(w.Body.Bytes(), &summary); err != nil {
+        t.Fatalf("POST /pokemons/import = %d %s: %v", w.Code, w.Body, err)
+    }
+    if summary.Inserted != 1 || summary.Skipped != 5 {
+        t.Errorf("summary = %+v, want 1 inserted and 5 skipped", summary)
+    }
+}
+
+func TestImportarPokemonsBadUpload(t *testing.T) {
+    r := httptest.NewRequest(http.MethodPost, "/pokemons/import", strings.NewReader("pikachu,12"))
+    r.Header.Set("Content-Type", "text/csv")
+    w := httptest.NewRecorder()
+    newRouter().ServeHTTP(w, r)
+    if w.Code != http.StatusUnsupportedMediaType {
+        t.Errorf("CSV posted as is = %d, want %d", w.Code, http.StatusUnsupportedMediaType)
+    }
+
+    overrideConfig(t).MaxBodyBytes = 64
+    if w := postImport(t, strings.Repeat("pikachu,12\n", 100)); w.Code != http.StatusRequestEntityTooLarge {
+        t.Errorf("upload over the limit = %d %s, want %d", w.Code, w.Body, http.StatusRequestEntityTooLarge)
+    }
+}
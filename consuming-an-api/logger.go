@@ -0,0 +1,74 @@
+package main
+
+import (
+    "log"
+    "os"
+    "strings"
+    "sync/atomic"
+)
+
+// logLevel orders log messages by importance; only messages at or above
+// the current level are written.
+type logLevel int32
+
+const (
+    levelDebug logLevel = iota
+    levelInfo
+    levelError
+)
+
+var levelNames = map[logLevel]string{
+    levelDebug: "debug",
+    levelInfo: "info",
+    levelError: "error",
+}
+
+// currentLevel is accessed atomically, as it can be switched by a signal
+// while requests are being logged.
+var currentLevel = int32(levelInfo)
+
+func setLogLevel(level logLevel) {
+    atomic.StoreInt32(&currentLevel, int32(level))
+}
+
+func getLogLevel() logLevel {
+    return logLevel(atomic.LoadInt32(&currentLevel))
+}
+
+// parseLogLevel parses a level name such as the value of $LOG_LEVEL.
+func parseLogLevel(name string) (logLevel, bool) {
+    for level, levelName := range levelNames {
+        if strings.EqualFold(name, levelName) {
+            return level, true
+        }
+    }
+    return levelInfo, false
+}
+
+func logf(level logLevel, format string, v ...interface{}) {
+    if level < getLogLevel() {
+        return
+    }
+    log.Printf("level=" + levelNames[level] + " " + format, v...)
+}
+
+// logDebugf logs detail only useful while troubleshooting.
+func logDebugf(format string, v ...interface{}) {
+    logf(levelDebug, format, v...)
+}
+
+// logInfof logs the normal operation of the server.
+func logInfof(format string, v ...interface{}) {
+    logf(levelInfo, format, v...)
+}
+
+// logErrorf logs failures.
+func logErrorf(format string, v ...interface{}) {
+    logf(levelError, format, v...)
+}
+
+// logFatalf logs a failure the server cannot go on after and exits.
+func logFatalf(format string, v ...interface{}) {
+    logErrorf(format, v...)
+    os.Exit(1)
+}
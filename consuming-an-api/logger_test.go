@@ -0,0 +1,38 @@
+package main
+
+import (
+    "strings"
+    "testing"
+)
+
+// TestLogLevel sets the level to error, checking that info and debug
+// messages are suppressed while errors still get through.
+func TestLogLevel(t *testing.T) {
+    buf := captureLog(t)
+    defer setLogLevel(getLogLevel())
+    setLogLevel(levelError)
+
+    logDebugf("debug %d", 1)
+    logInfof("info %d", 2)
+    logErrorf("error %d", 3)
+
+    got := buf.String()
+    if strings.Contains(got, "debug 1") || strings.Contains(got, "info 2") {
+        t.Errorf("log output %q contains messages below the error level", got)
+    }
+    if !strings.Contains(got, "level=error error 3") {
+        t.Errorf("log output %q does not contain the error message", got)
+    }
+}
+
+// TestParseLogLevel checks the accepted level names.
+func TestParseLogLevel(t *testing.T) {
+    for name, want := range map[string]logLevel{"debug": levelDebug, "INFO": levelInfo, "Error": levelError} {
+        if got, ok := parseLogLevel(name); !ok || got != want {
+            t.Errorf("parseLogLevel(%q) = %v, %v, want %v, true", name, got, ok, want)
+        }
+    }
+    if _, ok := parseLogLevel("verbose"); ok {
+        t.Error(`parseLogLevel("verbose") ok = true, want false`)
+    }
+}
@@ -0,0 +1,28 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+    "os"
+    "os/signal"
+    "syscall"
+)
+
+// toggleDebugOnSignal switches between debug logging and the configured
+// level every time the process gets SIGUSR1, so that a running server can
+// be troubleshot without a restart.
+func toggleDebugOnSignal(configured logLevel) {
+    signals := make(chan os.Signal, 1)
+    signal.Notify(signals, syscall.SIGUSR1)
+    go func() {
+        for range signals {
+            level := levelDebug
+            if getLogLevel() == levelDebug {
+                level = configured
+            }
+            setLogLevel(level)
+            logInfof("log level switched to %s", levelNames[level])
+        }
+    }()
+}
@@ -0,0 +1,4 @@
+package main
+
+// toggleDebugOnSignal does nothing on Windows, which has no SIGUSR1.
+func toggleDebugOnSignal(configured logLevel) {}
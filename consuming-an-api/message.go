@@ -0,0 +1,44 @@
+package main
+
+import (
+    "net/http"
+    "net/url"
+    "strconv"
+)
+
+// defaultMessage is the Message served by /retornarStruct when no query
+// parameters override it.
+var defaultMessage = Message{Body: "Hello, Mundão!", Number: 124, Decimal: 1687.87845, Validate: true}
+
+// messageFromQuery returns defaultMessage with the fields overridden by
+// the body, number, decimal and validate query parameters. Values that do
+// not parse into their field, such as a number outside the int8 range,
+// are a 400.
+func messageFromQuery(q url.Values) (Message, error) {
+    m := defaultMessage
+    if body, ok := q["body"]; ok {
+        m.Body = body[0]
+    }
+    if number := q.Get("number"); number != "" {
+        n, err := strconv.ParseInt(number, 10, 8)
+        if err != nil {
+            return Message{}, &httpError{http.StatusBadRequest, "number must be an integer between -128 and 127"}
+        }
+        m.Number = int8(n)
+    }
+    if decimal := q.Get("decimal"); decimal != "" {
+        d, err := strconv.ParseFloat(decimal, 32)
+        if err != nil {
+            return Message{}, &httpError{http.StatusBadRequest, "decimal must be a number"}
+        }
+        m.Decimal = float32(d)
+    }
+    if validate := q.Get("validate"); validate != "" {
+        v, err := strconv.ParseBool(validate)
+        if err != nil {
+            return Message{}, &httpError{http.StatusBadRequest, "validate must be true or false"}
+        }
+        m.Validate = v
+    }
+    return m, nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+// postMessage sends body to POST /message and returns the recorded
+// response.
+func postMessage(body string) *httptest.ResponseRecorder {
+    w := httptest.NewRecorder()
+    r := httptest.NewRequest(http.MethodPost, "/message", strings.NewReader(body))
+    newRouter().ServeHTTP(w, r)
+    return w
+}
+
+// failedFields decodes the failed fields from a 422 response.
+func failedFields(t *testing.T, w *httptest.ResponseRecorder) map[string]string {
+    var body struct {
+        Fields map[string]string `json:"fields"`
+    }
+    if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+        t.Fatal(err)
+    }
+    return body.Fields
+}
+
+// TestCriarMensagemValid posts a valid message, checking it is accepted.
+func TestCriarMensagemValid(t *testing.T) {
+    w := postMessage(`{"Body":"oi","Number":7,"Decimal":1.5,"Validate":true}`)
+    if w.Code != http.StatusOK {
+        t.Fatalf("POST /message status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+    }
+}
+
+// TestCriarMensagemLowercase posts a message with lowercase keys,
+// checking it is accepted like encoding/json accepts it.
+func TestCriarMensagemLowercase(t *testing.T) {
+    w := postMessage(`{"body":"oi","validate":true}`)
+    if w.Code != http.StatusOK {
+        t.Fatalf("POST /message status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+    }
+}
+
+// TestCriarMensagemEmptyBody posts a message with an empty Body and
+// Validate set, checking that Body is reported.
+func TestCriarMensagemEmptyBody(t *testing.T) {
+    w := postMessage(`{"Body":"","Number":7,"Validate":true}`)
+    if w.Code != http.StatusUnprocessableEntity {
+        t.Fatalf("POST /message status = %d, want %d", w.Code, http.StatusUnprocessableEntity)
+    }
+    if got := failedFields(t, w); !reflect.DeepEqual(got, map[string]string{"Body": "is required"}) {
+        t.Fatalf("failed fields = %v, want Body is required", got)
+    }
+}
+
+// TestCriarMensagemNegativeDecimal posts a negative Decimal, checking
+// that Decimal is reported.
+func TestCriarMensagemNegativeDecimal(t *testing.T) {
+    w := postMessage(`{"Body":"oi","Decimal":-2,"Validate":true}`)
+    if w.Code != http.StatusUnprocessableEntity {
+        t.Fatalf("POST /message status = %d, want %d", w.Code, http.StatusUnprocessableEntity)
+    }
+    if got := failedFields(t, w); !reflect.DeepEqual(got, map[string]string{"Decimal": "must be at least 0"}) {
+        t.Fatalf("failed fields = %v, want Decimal must be at least 0", got)
+    }
+}
+
+// TestCriarMensagemNumberOverflow posts a Number that does not fit an
+// int8, checking that Number is reported.
+func TestCriarMensagemNumberOverflow(t *testing.T) {
+    w := postMessage(`{"Body":"oi","Number":300}`)
+    if w.Code != http.StatusUnprocessableEntity {
+        t.Fatalf("POST /message status = %d, want %d", w.Code, http.StatusUnprocessableEntity)
+    }
+    if got := failedFields(t, w); !reflect.DeepEqual(got, map[string]string{"Number": "must be at most 127"}) {
+        t.Fatalf("failed fields = %v, want Number must be at most 127", got)
+    }
+}
+
+// TestCriarMensagemSchema posts bodies the schema rejects, checking that
+// each violation is reported.
+func TestCriarMensagemSchema(t *testing.T) {
+    tests := []struct {
+        body string
+        want map[string]string
+    }{
+        {`{"Body":"oi","Extra":1}`, map[string]string{"Extra": "is not allowed"}},
+        {`{"Body":1,"Number":"12"}`, map[string]string{"Body": "must be a string", "Number": "must be an integer"}},
+        {`{"Number":1.5}`, map[string]string{"Number": "must be an integer"}},
+        {`[]`, map[string]string{schemaRoot: "must be an object"}},
+    }
+    for _, tt := range tests {
+        w := postMessage(tt.body)
+        if w.Code != http.StatusUnprocessableEntity {
+            t.Fatalf("POST /message %s status = %d, want %d", tt.body, w.Code, http.StatusUnprocessableEntity)
+        }
+        if got := failedFields(t, w); !reflect.DeepEqual(got, tt.want) {
+            t.Errorf("POST /message %s failed fields = %v, want %v", tt.body, got, tt.want)
+        }
+    }
+}
+
+// getStruct requests /retornarStruct with the given query string.
+func getStruct(query string) *httptest.ResponseRecorder {
+    w := httptest.NewRecorder()
+    newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/retornarStruct?" + query, nil))
+    return w
+}
+
+// TestRetornarStructDefaults checks the Message served without query
+// parameters.
+func TestRetornarStructDefaults(t *testing.T) {
+    w := getStruct("")
+    want := `{"Body":"Hello, Mundão!","Number":124,"Decimal":1687.8784,"Validate":true}`
+    if w.Code != http.StatusOK || w.Body.String() != want {
+        t.Fatalf("GET /retornarStruct = %d %s, want 200 %s", w.Code, w.Body, want)
+    }
+}
+
+// TestRetornarStructOverridden overrides every field through the query.
+func TestRetornarStructOverridden(t *testing.T) {
+    w := getStruct("body=hi&number=5&decimal=3.14&validate=false")
+    want := `{"Body":"hi","Number":5,"Decimal":3.14,"Validate":false}`
+    if w.Code != http.StatusOK || w.Body.String() != want {
+        t.Fatalf("GET /retornarStruct = %d %s, want 200 %s", w.Code, w.Body, want)
+    }
+}
+
+// TestRetornarStructInvalid checks that values that do not fit their
+// field are a 400.
+func TestRetornarStructInvalid(t *testing.T) {
+    for _, query := range []string{"number=128", "number=-129", "number=1.5", "decimal=pi", "validate=maybe"} {
+        if w := getStruct(query); w.Code != http.StatusBadRequest {
+            t.Errorf("GET /retornarStruct?%s = %d, want %d", query, w.Code, http.StatusBadRequest)
+        }
+    }
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+    "fmt"
+    "net/http"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/julienschmidt/httprouter"
+)
+
+// metrics counts the requests we serve and the upstream calls we make.
+type metrics struct {
+    mu sync.Mutex
+    requests int64
+    routes map[string]int64
+    statuses map[int]int64
+    upstreamCalls int64
+    upstreamTime time.Duration
+    upstreamLatency map[string]*latencyHistogram
+}
+
+// metricsSnapshot is the JSON representation of the metrics.
+type metricsSnapshot struct {
+    Requests int64 `json:"requests_total"`
+    Routes map[string]int64 `json:"requests_by_route"`
+    Statuses map[string]int64 `json:"responses_by_status"`
+    UpstreamCalls int64 `json:"upstream_calls_total"`
+    UpstreamAverageMs float64 `json:"upstream_latency_average_ms"`
+    CacheEntries int `json:"cache_entries"`
+}
+
+func newMetrics() *metrics {
+    return &metrics{
+        routes: make(map[string]int64),
+        statuses: make(map[int]int64),
+        upstreamLatency: make(map[string]*latencyHistogram),
+    }
+}
+
+// appMetrics collects the metrics served at /metrics.
+var appMetrics = newMetrics()
+
+// recordRequest counts a request to route answered with status.
+func (m *metrics) recordRequest(route string, status int) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    m.requests++
+    m.routes[route]++
+    m.statuses[status]++
+}
+
+// recordUpstream counts a call to the upstream host that took d.
+func (m *metrics) recordUpstream(host string, d time.Duration) {
+    m.mu.Lock()
+    m.upstreamCalls++
+    m.upstreamTime += d
+    h, ok := m.upstreamLatency[host]
+    if !ok {
+        h = newLatencyHistogram()
+        m.upstreamLatency[host] = h
+    }
+    m.mu.Unlock()
+
+    h.observe(d)
+}
+
+func (m *metrics) snapshot() metricsSnapshot {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    s := metricsSnapshot{
+        Requests: m.requests,
+        Routes: make(map[string]int64, len(m.routes)),
+        Statuses: make(map[string]int64, len(m.statuses)),
+        UpstreamCalls: m.upstreamCalls,
+    }
+    for route, n := range m.routes {
+        s.Routes[route] = n
+    }
+    for status, n := range m.statuses {
+        s.Statuses[strconv.Itoa(status)] = n
+    }
+    if m.upstreamCalls > 0 {
+        s.UpstreamAverageMs = float64(m.upstreamTime) / float64(m.upstreamCalls) / float64(time.Millisecond)
+    }
+    return s
+}
+
+// countRequests records every request handled by next under route, the
+// path pattern it was registered with.
+func countRequests(route string, next httprouter.Handle) httprouter.Handle {
+    return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+        rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+        next(rec, r, ps)
+        appMetrics.recordRequest(route, rec.status)
+    }
+}
+
+// retornarMetricas serves the metrics as JSON, or in the Prometheus text
+// format with ?format=prometheus or when the client asks for text/plain
+// as Prometheus scrapers do.
+func retornarMetricas(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+    s := appMetrics.snapshot()
+    s.CacheEntries = pokemonCache.size()
+    if r.URL.Query().Get("format") != "prometheus" && !strings.Contains(r.Header.Get("Accept"), "text/plain") {
+        writeJsonFor(w, r, http.StatusOK, s)
+        return
+    }
+
+    w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+    fmt.Fprintf(w, "api_requests_total %d\n", s.Requests)
+    for _, route := range sortedKeys(s.Routes) {
+        fmt.Fprintf(w, "api_route_requests_total{route=%q} %d\n", route, s.Routes[route])
+    }
+    for _, status := range sortedKeys(s.Statuses) {
+        fmt.Fprintf(w, "api_responses_total{status=%q} %d\n", status, s.Statuses[status])
+    }
+    fmt.Fprintf(w, "api_upstream_calls_total %d\n", s.UpstreamCalls)
+    fmt.Fprintf(w, "api_upstream_latency_average_seconds %g\n", s.UpstreamAverageMs / 1000)
+    fmt.Fprintf(w, "api_cache_entries %d\n", s.CacheEntries)
+
+    appMetrics.mu.Lock()
+    latency := make(map[string]*latencyHistogram, len(appMetrics.upstreamLatency))
+    hosts := make([]string, 0, len(appMetrics.upstreamLatency))
+    for host, h := range appMetrics.upstreamLatency {
+        latency[host] = h
+        hosts = append(hosts, host)
+    }
+    appMetrics.mu.Unlock()
+    sort.Strings(hosts)
+
+    fmt.Fprintln(w, "# TYPE api_upstream_request_duration_seconds histogram")
+    for _, host := range hosts {
+        latency[host].writePrometheus(w, "api_upstream_request_duration_seconds", fmt.Sprintf("host=%q", host))
+    }
+}
+
+func sortedKeys(m map[string]int64) []string {
+    keys := make([]string, 0, len(m))
+    for k := range m {
+        keys = append(keys, k)
+    }
+    sort.Strings(keys)
+    return keys
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+)
+
+// useMetrics gives the test empty metrics, and puts the previous ones back
+// when the test ends.
+func useMetrics(t *testing.T) {
+    saved := appMetrics
+    appMetrics = newMetrics()
+    t.Cleanup(func() { appMetrics = saved })
+}
+
+// TestRetornarMetricas makes a couple of requests, checking that /metrics
+// reflects them.
+func TestRetornarMetricas(t *testing.T) {
+    useMetrics(t)
+    router := newRouter()
+    for _, path := range []string{"/retornarStruct", "/retornarStruct", "/healthz"} {
+        router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+    }
+
+    w := httptest.NewRecorder()
+    router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+    var s metricsSnapshot
+    if err := json.Unmarshal(w.Body.Bytes(), &s); err != nil {
+        t.Fatal(err)
+    }
+
+    if s.Requests != 3 {
+        t.Errorf("requests_total = %d, want 3", s.Requests)
+    }
+    if s.Routes["/retornarStruct"] != 2 || s.Routes["/healthz"] != 1 {
+        t.Errorf("requests_by_route = %v, want 2 for /retornarStruct and 1 for /healthz", s.Routes)
+    }
+    if s.Statuses["200"] != 3 {
+        t.Errorf("responses_by_status = %v, want 3 for 200", s.Statuses)
+    }
+}
+
+// TestRetornarMetricasPrometheus checks the Prometheus text output.
+func TestRetornarMetricasPrometheus(t *testing.T) {
+    useMetrics(t)
+    appMetrics.recordRequest("/retornarPokemon/:nome", http.StatusNotFound)
+
+    w := httptest.NewRecorder()
+    retornarMetricas(w, httptest.NewRequest(http.MethodGet, "/metrics?format=prometheus", nil), nil)
+
+    for _, want := range []string{
+        "api_requests_total 1\n",
+        `api_route_requests_total{route="/retornarPokemon/:nome"} 1` + "\n",
+        `api_responses_total{status="404"} 1` + "\n",
+    } {
+        if !strings.Contains(w.Body.String(), want) {
+            t.Errorf("prometheus output %q does not contain %q", w.Body.String(), want)
+        }
+    }
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+    "bufio"
+    "bytes"
+    "context"
+    "errors"
+    "io"
+    "net"
+    "net/http"
+    "runtime/debug"
+    "strconv"
+    "time"
+
+    "github.com/julienschmidt/httprouter"
+)
+
+// statusRecorder remembers the status code written through it.
+type statusRecorder struct {
+    http.ResponseWriter
+    status int
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+    rec.status = status
+    rec.ResponseWriter.WriteHeader(status)
+}
+
+// Hijack lets WebSocket handlers take over the connection through the
+// recorder, which then records the switch of protocols.
+func (rec *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+    conn, rw, err := hijack(rec.ResponseWriter)
+    if err == nil {
+        rec.status = http.StatusSwitchingProtocols
+    }
+    return conn, rw, err
+}
+
+// Flush lets streaming handlers flush through the recorder.
+func (rec *statusRecorder) Flush() {
+    flush(rec.ResponseWriter)
+}
+
+// Unwrap lets http.ResponseController reach the ResponseWriter behind the
+// recorder.
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+    return rec.ResponseWriter
+}
+
+// flush sends what was written to w so far, if w allows it.
+func flush(w http.ResponseWriter) {
+    if f, ok := w.(http.Flusher); ok {
+        f.Flush()
+    }
+}
+
+// hijack takes over the connection behind w, if w allows it.
+func hijack(w http.ResponseWriter) (net.Conn, *bufio.ReadWriter, error) {
+    hj, ok := w.(http.Hijacker)
+    if !ok {
+        return nil, nil, errors.New("connection cannot be hijacked")
+    }
+    return hj.Hijack()
+}
+
+// logRequests logs the request ID, method, path, status and duration of
+// every request handled by next as a single key=value line.
+func logRequests(next httprouter.Handle) httprouter.Handle {
+    return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+        start := time.Now()
+        rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+        next(rec, r, ps)
+
+        logInfof("request_id=%s method=%s path=%q status=%d duration=%s", requestIDFromContext(r.Context()), r.Method, r.URL.Path, rec.status, time.Since(start))
+    }
+}
+
+// recoverPanics turns a panic in next into a 500, logging it with the
+// request ID and stack trace instead of dropping the connection.
+// http.ErrAbortHandler is let through, since it is how a handler asks
+// for exactly that.
+func recoverPanics(next httprouter.Handle) httprouter.Handle {
+    return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+        defer func() {
+            err := recover()
+            if err == nil {
+                return
+            }
+            if err == http.ErrAbortHandler {
+                panic(err)
+            }
+            logErrorf("request_id=%s panic: %v\n%s", requestIDFromContext(r.Context()), err, debug.Stack())
+            writeError(w, http.StatusInternalServerError, "internal error")
+        }()
+
+        next(w, r, ps)
+    }
+}
+
+// limitDuration gives next d to finish: the request's context is
+// cancelled and the connection's write deadline passes once d is up, so
+// that next gives up on its upstream calls and a client that stopped
+// reading cannot hold a copy open. When next had not started its reply by
+// then, whatever it writes is dropped for a JSON 503. Replies are passed
+// through as they are written, so handlers copying an upstream body still
+// stream it.
+func limitDuration(d time.Duration, next httprouter.Handle) httprouter.Handle {
+    if d <= 0 {
+        return next
+    }
+    return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+        ctx, cancel := context.WithTimeout(r.Context(), d)
+        defer cancel()
+        setWriteDeadline(w, r, time.Now().Add(d))
+
+        header := w.Header().Clone()
+        tw := &timeoutWriter{ResponseWriter: w, ctx: ctx}
+        next(tw, r.WithContext(ctx), ps)
+
+        // What is still buffered, or the 503, is flushed after next
+        // returns, which may be past the deadline.
+        setWriteDeadline(w, r, time.Now().Add(d))
+        if tw.timedOut || (!tw.wroteHeader && ctx.Err() == context.DeadlineExceeded) {
+            // Only the headers set before next reach the 503.
+            for name := range w.Header() {
+                delete(w.Header(), name)
+            }
+            for name, values := range header {
+                w.Header()[name] = values
+            }
+            writeError(w, http.StatusServiceUnavailable, "request timed out")
+        }
+    }
+}
+
+// setWriteDeadline sets the write deadline of the connection behind w,
+// logging when it cannot, other than because w is not a connection.
+func setWriteDeadline(w http.ResponseWriter, r *http.Request, deadline time.Time) {
+    err := http.NewResponseController(w).SetWriteDeadline(deadline)
+    if err != nil && !errors.Is(err, http.ErrNotSupported) {
+        logDebugf("request_id=%s setting write deadline: %v", requestIDFromContext(r.Context()), err)
+    }
+}
+
+// timeoutWriter passes a reply through until ctx is done, after which a
+// reply that has not started yet is refused with http.ErrHandlerTimeout.
+type timeoutWriter struct {
+    http.ResponseWriter
+    ctx context.Context
+    wroteHeader bool
+    timedOut bool
+}
+
+func (tw *timeoutWriter) WriteHeader(status int) {
+    if tw.wroteHeader || tw.timedOut {
+        return
+    }
+    if tw.ctx.Err() == context.DeadlineExceeded {
+        tw.timedOut = true
+        return
+    }
+    tw.wroteHeader = true
+    tw.ResponseWriter.WriteHeader(status)
+}
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+    tw.WriteHeader(http.StatusOK)
+    if tw.timedOut {
+        return 0, http.ErrHandlerTimeout
+    }
+    return tw.ResponseWriter.Write(p)
+}
+
+func (tw *timeoutWriter) Flush() {
+    if tw.wroteHeader {
+        flush(tw.ResponseWriter)
+    }
+}
+
+func (tw *timeoutWriter) Unwrap() http.ResponseWriter {
+    return tw.ResponseWriter
+}
+
+// redactedHeaders are the request headers whose values never reach the
+// log.
+var redactedHeaders = []string{"Authorization", "Cookie", "X-API-Key"}
+
+// redactHeaders returns a copy of h with the values of redactedHeaders
+// replaced.
+func redactHeaders(h http.Header) http.Header {
+    h = h.Clone()
+    for _, name := range redactedHeaders {
+        if _, ok := h[http.CanonicalHeaderKey(name)]; ok {
+            h.Set(name, "[REDACTED]")
+        }
+    }
+    return h
+}
+
+// truncateBody formats the first max bytes of body for the log, noting
+// when there was more.
+func truncateBody(body []byte, more bool) string {
+    s := strconv.Quote(string(body))
+    if more {
+        s += "..."
+    }
+    return s
+}
+
+// bodyRecorder keeps the first max bytes written through it.
+type bodyRecorder struct {
+    http.ResponseWriter
+    max int
+    body []byte
+    more bool
+}
+
+func (rec *bodyRecorder) Write(b []byte) (int, error) {
+    if room := rec.max - len(rec.body); room > 0 {
+        if len(b) > room {
+            rec.body = append(rec.body, b[:room]...)
+            rec.more = true
+        } else {
+            rec.body = append(rec.body, b...)
+        }
+    } else if len(b) > 0 {
+        rec.more = true
+    }
+    return rec.ResponseWriter.Write(b)
+}
+
+func (rec *bodyRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+    return hijack(rec.ResponseWriter)
+}
+
+func (rec *bodyRecorder) Flush() {
+    flush(rec.ResponseWriter)
+}
+
+func (rec *bodyRecorder) Unwrap() http.ResponseWriter {
+    return rec.ResponseWriter
+}
+
+// noWriteDeadline lifts the server's WriteTimeout for next, which streams
+// for longer than any fixed deadline would allow.
+func noWriteDeadline(next httprouter.Handle) httprouter.Handle {
+    return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+        err := http.NewResponseController(w).SetWriteDeadline(time.Time{})
+        if err != nil && !errors.Is(err, http.ErrNotSupported) {
+            logDebugf("request_id=%s lifting write deadline: %v", requestIDFromContext(r.Context()), err)
+        }
+        next(w, r, ps)
+    }
+}
+
+// logBodies logs, at the debug level, the headers and the first max bytes
+// of the bodies of requests that have one and of every response. The
+// request body is only peeked at, so next still reads all of it.
+func logBodies(max int, next httprouter.Handle) httprouter.Handle {
+    return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+        if getLogLevel() > levelDebug {
+            next(w, r, ps)
+            return
+        }
+        id := requestIDFromContext(r.Context())
+
+        requestBody := "-"
+        if r.Method == http.MethodPost || r.Method == http.MethodPut || r.Method == http.MethodPatch {
+            peek := make([]byte, max + 1)
+            n, err := io.ReadFull(r.Body, peek)
+            if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+                logDebugf("request_id=%s reading request body: %v", id, err)
+            }
+            peek = peek[:n]
+            r.Body = struct {
+                io.Reader
+                io.Closer
+            }{io.MultiReader(bytes.NewReader(peek), r.Body), r.Body}
+            if n > max {
+                requestBody = truncateBody(peek[:max], true)
+            } else {
+                requestBody = truncateBody(peek, false)
+            }
+        }
+        logDebugf("request_id=%s request_headers=%v request_body=%s", id, redactHeaders(r.Header), requestBody)
+
+        rec := &bodyRecorder{ResponseWriter: w, max: max}
+        next(rec, r, ps)
+
+        logDebugf("request_id=%s response_headers=%v response_body=%s", id, w.Header(), truncateBody(rec.body, rec.more))
+    }
+}
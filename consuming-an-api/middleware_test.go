@@ -0,0 +1,211 @@
+package main
+
+import (
+    "bytes"
+    "io"
+    "io/ioutil"
+    "log"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/julienschmidt/httprouter"
+)
+
+// captureLog sends the standard logger's output to a buffer until the test
+// ends.
+func captureLog(t *testing.T) *bytes.Buffer {
+    var buf bytes.Buffer
+    log.SetOutput(&buf)
+    t.Cleanup(func() { log.SetOutput(os.Stderr) })
+    return &buf
+}
+
+// TestLogRequests exercises a route, checking that a log line with its
+// method, path and status was emitted.
+func TestLogRequests(t *testing.T) {
+    buf := captureLog(t)
+
+    w := httptest.NewRecorder()
+    newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/retornarStruct", nil))
+
+    line := buf.String()
+    for _, want := range []string{"method=GET", `path="/retornarStruct"`, "status=200", "duration="} {
+        if !strings.Contains(line, want) {
+            t.Errorf("log output %q does not contain %q", line, want)
+        }
+    }
+}
+
+// TestRecoverPanics sends a request to a handler that panics, checking
+// that the client gets a JSON 500 and the panic is logged with the
+// request ID and stack.
+func TestRecoverPanics(t *testing.T) {
+    buf := captureLog(t)
+    h := withRequestID(logRequests(recoverPanics(func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+        var m *Message
+        _ = m.Body
+    })))
+
+    w := httptest.NewRecorder()
+    r := httptest.NewRequest(http.MethodGet, "/boom", nil)
+    r.Header.Set("X-Request-ID", "panic-test")
+    h(w, r, nil)
+
+    if w.Code != http.StatusInternalServerError {
+        t.Errorf("status = %d, want %d", w.Code, http.StatusInternalServerError)
+    }
+    if want := `{"error":"internal error","status":500}`; w.Body.String() != want {
+        t.Errorf("body = %s, want %s", w.Body, want)
+    }
+    for _, want := range []string{"request_id=panic-test panic:", "nil pointer", "goroutine", "status=500"} {
+        if !strings.Contains(buf.String(), want) {
+            t.Errorf("log output %q does not contain %q", buf, want)
+        }
+    }
+}
+
+// TestLimitDuration runs a handler slower than the limit, checking for a
+// JSON 503 and a cancelled context.
+func TestLimitDuration(t *testing.T) {
+    cancelled := make(chan bool, 1)
+    h := limitDuration(20 * time.Millisecond, func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+        select {
+        case <-r.Context().Done():
+            cancelled <- true
+        case <-time.After(time.Second):
+            cancelled <- false
+        }
+    })
+
+    w := httptest.NewRecorder()
+    h(w, httptest.NewRequest(http.MethodGet, "/slow", nil), nil)
+
+    if w.Code != http.StatusServiceUnavailable || w.Header().Get("Content-Type") != "application/json" {
+        t.Errorf("slow handler = %d %s, want 503 application/json", w.Code, w.Header().Get("Content-Type"))
+    }
+    if want := `{"error":"request timed out","status":503}`; w.Body.String() != want {
+        t.Errorf("slow handler body = %s, want %s", w.Body, want)
+    }
+    if !<-cancelled {
+        t.Error("slow handler's context was not cancelled")
+    }
+}
+
+// TestLimitDurationFast checks that a handler within the limit is passed
+// through unchanged.
+func TestLimitDurationFast(t *testing.T) {
+    h := limitDuration(time.Second, func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+        w.Header().Set("Content-Type", "text/plain")
+        w.WriteHeader(http.StatusTeapot)
+        w.Write([]byte("short and stout"))
+    })
+
+    w := httptest.NewRecorder()
+    h(w, httptest.NewRequest(http.MethodGet, "/fast", nil), nil)
+    if w.Code != http.StatusTeapot || w.Header().Get("Content-Type") != "text/plain" || w.Body.String() != "short and stout" {
+        t.Errorf("fast handler = %d %s %q, want 418 text/plain %q", w.Code, w.Header().Get("Content-Type"), w.Body, "short and stout")
+    }
+}
+
+// TestLimitDurationLateReply runs a handler that sets a header and
+// answers only after the limit, checking that neither reaches the client.
+func TestLimitDurationLateReply(t *testing.T) {
+    var writeErr error
+    h := limitDuration(20 * time.Millisecond, func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+        w.Header().Set("ETag", `"late"`)
+        <-r.Context().Done()
+        _, writeErr = w.Write([]byte("too late"))
+    })
+
+    w := httptest.NewRecorder()
+    h(w, httptest.NewRequest(http.MethodGet, "/late", nil), nil)
+
+    if w.Code != http.StatusServiceUnavailable || w.Header().Get("ETag") != "" {
+        t.Errorf("late handler = %d with ETag %q, want 503 without it", w.Code, w.Header().Get("ETag"))
+    }
+    if writeErr != http.ErrHandlerTimeout {
+        t.Errorf("late Write error = %v, want %v", writeErr, http.ErrHandlerTimeout)
+    }
+}
+
+// TestLimitDurationStreams reads the first part of a reply over a real
+// connection while the handler is still writing, checking that the reply
+// is not held back until the handler returns and that no Content-Type is
+// forced onto it.
+func TestLimitDurationStreams(t *testing.T) {
+    release := make(chan struct{})
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        limitDuration(time.Minute, func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+            w.Write([]byte("first\n"))
+            flush(w)
+            <-release
+            w.Write([]byte("second\n"))
+        })(w, r, nil)
+    }))
+    defer server.Close()
+    defer close(release)
+
+    res, err := http.Get(server.URL)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer res.Body.Close()
+    if ct := res.Header.Get("Content-Type"); ct == "application/json" {
+        t.Errorf("Content-Type = %q, want the one sniffed from the body", ct)
+    }
+    line := make([]byte, len("first\n"))
+    if _, err := io.ReadFull(res.Body, line); err != nil || string(line) != "first\n" {
+        t.Errorf("first line = %q, %v, want %q while the handler runs", line, err, "first\n")
+    }
+}
+
+// TestLogBodies posts a body with an API key at the debug level, checking
+// that the handler still reads the whole body, that the logged bodies are
+// cut at the limit and that the key is redacted.
+func TestLogBodies(t *testing.T) {
+    buf := captureLog(t)
+    saved := getLogLevel()
+    setLogLevel(levelDebug)
+    t.Cleanup(func() { setLogLevel(saved) })
+
+    var read string
+    h := logBodies(8, func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+        b, _ := ioutil.ReadAll(r.Body)
+        read = string(b)
+        w.Write([]byte(`{"response":"long enough to cut"}`))
+    })
+    w := httptest.NewRecorder()
+    r := httptest.NewRequest(http.MethodPost, "/criarPokemon", strings.NewReader(`{"name":"pikachu"}`))
+    r.Header.Set("X-API-Key", "hunter2")
+    h(w, r, nil)
+
+    if read != `{"name":"pikachu"}` {
+        t.Errorf("handler read %q, want the whole body", read)
+    }
+    if w.Body.String() != `{"response":"long enough to cut"}` {
+        t.Errorf("response = %s, want it untouched", w.Body)
+    }
+    line := buf.String()
+    if strings.Contains(line, "hunter2") {
+        t.Errorf("log output %q contains the API key", line)
+    }
+    for _, want := range []string{"[REDACTED]", `request_body="{\"name\":"...`, `response_body="{\"respon"...`} {
+        if !strings.Contains(line, want) {
+            t.Errorf("log output %q does not contain %q", line, want)
+        }
+    }
+}
+
+// TestLogBodiesInfo checks that nothing is logged above the debug level.
+func TestLogBodiesInfo(t *testing.T) {
+    buf := captureLog(t)
+    h := logBodies(8, func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {})
+    h(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/criarPokemon", strings.NewReader("{}")), nil)
+    if buf.Len() != 0 {
+        t.Errorf("log output at the info level = %q, want none", buf)
+    }
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+    "fmt"
+    "io/ioutil"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+)
+
+// The mock upstreams shared by the tests. Those pointing PokéAPI at a
+// mock go through usePokeApi, so that the Pokemon cache filled during a
+// test never leaks into the next one.
+
+// usePokemonCache gives the test an empty Pokemon cache keeping entries
+// for ttl, and puts the previous cache back when the test ends.
+func usePokemonCache(t *testing.T, ttl time.Duration) {
+    saved := pokemonCache
+    pokemonCache = newResponseCache(ttl, 0)
+    t.Cleanup(func() { pokemonCache = saved })
+}
+
+// usePokeApi serves PokéAPI with h and an empty cache until the test
+// ends, when the Pokemon fetches the test gave up on are waited for.
+func usePokeApi(t *testing.T, h http.Handler) *httptest.Server {
+    upstream := httptest.NewServer(h)
+    t.Cleanup(upstream.Close)
+    overrideConfig(t).PokeAPIBaseURL = upstream.URL
+    usePokemonCache(t, time.Minute)
+    t.Cleanup(pokemonFetches.Wait)
+    return upstream
+}
+
+// servePaths answers each of the paths with its JSON payload, and with a
+// 404 anything else.
+func servePaths(payloads map[string]string) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        payload, ok := payloads[r.URL.Path]
+        if !ok {
+            http.NotFound(w, r)
+            return
+        }
+        w.Header().Set("Content-Type", "application/json")
+        w.Write([]byte(payload))
+    })
+}
+
+// serveFixture answers every request with testdata/file as JSON.
+func serveFixture(t *testing.T, file string) http.Handler {
+    data, err := ioutil.ReadFile("testdata/" + file)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("Content-Type", "application/json")
+        w.Write(data)
+    })
+}
+
+// fixtureServer serves testdata/<file> as JSON for every request.
+func fixtureServer(t *testing.T, file string) *httptest.Server {
+    upstream := httptest.NewServer(serveFixture(t, file))
+    t.Cleanup(upstream.Close)
+    return upstream
+}
+
+// pokeApiFixture serves the recorded PokéAPI payload in testdata/file for
+// every request and points PokéAPI at it until the test ends.
+func pokeApiFixture(t *testing.T, file string) *httptest.Server {
+    return usePokeApi(t, serveFixture(t, file))
+}
+
+// mockPokeApi serves a minimal PokéAPI payload for each of the given
+// names, numbered from 1, and a 404 for anything else. PokéAPI is pointed
+// at it until the test ends.
+func mockPokeApi(t *testing.T, names ...string) *httptest.Server {
+    payloads := make(map[string]string)
+    for i, name := range names {
+        payloads["/pokemon/" + name] = fmt.Sprintf(`{"name":%q,"id":%d,"types":[{"type":{"name":"normal"}}]}`, name, i + 1)
+    }
+    return usePokeApi(t, servePaths(payloads))
+}
+
+// mockComparePokeApi serves pikachu and raichu with a few base stats.
+func mockComparePokeApi(t *testing.T) {
+    usePokeApi(t, servePaths(map[string]string{
+        "/pokemon/pikachu": `{"name":"pikachu","id":25,"stats":[{"base_stat":35,"stat":{"name":"hp"}},{"base_stat":55,"stat":{"name":"attack"}},{"base_stat":90,"stat":{"name":"speed"}}]}`,
+        "/pokemon/raichu": `{"name":"raichu","id":26,"stats":[{"base_stat":60,"stat":{"name":"hp"}},{"base_stat":90,"stat":{"name":"attack"}},{"base_stat":90,"stat":{"name":"speed"}}]}`,
+    }))
+}
+
+// mockTeamPokeApi serves Pokemon of the given types with two base stats
+// of 10 each.
+func mockTeamPokeApi(t *testing.T, types map[string]string) {
+    payloads := make(map[string]string)
+    for name, typ := range types {
+        payloads["/pokemon/" + name] = fmt.Sprintf(`{"name":%q,"types":[{"type":{"name":%q}}],"stats":[{"base_stat":10,"stat":{"name":"hp"}},{"base_stat":10,"stat":{"name":"speed"}}]}`, name, typ)
+    }
+    usePokeApi(t, servePaths(payloads))
+}
+
+// mockFlavorPokeApi serves a Pokemon whose species has English and
+// Spanish flavor text, laid out as in the games.
+func mockFlavorPokeApi(t *testing.T) {
+    payloads := make(map[string]string)
+    upstream := usePokeApi(t, servePaths(payloads))
+    payloads["/pokemon/pikachu"] = `{"name":"pikachu","species":{"url":"` + upstream.URL + `/pokemon-species/25/"}}`
+    payloads["/pokemon-species/25/"] = `{"flavor_text_entries":[
+        {"flavor_text":"When several of\nthese POKéMON\fgather, their elec\u00ad\ntricity could\nbuild.","language":{"name":"en"}},
+        {"flavor_text":"Cuanto más potente es\nla energía eléctrica.","language":{"name":"es"}}
+    ]}`
+}
+
+// mockEvolutionPokeApi serves a Pokemon, its species and an evolution
+// chain, with the links between them pointing back at the mock.
+func mockEvolutionPokeApi(t *testing.T, chain string) {
+    payloads := map[string]string{"/evolution-chain/1/": chain}
+    var upstream *httptest.Server
+    upstream = usePokeApi(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if name := strings.TrimPrefix(r.URL.Path, "/pokemon/"); name != r.URL.Path {
+            w.Header().Set("Content-Type", "application/json")
+            w.Write([]byte(`{"name":"` + name + `","species":{"url":"` + upstream.URL + `/pokemon-species/1/"}}`))
+            return
+        }
+        servePaths(payloads).ServeHTTP(w, r)
+    }))
+    payloads["/pokemon-species/1/"] = `{"evolution_chain":{"url":"` + upstream.URL + `/evolution-chain/1/"}}`
+}
+
+// mockGappyPokeApi answers 404 to the first missing requests and with a
+// Pokemon after that, recording the requested paths.
+func mockGappyPokeApi(t *testing.T, missing int) *[]string {
+    var paths []string
+    usePokeApi(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        paths = append(paths, r.URL.Path)
+        if len(paths) <= missing {
+            http.NotFound(w, r)
+            return
+        }
+        w.Header().Set("Content-Type", "application/json")
+        w.Write([]byte(`{"name":"bulbasaur","id":1}`))
+    }))
+    config.MaxPokemonID = 1
+    return &paths
+}
+
+// mockPokemonList serves a short list of every Pokemon, counting how many
+// times it was fetched.
+func mockPokemonList(t *testing.T) *int {
+    calls := 0
+    usePokeApi(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.URL.Path != "/pokemon" || r.URL.Query().Get("limit") != "10000" {
+            http.NotFound(w, r)
+            return
+        }
+        calls++
+        w.Write([]byte(`{"count":6,"results":[{"name":"pidgey"},{"name":"pikachu"},{"name":"pidgeotto"},{"name":"raichu"},{"name":"pichu"},{"name":"pidgeot"}]}`))
+    }))
+    return &calls
+}
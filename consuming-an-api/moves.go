@@ -0,0 +1,33 @@
+package main
+
+import (
+    "net/http"
+
+    "github.com/julienschmidt/httprouter"
+)
+
+// retornarMovimentos lists the names of the moves a Pokemon can learn,
+// capped at ?limit= when given.
+func retornarMovimentos(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+    limit, err := queryLimit(r)
+    if err != nil {
+        writeHttpError(w, err)
+        return
+    }
+    pokemon, err := fetchRawPokemon(r.Context(), ps.ByName("nome"))
+    if err != nil {
+        writeHttpError(w, err)
+        return
+    }
+
+    // Made with make so that a Pokemon without moves is [] rather than
+    // null.
+    moves := make([]string, 0, len(pokemon.Moves))
+    for _, m := range pokemon.Moves {
+        if limit >= 0 && len(moves) == limit {
+            break
+        }
+        moves = append(moves, m.Move.Name)
+    }
+    writeJsonFor(w, r, http.StatusOK, moves)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "testing"
+)
+
+// TestRetornarMovimentos lists the moves of a recorded payload, with and
+// without a limit.
+func TestRetornarMovimentos(t *testing.T) {
+    pokeApiFixture(t, "pikachu.json")
+
+    tests := []struct {
+        url string
+        want string
+    }{
+        {"/pokemon/pikachu/moves", `["mega-punch","pay-day","thunder-punch"]`},
+        {"/pokemon/pikachu/moves?limit=1", `["mega-punch"]`},
+    }
+    for _, tt := range tests {
+        w := httptest.NewRecorder()
+        newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.url, nil))
+        if w.Code != http.StatusOK || w.Body.String() != tt.want {
+            t.Errorf("GET %s = %d %s, want %d %s", tt.url, w.Code, w.Body, http.StatusOK, tt.want)
+        }
+    }
+}
+
+// TestRetornarMovimentosNone lists a Pokemon without moves, checking for
+// an empty array rather than null.
+func TestRetornarMovimentosNone(t *testing.T) {
+    mockPokeApi(t, "ditto")
+
+    w := httptest.NewRecorder()
+    newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemon/ditto/moves", nil))
+    if w.Code != http.StatusOK || w.Body.String() != `[]` {
+        t.Errorf("GET /pokemon/ditto/moves = %d %s, want 200 []", w.Code, w.Body)
+    }
+}
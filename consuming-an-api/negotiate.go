@@ -0,0 +1,81 @@
+package main
+
+import (
+    "encoding/xml"
+    "net/http"
+    "strconv"
+    "strings"
+)
+
+// Media types writeNegotiated can produce.
+const (
+    mediaJson = "application/json"
+    mediaXml = "application/xml"
+)
+
+// negotiate picks the media type to reply with from the request's Accept
+// header, preferring the types the client ranks higher. JSON is used when
+// the client does not say, and "" is returned when it only accepts types
+// we cannot produce.
+func negotiate(r *http.Request) string {
+    accept := r.Header.Get("Accept")
+    if strings.TrimSpace(accept) == "" {
+        return mediaJson
+    }
+
+    best, bestQ := "", 0.0
+    for _, part := range strings.Split(accept, ",") {
+        params := strings.Split(part, ";")
+        q := 1.0
+        for _, param := range params[1:] {
+            param = strings.TrimSpace(param)
+            if strings.HasPrefix(param, "q=") {
+                if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+                    q = v
+                }
+            }
+        }
+
+        var media string
+        switch strings.ToLower(strings.TrimSpace(params[0])) {
+        case "application/json", "application/*", "*/*":
+            media = mediaJson
+        case "application/xml", "text/xml":
+            media = mediaXml
+        }
+        if media != "" && q > bestQ {
+            best, bestQ = media, q
+        }
+    }
+    return best
+}
+
+// writeNegotiated replies with v encoded in the media type picked by
+// negotiate, or with a 406 when there is none.
+func writeNegotiated(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+    switch negotiate(r) {
+    case mediaJson:
+        writeJsonFor(w, r, status, v)
+    case mediaXml:
+        marshal := xml.Marshal
+        if wantsPretty(r) {
+            marshal = func(v interface{}) ([]byte, error) { return xml.MarshalIndent(v, "", "  ") }
+        }
+        b, err := marshal(v)
+        if err != nil {
+            logErrorf("%v", err)
+            writeError(w, http.StatusInternalServerError, "could not encode response")
+            return
+        }
+        w.Header().Set("Content-Type", mediaXml)
+        w.WriteHeader(status)
+        w.Write([]byte(xml.Header))
+        w.Write(b)
+    default:
+        writeNotAcceptable(w)
+    }
+}
+
+func writeNotAcceptable(w http.ResponseWriter) {
+    writeError(w, http.StatusNotAcceptable, "can only produce application/json or application/xml")
+}
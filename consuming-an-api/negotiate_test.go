@@ -0,0 +1,60 @@
+package main
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+)
+
+// getStructAccepting requests /retornarStruct with the given Accept
+// header.
+func getStructAccepting(accept string) *httptest.ResponseRecorder {
+    w := httptest.NewRecorder()
+    r := httptest.NewRequest(http.MethodGet, "/retornarStruct?body=oi&number=1&decimal=2&validate=false", nil)
+    if accept != "" {
+        r.Header.Set("Accept", accept)
+    }
+    newRouter().ServeHTTP(w, r)
+    return w
+}
+
+// TestNegotiateJsonDefault checks that JSON is served without an Accept
+// header and for wildcards.
+func TestNegotiateJsonDefault(t *testing.T) {
+    for _, accept := range []string{"", "*/*", "application/json", "text/html, application/json;q=0.9"} {
+        w := getStructAccepting(accept)
+        want := `{"Body":"oi","Number":1,"Decimal":2,"Validate":false}`
+        if w.Header().Get("Content-Type") != mediaJson || w.Body.String() != want {
+            t.Errorf("Accept %q: got %s %s, want JSON %s", accept, w.Header().Get("Content-Type"), w.Body, want)
+        }
+    }
+}
+
+// TestNegotiateXml asks for XML, checking the Message is encoded with
+// encoding/xml.
+func TestNegotiateXml(t *testing.T) {
+    for _, accept := range []string{"application/xml", "application/json;q=0.5, application/xml"} {
+        w := getStructAccepting(accept)
+        want := "<message><body>oi</body><number>1</number><decimal>2</decimal><validate>false</validate></message>"
+        if w.Header().Get("Content-Type") != mediaXml || !strings.HasSuffix(w.Body.String(), want) {
+            t.Errorf("Accept %q: got %s %s, want XML %s", accept, w.Header().Get("Content-Type"), w.Body, want)
+        }
+    }
+}
+
+// TestNegotiateUnsupported asks only for types we cannot produce,
+// checking for a 406 from both Message endpoints.
+func TestNegotiateUnsupported(t *testing.T) {
+    if w := getStructAccepting("text/csv"); w.Code != http.StatusNotAcceptable {
+        t.Errorf("GET /retornarStruct with Accept text/csv = %d, want %d", w.Code, http.StatusNotAcceptable)
+    }
+
+    w := httptest.NewRecorder()
+    r := httptest.NewRequest(http.MethodPost, "/message", strings.NewReader(`{"Body":"oi"}`))
+    r.Header.Set("Accept", "text/csv")
+    newRouter().ServeHTTP(w, r)
+    if w.Code != http.StatusNotAcceptable {
+        t.Errorf("POST /message with Accept text/csv = %d, want %d", w.Code, http.StatusNotAcceptable)
+    }
+}
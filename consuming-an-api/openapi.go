@@ -0,0 +1,81 @@
+package main
+
+import (
+    "net/http"
+    "strings"
+
+    "github.com/julienschmidt/httprouter"
+)
+
+// The parts of an OpenAPI 3.0 document that /openapi.json fills in.
+type openAPIDoc struct {
+    OpenAPI string `json:"openapi"`
+    Info openAPIInfo `json:"info"`
+    Paths map[string]map[string]openAPIOperation `json:"paths"`
+}
+
+type openAPIInfo struct {
+    Title string `json:"title"`
+    Version string `json:"version"`
+}
+
+type openAPIOperation struct {
+    Summary string `json:"summary"`
+    Parameters []openAPIParameter `json:"parameters,omitempty"`
+    Responses map[string]openAPIResponse `json:"responses"`
+}
+
+type openAPIParameter struct {
+    Name string `json:"name"`
+    In string `json:"in"`
+    Required bool `json:"required"`
+    Schema map[string]string `json:"schema"`
+}
+
+type openAPIResponse struct {
+    Description string `json:"description"`
+}
+
+// openAPIPath turns an httprouter path into an OpenAPI one, returning the
+// names of its parameters: /pokemon/:nome becomes /pokemon/{nome}.
+func openAPIPath(path string) (string, []string) {
+    var params []string
+    segments := strings.Split(path, "/")
+    for i, segment := range segments {
+        if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+            params = append(params, segment[1:])
+            segments[i] = "{" + segment[1:] + "}"
+        }
+    }
+    return strings.Join(segments, "/"), params
+}
+
+// openAPISpec describes the routes as an OpenAPI document. It only goes
+// as far as paths, methods and path parameters.
+func openAPISpec(rs []route) openAPIDoc {
+    doc := openAPIDoc{
+        OpenAPI: "3.0.3",
+        Info: openAPIInfo{Title: "go-learn API", Version: version},
+        Paths: make(map[string]map[string]openAPIOperation),
+    }
+    for _, rt := range rs {
+        path, params := openAPIPath(rt.Path)
+        op := openAPIOperation{
+            Summary: rt.Description,
+            Responses: map[string]openAPIResponse{"default": {Description: "The response, or an error object."}},
+        }
+        for _, name := range params {
+            op.Parameters = append(op.Parameters, openAPIParameter{Name: name, In: "path", Required: true, Schema: map[string]string{"type": "string"}})
+        }
+        if doc.Paths[path] == nil {
+            doc.Paths[path] = make(map[string]openAPIOperation)
+        }
+        doc.Paths[path][strings.ToLower(rt.Method)] = op
+    }
+    return doc
+}
+
+// retornarOpenAPI serves the OpenAPI description of the routes.
+func retornarOpenAPI(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+    writeJsonFor(w, r, http.StatusOK, openAPISpec(routes()))
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+)
+
+// TestRetornarOpenAPI checks that /openapi.json parses and describes the
+// Pokemon route with its path parameter.
+func TestRetornarOpenAPI(t *testing.T) {
+    w := httptest.NewRecorder()
+    newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+    if w.Code != http.StatusOK {
+        t.Fatalf("GET /openapi.json status = %d, want %d", w.Code, http.StatusOK)
+    }
+
+    var doc openAPIDoc
+    if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+        t.Fatalf("GET /openapi.json is not JSON: %v", err)
+    }
+    if doc.OpenAPI != "3.0.3" {
+        t.Errorf("openapi = %q, want 3.0.3", doc.OpenAPI)
+    }
+    op, ok := doc.Paths["/retornarPokemon/{nome}"]["get"]
+    if !ok {
+        t.Fatalf("paths = %v, want GET /retornarPokemon/{nome}", doc.Paths)
+    }
+    want := openAPIParameter{Name: "nome", In: "path", Required: true, Schema: map[string]string{"type": "string"}}
+    if len(op.Parameters) != 1 || op.Parameters[0].Name != want.Name || op.Parameters[0].In != want.In || !op.Parameters[0].Required {
+        t.Errorf("GET /retornarPokemon/{nome} parameters = %+v, want [%+v]", op.Parameters, want)
+    }
+    if _, ok := doc.Paths["/pokemon/{nome}"]["delete"]; !ok {
+        t.Errorf("paths = %v, want DELETE /pokemon/{nome} next to PUT", doc.Paths)
+    }
+}
+
+// TestOpenAPIPath checks the conversion of router paths.
+func TestOpenAPIPath(t *testing.T) {
+    tests := []struct {
+        path, want string
+        params int
+    }{
+        {"/pokemons", "/pokemons", 0},
+        {"/pokemon/:nome/sprite", "/pokemon/{nome}/sprite", 1},
+        {"/pokeapi/*path", "/pokeapi/{path}", 1},
+    }
+    for _, tt := range tests {
+        got, params := openAPIPath(tt.path)
+        if got != tt.want || len(params) != tt.params {
+            t.Errorf("openAPIPath(%q) = %q %v, want %q with %d parameters", tt.path, got, params, tt.want, tt.params)
+        }
+    }
+}
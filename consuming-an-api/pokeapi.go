@@ -0,0 +1,214 @@
+package main
+
+import (
+    "context"
+    "net/http"
+    "sync"
+
+    "golang.org/x/sync/singleflight"
+)
+
+// PokemonResponse is the trimmed view of a PokéAPI Pokemon we hand out.
+type PokemonResponse struct {
+    Name string `json:"name"`
+    ID int `json:"id"`
+    Height int `json:"height"`
+    Weight int `json:"weight"`
+    BaseExperience int `json:"base_experience"`
+    Types []string `json:"types"`
+    Stats map[string]int `json:"stats,omitempty"`
+}
+
+// pokeApiPokemon mirrors the parts of the PokéAPI payload we decode.
+type pokeApiPokemon struct {
+    Name string `json:"name"`
+    ID int `json:"id"`
+    Height int `json:"height"`
+    Weight int `json:"weight"`
+    BaseExperience int `json:"base_experience"`
+    Types []struct {
+        Type struct {
+            Name string `json:"name"`
+        } `json:"type"`
+    } `json:"types"`
+    Stats []struct {
+        BaseStat int `json:"base_stat"`
+        Stat struct {
+            Name string `json:"name"`
+        } `json:"stat"`
+    } `json:"stats"`
+    Sprites struct {
+        FrontDefault string `json:"front_default"`
+    } `json:"sprites"`
+    Species struct {
+        URL string `json:"url"`
+    } `json:"species"`
+    Moves []struct {
+        Move struct {
+            Name string `json:"name"`
+        } `json:"move"`
+    } `json:"moves"`
+}
+
+// fetchPokemon looks up the named Pokemon on PokéAPI and trims it down to
+// a PokemonResponse.
+func fetchPokemon(ctx context.Context, name string) (PokemonResponse, error) {
+    raw, err := fetchRawPokemon(ctx, name)
+    if err != nil {
+        return PokemonResponse{}, err
+    }
+    return trimPokemon(raw), nil
+}
+
+// trimPokemon keeps the fields of raw we hand out.
+func trimPokemon(raw pokeApiPokemon) PokemonResponse {
+    pokemon := PokemonResponse{
+        Name: raw.Name,
+        ID: raw.ID,
+        Height: raw.Height,
+        Weight: raw.Weight,
+        BaseExperience: raw.BaseExperience,
+        Types: make([]string, 0, len(raw.Types)),
+    }
+    for _, t := range raw.Types {
+        pokemon.Types = append(pokemon.Types, t.Type.Name)
+    }
+    if len(raw.Stats) > 0 {
+        pokemon.Stats = make(map[string]int, len(raw.Stats))
+        for _, s := range raw.Stats {
+            pokemon.Stats[s.Stat.Name] = s.BaseStat
+        }
+    }
+    return pokemon
+}
+
+// fetchRawPokemon looks up the named Pokemon on PokéAPI. Raw responses
+// are cached since Pokemon data never changes, concurrent misses share
+// a single upstream call, and upstream calls go through pokeApiBreaker.
+func fetchRawPokemon(ctx context.Context, name string) (pokeApiPokemon, error) {
+    raw, _, err := fetchRawPokemonStale(ctx, name)
+    return raw, err
+}
+
+// fetchWithFailover GETs path from config.PokeAPIBaseURL, moving on to
+// each of config.PokeAPIMirrors in turn while the one tried cannot be
+// reached or answers with a server error. Those the upstreams registry
+// last found unhealthy are tried after the others.
+func fetchWithFailover(ctx context.Context, path string) ([]byte, error) {
+    var err error
+    for _, base := range upstreams.byHealth(append([]string{config.PokeAPIBaseURL}, config.PokeAPIMirrors...)) {
+        var responseData []byte
+        responseData, err = fetchUpstream(ctx, base + path)
+        he, _ := err.(*httpError)
+        if err == nil || he == nil || he.status < 500 || err == errUpstreamBusy || ctx.Err() != nil {
+            return responseData, err
+        }
+        logErrorf("request_id=%s pokeapi mirror %s failed: %v", requestIDFromContext(ctx), base, err)
+    }
+    return nil, err
+}
+
+// pokemonFlight makes concurrent cache misses for the same Pokemon share
+// a single upstream call.
+var pokemonFlight singleflight.Group
+
+// pokemonFetches counts the calls to downloadPokemon until the shared
+// fetch they joined ends, which may be after they gave up on it, so that
+// the fetches left running can be waited for.
+var pokemonFetches sync.WaitGroup
+
+// downloadPokemon fetches the raw PokéAPI payload of name past the cache,
+// refreshing the cache with it. It gives up with ctx.Err() once ctx is
+// done, leaving the fetch to finish for the other requests sharing it.
+func downloadPokemon(ctx context.Context, name string) ([]byte, error) {
+    pokemonFetches.Add(1)
+    results := pokemonFlight.DoChan(name, func() (interface{}, error) {
+        // The fetch is shared by every concurrent request for name, so it
+        // must not be cut short when the one that started it goes away,
+        // but it gets a limit of its own now that nobody may wait for it.
+        detached := context.WithoutCancel(ctx)
+        shared, cancel := context.WithTimeout(detached, config.UpstreamTimeout)
+        defer cancel()
+        var data []byte
+        err := pokeApiBreaker.call(detached, func() error {
+            var err error
+            data, err = fetchWithFailover(shared, "/pokemon/" + name)
+            return err
+        })
+        if err == nil {
+            pokemonCache.set(name, data)
+        }
+        return data, err
+    })
+
+    var result singleflight.Result
+    select {
+    case <-ctx.Done():
+        go func() {
+            <-results
+            pokemonFetches.Done()
+        }()
+        return nil, ctx.Err()
+    case result = <-results:
+        pokemonFetches.Done()
+    }
+    if he, ok := result.Err.(*httpError); ok && he.status == http.StatusNotFound {
+        return nil, &httpError{http.StatusNotFound, "pokemon not found"}
+    }
+    if result.Err != nil {
+        return nil, result.Err
+    }
+    return result.Val.([]byte), nil
+}
+
+// decodePokemon decodes a raw PokéAPI payload.
+func decodePokemon(responseData []byte) (pokeApiPokemon, error) {
+    var raw pokeApiPokemon
+    if err := decodeUpstreamJSON(responseData, &raw); err != nil {
+        return pokeApiPokemon{}, err
+    }
+    return raw, nil
+}
+
+// fetchLivePokemon is fetchPokemon past the cache, for when only what
+// PokéAPI says right now will do.
+func fetchLivePokemon(ctx context.Context, name string) (PokemonResponse, error) {
+    responseData, err := downloadPokemon(ctx, name)
+    if err != nil {
+        return PokemonResponse{}, err
+    }
+    raw, err := decodePokemon(responseData)
+    if err != nil {
+        return PokemonResponse{}, err
+    }
+    return trimPokemon(raw), nil
+}
+
+// fetchRawPokemonStale is fetchRawPokemon that, with config.StaleIfError
+// set, falls back to an expired cache entry when PokéAPI fails. It
+// reports whether it did.
+func fetchRawPokemonStale(ctx context.Context, name string) (pokeApiPokemon, bool, error) {
+    stale := false
+    responseData, ok := pokemonCache.get(name)
+    if !ok {
+        var err error
+        responseData, err = downloadPokemon(ctx, name)
+        he, _ := err.(*httpError)
+        switch {
+        case err == nil:
+        case he != nil && he.status >= 500 && config.StaleIfError:
+            if responseData, stale = pokemonCache.getStale(name); !stale {
+                return pokeApiPokemon{}, false, err
+            }
+            logInfof("serving stale %s: %v", name, err)
+        default:
+            return pokeApiPokemon{}, false, err
+        }
+    }
+
+    raw, err := decodePokemon(responseData)
+    if err != nil {
+        return pokeApiPokemon{}, false, err
+    }
+    return raw, stale, nil
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "io/ioutil"
+    "net/http"
+    "net/http/httptest"
+    "reflect"
+    "strings"
+    "sync"
+    "sync/atomic"
+    "testing"
+    "time"
+)
+
+// TestFetchPokemon decodes a recorded PokéAPI payload, checking the
+// trimmed fields.
+func TestFetchPokemon(t *testing.T) {
+    pokeApiFixture(t, "pikachu.json")
+
+    got, err := fetchPokemon(context.Background(), "pikachu")
+    if err != nil {
+        t.Fatalf("fetchPokemon(pikachu) error = %v", err)
+    }
+    want := PokemonResponse{
+        Name: "pikachu",
+        ID: 25,
+        Height: 4,
+        Weight: 60,
+        BaseExperience: 112,
+        Types: []string{"electric"},
+        Stats: map[string]int{
+            "hp": 35,
+            "attack": 55,
+            "defense": 40,
+            "special-attack": 50,
+            "special-defense": 50,
+            "speed": 90,
+        },
+    }
+    if !reflect.DeepEqual(got, want) {
+        t.Fatalf("fetchPokemon(pikachu) = %+v, want %+v", got, want)
+    }
+}
+
+// TestRetornarPokemonNotFound asks for a Pokemon PokéAPI does not know,
+// checking that our client gets a JSON 404.
+func TestRetornarPokemonNotFound(t *testing.T) {
+    mockPokeApi(t)
+
+    w := httptest.NewRecorder()
+    newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/retornarPokemon/missingno", nil))
+
+    if w.Code != http.StatusNotFound {
+        t.Fatalf("GET /retornarPokemon/missingno status = %d, want %d", w.Code, http.StatusNotFound)
+    }
+    if got := w.Header().Get("Content-Type"); got != "application/json" {
+        t.Errorf("Content-Type = %q, want application/json", got)
+    }
+    if got, want := w.Body.String(), `{"error":"pokemon not found","status":404}`; got != want {
+        t.Errorf("body = %s, want %s", got, want)
+    }
+}
+
+// TestFetchPokemonMirror makes the primary PokéAPI fail, checking that
+// the Pokemon comes from the mirror instead.
+func TestFetchPokemonMirror(t *testing.T) {
+    fastRetries(t)
+    mirror := pokeApiFixture(t, "pikachu.json")
+    primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        http.Error(w, "down", http.StatusServiceUnavailable)
+    }))
+    defer primary.Close()
+    config.PokeAPIBaseURL = primary.URL
+    config.PokeAPIMirrors = []string{closedURL(t), mirror.URL}
+
+    got, err := fetchPokemon(context.Background(), "pikachu")
+    if err != nil {
+        t.Fatalf("fetchPokemon(pikachu) error = %v", err)
+    }
+    if got.Name != "pikachu" {
+        t.Errorf("Name = %q, want the mirror's pikachu", got.Name)
+    }
+}
+
+// TestFetchPokemonUnhealthyFirst registers a primary PokéAPI that failed
+// its health check, checking that the Pokemon is fetched from the healthy
+// mirror without trying the primary first.
+func TestFetchPokemonUnhealthyFirst(t *testing.T) {
+    fastRetries(t)
+    mirror := pokeApiFixture(t, "pikachu.json")
+    var gets int32
+    primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.Method == http.MethodGet {
+            atomic.AddInt32(&gets, 1)
+        }
+        http.Error(w, "down", http.StatusServiceUnavailable)
+    }))
+    defer primary.Close()
+    config.PokeAPIBaseURL = primary.URL
+    config.PokeAPIMirrors = []string{mirror.URL}
+    registry := registryFromConfig(config)
+    registry.checkAll(context.Background())
+    useRegistry(t, registry)
+
+    got, err := fetchPokemon(context.Background(), "pikachu")
+    if err != nil || got.Name != "pikachu" {
+        t.Fatalf("fetchPokemon(pikachu) = %q, %v, want the mirror's pikachu", got.Name, err)
+    }
+    if n := atomic.LoadInt32(&gets); n != 0 {
+        t.Errorf("unhealthy primary got %d GETs, want none", n)
+    }
+}
+
+// TestRetornarPokemonHandlerTimeout points PokéAPI at a hanging upstream,
+// checking that the handler time limit still answers with a 503 in time
+// and that a request whose context is done gives up at once.
+func TestRetornarPokemonHandlerTimeout(t *testing.T) {
+    release := make(chan struct{})
+    usePokeApi(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        select {
+        case <-release:
+            w.Write([]byte(`{"name":"ditto","id":132}`))
+        case <-r.Context().Done():
+        }
+    }))
+    // Release the upstream before the fetch the test gave up on is waited
+    // for.
+    t.Cleanup(func() { close(release) })
+    config.HandlerTimeout = 100 * time.Millisecond
+
+    start := time.Now()
+    w := httptest.NewRecorder()
+    newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/retornarPokemon/ditto", nil))
+    if elapsed := time.Since(start); w.Code != http.StatusServiceUnavailable || elapsed > time.Second {
+        t.Errorf("status = %d after %s, want %d within the handler timeout", w.Code, elapsed, http.StatusServiceUnavailable)
+    }
+
+    ctx, cancel := context.WithCancel(context.Background())
+    cancel()
+    start = time.Now()
+    if _, err := fetchPokemon(ctx, "ditto"); err != context.Canceled || time.Since(start) > time.Second {
+        t.Errorf("fetchPokemon with a cancelled context = %v after %s, want %v at once", err, time.Since(start), context.Canceled)
+    }
+}
+
+// TestRetornarPokemonSingleflight fires concurrent requests for the same
+// Pokemon on a cold cache, checking that they share one upstream call.
+func TestRetornarPokemonSingleflight(t *testing.T) {
+    data, err := ioutil.ReadFile("testdata/pikachu.json")
+    if err != nil {
+        t.Fatal(err)
+    }
+    var calls int32
+    usePokeApi(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        atomic.AddInt32(&calls, 1)
+        // Long enough for every request to join the call in flight.
+        time.Sleep(100 * time.Millisecond)
+        w.Write(data)
+    }))
+    router := newRouter()
+
+    const requests = 20
+    codes := make(chan int, requests)
+    var wg sync.WaitGroup
+    for i := 0; i < requests; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            w := httptest.NewRecorder()
+            router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/retornarPokemon/pikachu", nil))
+            codes <- w.Code
+        }()
+    }
+    wg.Wait()
+    close(codes)
+
+    for code := range codes {
+        if code != http.StatusOK {
+            t.Errorf("status = %d, want %d", code, http.StatusOK)
+        }
+    }
+    if n := atomic.LoadInt32(&calls); n != 1 {
+        t.Errorf("upstream calls = %d, want 1", n)
+    }
+}
+
+// TestRetornarPokemonHTML has PokéAPI answer with an HTML block page,
+// checking for a 502 envelope and the start of the page in the log.
+func TestRetornarPokemonHTML(t *testing.T) {
+    page := "<!DOCTYPE html><html><title>Attention Required! | Cloudflare</title>" + strings.Repeat("<p>blocked</p>", 100) + "</html>"
+    tests := []struct {
+        name, contentType string
+    }{
+        {"html", "text/html; charset=UTF-8"},
+        {"json", "application/json"},
+    }
+    for _, tt := range tests {
+        upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            w.Header().Set("Content-Type", tt.contentType)
+            w.Write([]byte(page))
+        }))
+        overrideConfig(t).PokeAPIBaseURL = upstream.URL
+        usePokemonCache(t, time.Minute)
+        buf := captureLog(t)
+
+        w := httptest.NewRecorder()
+        newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/retornarPokemon/pikachu", nil))
+        upstream.Close()
+
+        var body errorResponse
+        if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || w.Code != http.StatusBadGateway || body.Error != "unexpected upstream response" {
+            t.Errorf("%s: GET /retornarPokemon/pikachu = %d %s, want a 502 unexpected upstream response", tt.name, w.Code, w.Body)
+        }
+        logged := buf.String()
+        if !strings.Contains(logged, "Attention Required!") || strings.Contains(logged, "</html>") {
+            t.Errorf("%s: log = %q, want the start of the page only", tt.name, logged)
+        }
+    }
+}
+
+func TestMaybeJSON(t *testing.T) {
+    for contentType, want := range map[string]bool{
+        "": true,
+        "application/json": true,
+        "application/json; charset=utf-8": true,
+        "application/problem+json": true,
+        "text/plain; charset=utf-8": true,
+        "text/html; charset=UTF-8": false,
+        "application/xml": false,
+        "not a type;;": false,
+    } {
+        if got := maybeJSON(contentType); got != want {
+            t.Errorf("maybeJSON(%q) = %v, want %v", contentType, got, want)
+        }
+    }
+}
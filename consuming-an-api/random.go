@@ -0,0 +1,50 @@
+package main
+
+import (
+    "crypto/rand"
+    "math/big"
+    "net/http"
+    "strconv"
+
+    "github.com/julienschmidt/httprouter"
+)
+
+// randomPokemonAttempts is how many ids /pokemons/random tries before
+// giving up, since PokéAPI has gaps in its id range.
+const randomPokemonAttempts = 3
+
+// randomPokemonID picks an id between 1 and config.MaxPokemonID. It reads
+// from crypto/rand, so the sequence does not repeat across restarts.
+func randomPokemonID() (int, error) {
+    n, err := rand.Int(rand.Reader, big.NewInt(int64(config.MaxPokemonID)))
+    if err != nil {
+        return 0, err
+    }
+    return int(n.Int64()) + 1, nil
+}
+
+// retornarPokemonAleatorio replies with a random Pokemon, trying another
+// id when PokéAPI has none under the one picked.
+func retornarPokemonAleatorio(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+    var err error
+    for attempt := 0; attempt < randomPokemonAttempts; attempt++ {
+        var id int
+        if id, err = randomPokemonID(); err != nil {
+            logErrorf("picking a random pokemon id: %v", err)
+            writeError(w, http.StatusInternalServerError, "internal error")
+            return
+        }
+
+        var pokemon PokemonResponse
+        pokemon, err = fetchPokemon(r.Context(), strconv.Itoa(id))
+        if err == nil {
+            writeJsonFor(w, r, http.StatusOK, pokemon)
+            return
+        }
+        if he, ok := err.(*httpError); !ok || he.status != http.StatusNotFound {
+            break
+        }
+        logDebugf("random pokemon id %d not found, picking another", id)
+    }
+    writeHttpError(w, err)
+}
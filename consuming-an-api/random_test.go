@@ -0,0 +1,54 @@
+package main
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+)
+
+// TestRetornarPokemonAleatorio has the first id picked missing upstream,
+// checking that another one is tried and returned.
+func TestRetornarPokemonAleatorio(t *testing.T) {
+    paths := mockGappyPokeApi(t, 1)
+
+    w := httptest.NewRecorder()
+    newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemons/random", nil))
+    if w.Code != http.StatusOK {
+        t.Fatalf("GET /pokemons/random status = %d, want %d", w.Code, http.StatusOK)
+    }
+    var got PokemonResponse
+    if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+        t.Fatal(err)
+    }
+    if got.Name != "bulbasaur" || len(*paths) != 2 || (*paths)[0] != "/pokemon/1" {
+        t.Errorf("GET /pokemons/random = %+v after %v, want bulbasaur after two lookups of /pokemon/1", got, *paths)
+    }
+}
+
+// TestRetornarPokemonAleatorioGivesUp has every id missing, checking that
+// the lookups are bounded.
+func TestRetornarPokemonAleatorioGivesUp(t *testing.T) {
+    paths := mockGappyPokeApi(t, randomPokemonAttempts + 1)
+
+    w := httptest.NewRecorder()
+    newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemons/random", nil))
+    if w.Code != http.StatusNotFound || len(*paths) != randomPokemonAttempts {
+        t.Errorf("GET /pokemons/random = %d after %d lookups, want 404 after %d", w.Code, len(*paths), randomPokemonAttempts)
+    }
+}
+
+// TestRandomPokemonID checks that picked ids stay within the configured
+// range.
+func TestRandomPokemonID(t *testing.T) {
+    overrideConfig(t).MaxPokemonID = 3
+    for i := 0; i < 100; i++ {
+        id, err := randomPokemonID()
+        if err != nil {
+            t.Fatal(err)
+        }
+        if id < 1 || id > 3 {
+            t.Fatalf("randomPokemonID() = %d, want 1 to 3", id)
+        }
+    }
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+    "context"
+    "net/http"
+    "net/url"
+    "strconv"
+    "strings"
+
+    "github.com/julienschmidt/httprouter"
+)
+
+// RandomUser is the flattened view of a randomuser.me user we hand out.
+type RandomUser struct {
+    FirstName string `json:"first_name"`
+    LastName string `json:"last_name"`
+    Email string `json:"email"`
+    Country string `json:"country"`
+}
+
+// randomUserResponse mirrors the parts of the randomuser.me payload we
+// decode.
+type randomUserResponse struct {
+    Results []struct {
+        Name struct {
+            First string `json:"first"`
+            Last string `json:"last"`
+        } `json:"name"`
+        Email string `json:"email"`
+        Location struct {
+            Country string `json:"country"`
+        } `json:"location"`
+    } `json:"results"`
+}
+
+// fetchRandomUser asks randomuser.me for a user and flattens it.
+func fetchRandomUser(ctx context.Context) (RandomUser, error) {
+    responseData, err := fetchUpstream(ctx, config.RandomUserBaseURL + "/")
+    if err != nil {
+        return RandomUser{}, err
+    }
+
+    var raw randomUserResponse
+    if err := decodeUpstreamJSON(responseData, &raw); err != nil {
+        return RandomUser{}, err
+    }
+    if len(raw.Results) == 0 {
+        logErrorf("randomuser response without results")
+        return RandomUser{}, errUnexpectedUpstream
+    }
+
+    u := raw.Results[0]
+    return RandomUser{
+        FirstName: u.Name.First,
+        LastName: u.Name.Last,
+        Email: u.Email,
+        Country: u.Location.Country,
+    }, nil
+}
+
+// retornarUsuarioSimples returns a random user trimmed to a RandomUser.
+func retornarUsuarioSimples(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+    w.Header().Set("Cache-Control", "no-store")
+
+    user, err := fetchRandomUser(r.Context())
+    if err != nil {
+        writeHttpError(w, err)
+        return
+    }
+    writeJsonFor(w, r, http.StatusOK, user)
+}
+
+// randomUserQuery validates the query parameters of a random user request
+// and returns the ones to forward to randomuser.me. Only results, gender,
+// nat and seed are accepted.
+func randomUserQuery(r *http.Request) (url.Values, error) {
+    forward := url.Values{}
+    for key, values := range r.URL.Query() {
+        value := values[0]
+        switch key {
+        case "results":
+            n, err := strconv.Atoi(value)
+            if err != nil || n < 1 || n > 100 {
+                return nil, &httpError{http.StatusBadRequest, "results must be an integer between 1 and 100"}
+            }
+        case "gender":
+            if value != "male" && value != "female" {
+                return nil, &httpError{http.StatusBadRequest, "gender must be male or female"}
+            }
+        case "nat":
+            for _, nat := range strings.Split(value, ",") {
+                if !isCountryCode(nat) {
+                    return nil, &httpError{http.StatusBadRequest, "nat must be a comma-separated list of country codes"}
+                }
+            }
+        case "seed":
+            if value == "" {
+                return nil, &httpError{http.StatusBadRequest, "seed must not be empty"}
+            }
+        default:
+            return nil, &httpError{http.StatusBadRequest, "unknown query parameter " + strconv.Quote(key)}
+        }
+        forward.Set(key, value)
+    }
+    return forward, nil
+}
+
+// isCountryCode reports whether s looks like a two-letter country code.
+func isCountryCode(s string) bool {
+    if len(s) != 2 {
+        return false
+    }
+    for _, c := range s {
+        if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') {
+            return false
+        }
+    }
+    return true
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+    "io/ioutil"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+)
+
+// TestRetornarUsuarioAleatorioQuery checks that the allowed query
+// parameters are forwarded to randomuser.me.
+func TestRetornarUsuarioAleatorioQuery(t *testing.T) {
+    var query string
+    upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        query = r.URL.RawQuery
+        w.Write([]byte(`{"results":[]}`))
+    }))
+    defer upstream.Close()
+    overrideConfig(t).RandomUserBaseURL = upstream.URL
+
+    w := httptest.NewRecorder()
+    newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/retornarUsuarioAleatorio?results=5&gender=female", nil))
+
+    if w.Code != http.StatusOK {
+        t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+    }
+    if want := "gender=female&results=5"; query != want {
+        t.Fatalf("upstream query = %q, want %q", query, want)
+    }
+}
+
+// TestRetornarUsuarioAleatorioBadQuery checks that unknown and malformed
+// query parameters are rejected without calling upstream.
+func TestRetornarUsuarioAleatorioBadQuery(t *testing.T) {
+    overrideConfig(t).RandomUserBaseURL = closedURL(t)
+
+    for _, query := range []string{"results=0", "results=101", "results=many", "gender=other", "nat=BRA", "password=x"} {
+        w := httptest.NewRecorder()
+        newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/retornarUsuarioAleatorio?" + query, nil))
+        if w.Code != http.StatusBadRequest {
+            t.Errorf("?%s status = %d, want %d", query, w.Code, http.StatusBadRequest)
+        }
+    }
+}
+
+// TestRetornarUsuarioAleatorioSeeded repeats a seeded request with the
+// Last-Modified it got, checking for a 304 that does not call upstream,
+// while unseeded requests stay uncacheable.
+func TestRetornarUsuarioAleatorioSeeded(t *testing.T) {
+    var calls int
+    upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        calls++
+        w.Write([]byte(`{"results":[]}`))
+    }))
+    defer upstream.Close()
+    overrideConfig(t).RandomUserBaseURL = upstream.URL
+    router := newRouter()
+
+    w := httptest.NewRecorder()
+    router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/retornarUsuarioAleatorio?seed=foo", nil))
+    lastModified := w.Header().Get("Last-Modified")
+    if w.Code != http.StatusOK || lastModified == "" {
+        t.Fatalf("seeded GET = %d, Last-Modified %q, want 200 with a Last-Modified", w.Code, lastModified)
+    }
+
+    r := httptest.NewRequest(http.MethodGet, "/retornarUsuarioAleatorio?seed=foo", nil)
+    r.Header.Set("If-Modified-Since", lastModified)
+    w = httptest.NewRecorder()
+    router.ServeHTTP(w, r)
+    if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+        t.Fatalf("seeded GET with If-Modified-Since = %d %q, want an empty 304", w.Code, w.Body)
+    }
+    if calls != 1 {
+        t.Errorf("upstream calls = %d, want 1", calls)
+    }
+
+    r = httptest.NewRequest(http.MethodGet, "/retornarUsuarioAleatorio", nil)
+    r.Header.Set("If-Modified-Since", lastModified)
+    w = httptest.NewRecorder()
+    router.ServeHTTP(w, r)
+    if w.Code != http.StatusOK || w.Header().Get("Last-Modified") != "" || w.Header().Get("Cache-Control") != "no-store" {
+        t.Errorf("unseeded GET = %d, Last-Modified %q, Cache-Control %q, want an uncacheable 200", w.Code, w.Header().Get("Last-Modified"), w.Header().Get("Cache-Control"))
+    }
+}
+
+// TestRetornarUsuarioSimples serves a recorded randomuser.me payload,
+// checking the flattened user.
+func TestRetornarUsuarioSimples(t *testing.T) {
+    data, err := ioutil.ReadFile("testdata/randomuser.json")
+    if err != nil {
+        t.Fatal(err)
+    }
+    upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Write(data)
+    }))
+    defer upstream.Close()
+    overrideConfig(t).RandomUserBaseURL = upstream.URL
+
+    w := httptest.NewRecorder()
+    newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/user/simple", nil))
+
+    want := `{"first_name":"Gladys","last_name":"Ribeiro","email":"gladys.ribeiro@example.com","country":"Brazil"}`
+    if w.Code != http.StatusOK || w.Body.String() != want {
+        t.Fatalf("GET /user/simple = %d %s, want 200 %s", w.Code, w.Body, want)
+    }
+}
+
+// TestRetornarUsuarioSimplesUnexpected checks that an upstream payload of
+// the wrong shape is reported as a 502.
+func TestRetornarUsuarioSimplesUnexpected(t *testing.T) {
+    for _, body := range []string{`{"results":[]}`, `<html>`} {
+        upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            w.Write([]byte(body))
+        }))
+        overrideConfig(t).RandomUserBaseURL = upstream.URL
+
+        w := httptest.NewRecorder()
+        newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/user/simple", nil))
+        upstream.Close()
+
+        if w.Code != http.StatusBadGateway {
+            t.Errorf("GET /user/simple with upstream %s = %d, want %d", body, w.Code, http.StatusBadGateway)
+        }
+    }
+}
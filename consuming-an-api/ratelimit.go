@@ -0,0 +1,134 @@
+package main
+
+import (
+    "math"
+    "net"
+    "net/http"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/julienschmidt/httprouter"
+)
+
+// maxBuckets is how many client buckets are kept before idle ones are
+// swept away.
+const maxBuckets = 10000
+
+// rateLimiter is a per-client token bucket limiter. Each client may send
+// burst requests at once and then rate requests per second.
+type rateLimiter struct {
+    rate float64
+    burst float64
+    trustProxy bool
+
+    mu sync.Mutex
+    buckets map[string]*bucket
+}
+
+type bucket struct {
+    tokens float64
+    last time.Time
+}
+
+// newRateLimiter returns a limiter allowing rate requests per second with
+// the given burst. A rate of zero or less disables limiting.
+func newRateLimiter(rate float64, burst int, trustProxy bool) *rateLimiter {
+    return &rateLimiter{
+        rate: rate,
+        burst: float64(burst),
+        trustProxy: trustProxy,
+        buckets: make(map[string]*bucket),
+    }
+}
+
+// allow takes a token from the client's bucket. When the bucket is empty
+// it reports how long the client should wait for the next token.
+func (l *rateLimiter) allow(client string) (bool, time.Duration) {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+
+    now := time.Now()
+    b, ok := l.buckets[client]
+    if !ok {
+        if len(l.buckets) >= maxBuckets {
+            l.sweep(now)
+        }
+        b = &bucket{tokens: l.burst, last: now}
+        l.buckets[client] = b
+    }
+
+    b.tokens = math.Min(l.burst, b.tokens + now.Sub(b.last).Seconds() * l.rate)
+    b.last = now
+    if b.tokens >= 1 {
+        b.tokens--
+        return true, 0
+    }
+    return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+}
+
+// sweep forgets the clients whose buckets have refilled completely, as
+// they are indistinguishable from new clients.
+func (l *rateLimiter) sweep(now time.Time) {
+    for client, b := range l.buckets {
+        if b.tokens + now.Sub(b.last).Seconds() * l.rate >= l.burst {
+            delete(l.buckets, client)
+        }
+    }
+}
+
+// limit rejects requests from clients that exceeded their rate with a
+// 429 and a Retry-After header.
+func (l *rateLimiter) limit(next httprouter.Handle) httprouter.Handle {
+    if l.rate <= 0 {
+        return next
+    }
+    return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+        ok, wait := l.allow(clientIP(r, l.trustProxy))
+        if !ok {
+            seconds := int(math.Ceil(wait.Seconds()))
+            w.Header().Set("Retry-After", strconv.Itoa(seconds))
+            writeErrorCode(w, http.StatusTooManyRequests, "rate_limited", "rate limit exceeded")
+            return
+        }
+        next(w, r, ps)
+    }
+}
+
+// clientIP returns the address of the client that sent r. X-Forwarded-For
+// is only honored when we run behind a trusted proxy, as anyone can set
+// it otherwise.
+func clientIP(r *http.Request, trustProxy bool) string {
+    if trustProxy {
+        if client := forwardedClient(strings.Join(r.Header.Values("X-Forwarded-For"), ",")); client != "" {
+            return client
+        }
+    }
+    host, _, err := net.SplitHostPort(r.RemoteAddr)
+    if err != nil {
+        return r.RemoteAddr
+    }
+    return host
+}
+
+// forwardedClient picks the client out of an X-Forwarded-For chain. Each
+// proxy appends the address it was called from, so only the entries on
+// the right were written by our proxies; the client may have put anything
+// before them. The chain is read from the right, past the private and
+// loopback addresses of our own proxies, up to the first public address.
+func forwardedClient(forwarded string) string {
+    hops := strings.Split(forwarded, ",")
+    client := ""
+    for i := len(hops) - 1; i >= 0; i-- {
+        ip := net.ParseIP(strings.TrimSpace(hops[i]))
+        if ip == nil {
+            break
+        }
+        client = ip.String()
+        if !ip.IsLoopback() && !ip.IsPrivate() {
+            break
+        }
+    }
+    return client
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "testing"
+)
+
+// TestRateLimit hammers a route past the limit, checking that the excess
+// requests get a 429 with a Retry-After header.
+func TestRateLimit(t *testing.T) {
+    c := overrideConfig(t)
+    c.RateLimit = 1
+    c.RateBurst = 3
+
+    router := newRouter()
+    limited := 0
+    for i := 0; i < 10; i++ {
+        w := httptest.NewRecorder()
+        router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/retornarStruct", nil))
+        if w.Code == http.StatusTooManyRequests {
+            limited++
+            if w.Header().Get("Retry-After") == "" {
+                t.Fatal("429 response without a Retry-After header")
+            }
+        }
+    }
+
+    if limited != 7 {
+        t.Fatalf("%d requests were limited, want 7", limited)
+    }
+}
+
+// TestClientIP checks that X-Forwarded-For is only honored behind a
+// trusted proxy, and then only for the entries our proxies appended.
+func TestClientIP(t *testing.T) {
+    r := httptest.NewRequest(http.MethodGet, "/", nil)
+    r.RemoteAddr = "10.0.0.1:1234"
+    r.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.1")
+
+    if got := clientIP(r, false); got != "10.0.0.1" {
+        t.Errorf("clientIP(untrusted) = %q, want 10.0.0.1", got)
+    }
+    if got := clientIP(r, true); got != "203.0.113.7" {
+        t.Errorf("clientIP(trusted) = %q, want 203.0.113.7", got)
+    }
+
+    tests := []struct {
+        forwarded []string
+        want string
+    }{
+        // The client sent a made-up address, which the proxy kept in
+        // front of the one it saw.
+        {[]string{"198.51.100.1, 203.0.113.7"}, "203.0.113.7"},
+        {[]string{"198.51.100.1", "203.0.113.7, 10.0.0.2"}, "203.0.113.7"},
+        {[]string{"not an address, 203.0.113.7"}, "203.0.113.7"},
+        // A client on the private network.
+        {[]string{"192.168.1.5, 10.0.0.2"}, "192.168.1.5"},
+        {[]string{"garbage"}, "10.0.0.1"},
+    }
+    for _, tt := range tests {
+        r.Header["X-Forwarded-For"] = tt.forwarded
+        if got := clientIP(r, true); got != tt.want {
+            t.Errorf("clientIP(X-Forwarded-For: %q) = %q, want %q", tt.forwarded, got, tt.want)
+        }
+    }
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+    "context"
+    "net/http"
+    "strconv"
+    "sync"
+    "time"
+
+    "github.com/julienschmidt/httprouter"
+)
+
+// registeredUpstream is an upstream API we call, along with what its last
+// health check found.
+type registeredUpstream struct {
+    name string
+    baseURL string
+
+    mu sync.Mutex
+    healthy bool
+    lastError string
+    lastCheck time.Time
+}
+
+// upstreamStatus is the JSON view of a registeredUpstream. LastCheck is
+// null until the first check is done.
+type upstreamStatus struct {
+    Name string `json:"name"`
+    BaseURL string `json:"base_url"`
+    Healthy bool `json:"healthy"`
+    LastError string `json:"last_error,omitempty"`
+    LastCheck *time.Time `json:"last_check"`
+}
+
+func (u *registeredUpstream) status() upstreamStatus {
+    u.mu.Lock()
+    defer u.mu.Unlock()
+
+    s := upstreamStatus{Name: u.name, BaseURL: u.baseURL, Healthy: u.healthy, LastError: u.lastError}
+    if !u.lastCheck.IsZero() {
+        checked := u.lastCheck
+        s.LastCheck = &checked
+    }
+    return s
+}
+
+// check sends the upstream a HEAD request and records the outcome.
+func (u *registeredUpstream) check(ctx context.Context) {
+    ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+    defer cancel()
+    err := checkUpstream(ctx, u.baseURL + "/")
+
+    u.mu.Lock()
+    defer u.mu.Unlock()
+    u.lastCheck = time.Now()
+    u.healthy = err == nil
+    u.lastError = ""
+    if err != nil {
+        u.lastError = err.Error()
+    }
+}
+
+// upstreamRegistry holds the upstreams we call, in the order they were
+// registered.
+type upstreamRegistry struct {
+    mu sync.Mutex
+    upstreams []*registeredUpstream
+}
+
+// upstreams is the registry checked in the background by serve and shown
+// at /upstreams.
+var upstreams = &upstreamRegistry{}
+
+// registryFromConfig registers the upstreams of c: randomuser.me,
+// PokéAPI and each PokéAPI mirror.
+func registryFromConfig(c Config) *upstreamRegistry {
+    r := &upstreamRegistry{}
+    r.register("randomuser", c.RandomUserBaseURL)
+    r.register("pokeapi", c.PokeAPIBaseURL)
+    for i, mirror := range c.PokeAPIMirrors {
+        r.register("pokeapi-mirror-" + strconv.Itoa(i + 1), mirror)
+    }
+    return r
+}
+
+// register adds the upstream name served at baseURL.
+func (r *upstreamRegistry) register(name, baseURL string) *registeredUpstream {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    u := &registeredUpstream{name: name, baseURL: baseURL}
+    r.upstreams = append(r.upstreams, u)
+    return u
+}
+
+func (r *upstreamRegistry) all() []*registeredUpstream {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    return append([]*registeredUpstream(nil), r.upstreams...)
+}
+
+// down reports whether the upstream failed its last health check; one not
+// checked yet is not down.
+func (u *registeredUpstream) down() bool {
+    u.mu.Lock()
+    defer u.mu.Unlock()
+
+    return !u.lastCheck.IsZero() && !u.healthy
+}
+
+// byHealth reorders baseURLs so that those of upstreams that failed their
+// last health check come last, keeping the order within each group. They
+// stay in the list since the check may be out of date.
+func (r *upstreamRegistry) byHealth(baseURLs []string) []string {
+    down := make(map[string]bool)
+    for _, u := range r.all() {
+        if u.down() {
+            down[u.baseURL] = true
+        }
+    }
+    ordered := make([]string, 0, len(baseURLs))
+    var last []string
+    for _, base := range baseURLs {
+        if down[base] {
+            last = append(last, base)
+        } else {
+            ordered = append(ordered, base)
+        }
+    }
+    return append(ordered, last...)
+}
+
+// checkAll checks every upstream concurrently.
+func (r *upstreamRegistry) checkAll(ctx context.Context) {
+    var wg sync.WaitGroup
+    for _, u := range r.all() {
+        wg.Add(1)
+        go func(u *registeredUpstream) {
+            defer wg.Done()
+            u.check(ctx)
+        }(u)
+    }
+    wg.Wait()
+}
+
+// run checks every upstream straight away and then every interval, until
+// ctx is done.
+func (r *upstreamRegistry) run(ctx context.Context, interval time.Duration) {
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+    for {
+        r.checkAll(ctx)
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+// retornarUpstreams lists the upstreams with the outcome of their last
+// health check.
+func retornarUpstreams(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+    all := upstreams.all()
+    statuses := make([]upstreamStatus, 0, len(all))
+    for _, u := range all {
+        statuses = append(statuses, u.status())
+    }
+    writeJsonFor(w, r, http.StatusOK, statuses)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "reflect"
+    "sync/atomic"
+    "testing"
+    "time"
+)
+
+// useRegistry makes r the registry until the test ends.
+func useRegistry(t *testing.T, r *upstreamRegistry) {
+    saved := upstreams
+    upstreams = r
+    t.Cleanup(func() { upstreams = saved })
+}
+
+// getUpstreams requests /upstreams.
+func getUpstreams(t *testing.T) []upstreamStatus {
+    w := httptest.NewRecorder()
+    newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/upstreams", nil))
+    var statuses []upstreamStatus
+    if err := json.Unmarshal(w.Body.Bytes(), &statuses); err != nil {
+        t.Fatalf("GET /upstreams = %d %s: %v", w.Code, w.Body, err)
+    }
+    return statuses
+}
+
+// waitForHealth polls /upstreams until the only upstream has been
+// checked with the wanted outcome.
+func waitForHealth(t *testing.T, healthy bool) upstreamStatus {
+    deadline := time.Now().Add(2 * time.Second)
+    for {
+        statuses := getUpstreams(t)
+        if len(statuses) == 1 && statuses[0].LastCheck != nil && statuses[0].Healthy == healthy {
+            return statuses[0]
+        }
+        if time.Now().After(deadline) {
+            t.Fatalf("/upstreams = %+v, want the upstream healthy=%v", statuses, healthy)
+        }
+        time.Sleep(5 * time.Millisecond)
+    }
+}
+
+// TestUpstreamRegistry runs the background checker against an upstream
+// that goes down and comes back, checking that /upstreams follows it.
+func TestUpstreamRegistry(t *testing.T) {
+    fastRetries(t)
+    var down int32
+    mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if atomic.LoadInt32(&down) == 1 {
+            w.WriteHeader(http.StatusInternalServerError)
+        }
+    }))
+    defer mock.Close()
+
+    registry := &upstreamRegistry{}
+    registry.register("mock", mock.URL)
+    useRegistry(t, registry)
+    if statuses := getUpstreams(t); len(statuses) != 1 || statuses[0].LastCheck != nil {
+        t.Fatalf("/upstreams before any check = %+v, want mock unchecked", statuses)
+    }
+
+    ctx, cancel := context.WithCancel(context.Background())
+    stopped := make(chan struct{})
+    go func() {
+        registry.run(ctx, 10 * time.Millisecond)
+        close(stopped)
+    }()
+
+    if s := waitForHealth(t, true); s.Name != "mock" || s.BaseURL != mock.URL || s.LastError != "" {
+        t.Errorf("healthy status = %+v", s)
+    }
+    atomic.StoreInt32(&down, 1)
+    if s := waitForHealth(t, false); s.LastError == "" {
+        t.Errorf("unhealthy status = %+v, want the last error", s)
+    }
+    atomic.StoreInt32(&down, 0)
+    waitForHealth(t, true)
+
+    cancel()
+    select {
+    case <-stopped:
+    case <-time.After(time.Second):
+        t.Fatal("checker still running after its context was cancelled")
+    }
+}
+
+func TestRegistryFromConfig(t *testing.T) {
+    c := defaultConfig
+    c.PokeAPIMirrors = []string{"http://mirror.example"}
+
+    var names []string
+    for _, u := range registryFromConfig(c).all() {
+        names = append(names, u.name)
+    }
+    if want := []string{"randomuser", "pokeapi", "pokeapi-mirror-1"}; !reflect.DeepEqual(names, want) {
+        t.Errorf("names = %v, want %v", names, want)
+    }
+}
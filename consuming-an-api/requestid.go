@@ -0,0 +1,70 @@
+package main
+
+import (
+    "context"
+    "crypto/rand"
+    "fmt"
+    "net/http"
+
+    "github.com/julienschmidt/httprouter"
+)
+
+// contextKey is the type of the keys this package stores in contexts.
+type contextKey int
+
+const (
+    requestIDKey contextKey = iota
+    acceptLanguageKey
+    shutdownKey
+)
+
+// maxRequestIDLength bounds the incoming request IDs we accept, as they
+// end up in our logs.
+const maxRequestIDLength = 128
+
+// withRequestID tags every request with an ID, taken from its
+// X-Request-ID header or generated when missing, so that it can be
+// followed through our logs and the upstream calls it causes. The ID is
+// echoed back in the response's X-Request-ID header.
+func withRequestID(next httprouter.Handle) httprouter.Handle {
+    return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+        id := r.Header.Get("X-Request-ID")
+        if !validRequestID(id) {
+            id = newUUID()
+        }
+        w.Header().Set("X-Request-ID", id)
+        next(w, r.WithContext(context.WithValue(r.Context(), requestIDKey, id)), ps)
+    }
+}
+
+// requestIDFromContext returns the ID of the request ctx belongs to, or
+// "" outside of a request.
+func requestIDFromContext(ctx context.Context) string {
+    id, _ := ctx.Value(requestIDKey).(string)
+    return id
+}
+
+// validRequestID reports whether a client supplied ID is short and made
+// of printable ASCII only.
+func validRequestID(id string) bool {
+    if id == "" || len(id) > maxRequestIDLength {
+        return false
+    }
+    for i := 0; i < len(id); i++ {
+        if id[i] <= ' ' || id[i] > '~' {
+            return false
+        }
+    }
+    return true
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() string {
+    var b [16]byte
+    if _, err := rand.Read(b[:]); err != nil {
+        panic(err)
+    }
+    b[6] = b[6] & 0x0f | 0x40
+    b[8] = b[8] & 0x3f | 0x80
+    return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
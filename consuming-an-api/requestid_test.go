@@ -0,0 +1,56 @@
+package main
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "regexp"
+    "strings"
+    "testing"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+// TestRequestIDEchoed sends an X-Request-ID, checking it is echoed back,
+// logged and forwarded upstream.
+func TestRequestIDEchoed(t *testing.T) {
+    var forwarded string
+    upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        forwarded = r.Header.Get("X-Request-ID")
+        w.Write([]byte(`{}`))
+    }))
+    defer upstream.Close()
+    overrideConfig(t).RandomUserBaseURL = upstream.URL
+    buf := captureLog(t)
+
+    w := httptest.NewRecorder()
+    r := httptest.NewRequest(http.MethodGet, "/retornarUsuarioAleatorio", nil)
+    r.Header.Set("X-Request-ID", "abc-123")
+    newRouter().ServeHTTP(w, r)
+
+    if got := w.Header().Get("X-Request-ID"); got != "abc-123" {
+        t.Errorf("response X-Request-ID = %q, want abc-123", got)
+    }
+    if forwarded != "abc-123" {
+        t.Errorf("upstream X-Request-ID = %q, want abc-123", forwarded)
+    }
+    if !strings.Contains(buf.String(), "request_id=abc-123") {
+        t.Errorf("log output %q does not contain request_id=abc-123", buf.String())
+    }
+}
+
+// TestRequestIDGenerated checks that a request without an ID, or with an
+// unacceptable one, gets a fresh UUID.
+func TestRequestIDGenerated(t *testing.T) {
+    for _, id := range []string{"", "has spaces", strings.Repeat("x", maxRequestIDLength + 1)} {
+        w := httptest.NewRecorder()
+        r := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+        if id != "" {
+            r.Header.Set("X-Request-ID", id)
+        }
+        newRouter().ServeHTTP(w, r)
+
+        if got := w.Header().Get("X-Request-ID"); !uuidPattern.MatchString(got) {
+            t.Errorf("X-Request-ID for incoming %q = %q, want a generated UUID", id, got)
+        }
+    }
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+    "math/rand"
+    "net"
+    "net/http"
+    "strconv"
+    "time"
+)
+
+// upstreamRetries is how many times a failed upstream GET is retried
+// before giving up, set from config.UpstreamRetries.
+var upstreamRetries = defaultConfig.UpstreamRetries
+
+// retryBaseDelay is the wait before the first retry; it doubles on every
+// following attempt.
+var retryBaseDelay = 100 * time.Millisecond
+
+// maxRetryAfter is the longest Retry-After we wait out before retrying a
+// 429; beyond it the 429 is passed on instead.
+var maxRetryAfter = 10 * time.Second
+
+// upstreamRateLimitedError is returned when an upstream keeps answering
+// 429. retryAfter is its Retry-After header, passed on to our client.
+type upstreamRateLimitedError struct {
+    retryAfter string
+}
+
+func (e *upstreamRateLimitedError) Error() string {
+    return "upstream rate limit exceeded"
+}
+
+// doWithRetry sends the idempotent request through upstreamClient,
+// retrying with exponential backoff and jitter on connection errors, 429s
+// and 5xx responses. A 429 is retried after its Retry-After instead, when
+// it has one. The last response or error is returned once the retries run
+// out. Other client errors (4xx) and timeouts are never retried.
+func doWithRetry(request *http.Request) (*http.Response, error) {
+    delay := retryBaseDelay
+    for attempt := 0; ; attempt++ {
+        start := time.Now()
+        response, err := upstreamClient.Do(request)
+        appMetrics.recordUpstream(request.URL.Host, time.Since(start))
+        if attempt == upstreamRetries || !shouldRetry(response, err) {
+            return response, err
+        }
+
+        // Sleep between delay/2 and delay*3/2 so that clients failing at
+        // the same time do not retry in lockstep.
+        wait := delay/2 + time.Duration(rand.Int63n(int64(delay) + 1))
+        if response != nil && response.StatusCode == http.StatusTooManyRequests {
+            if after, ok := parseRetryAfter(response.Header.Get("Retry-After")); ok {
+                if after > maxRetryAfter {
+                    return response, nil
+                }
+                wait = after
+            }
+        }
+        if response != nil {
+            response.Body.Close()
+        }
+
+        select {
+        case <-time.After(wait):
+        case <-request.Context().Done():
+            return nil, request.Context().Err()
+        }
+        delay *= 2
+    }
+}
+
+// shouldRetry reports whether the outcome of an upstream call is worth
+// another attempt.
+func shouldRetry(response *http.Response, err error) bool {
+    if err != nil {
+        netErr, ok := err.(net.Error)
+        return !(ok && netErr.Timeout())
+    }
+    return response.StatusCode >= 500 || response.StatusCode == http.StatusTooManyRequests
+}
+
+// parseRetryAfter reads a Retry-After header, given either in seconds or
+// as an HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+    if value == "" {
+        return 0, false
+    }
+    if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+        return time.Duration(seconds) * time.Second, true
+    }
+    if date, err := http.ParseTime(value); err == nil {
+        if wait := time.Until(date); wait > 0 {
+            return wait, true
+        }
+        return 0, true
+    }
+    return 0, false
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "sync/atomic"
+    "testing"
+    "time"
+)
+
+// fastRetries shrinks the backoff delay until the test ends.
+func fastRetries(t *testing.T) {
+    delay := retryBaseDelay
+    retryBaseDelay = time.Millisecond
+    t.Cleanup(func() { retryBaseDelay = delay })
+}
+
+// TestReturnJsonRetries points returnJson at an upstream that fails twice
+// before succeeding, checking the final response is served after exactly
+// three attempts.
+func TestReturnJsonRetries(t *testing.T) {
+    fastRetries(t)
+    var attempts int64
+    upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if atomic.AddInt64(&attempts, 1) <= 2 {
+            w.WriteHeader(http.StatusServiceUnavailable)
+            return
+        }
+        w.Write([]byte(`{"ok":true}`))
+    }))
+    defer upstream.Close()
+
+    w := httptest.NewRecorder()
+    returnJson(upstream.URL, w, httptest.NewRequest(http.MethodGet, "/retornarUsuarioAleatorio", nil))
+
+    if w.Code != http.StatusOK || w.Body.String() != `{"ok":true}` {
+        t.Fatalf("returnJson = %d %q, want 200 {\"ok\":true}", w.Code, w.Body.String())
+    }
+    if attempts != 3 {
+        t.Fatalf("upstream attempts = %d, want 3", attempts)
+    }
+}
+
+// TestReturnJsonNoRetryOn4xx checks that client errors are not retried.
+func TestReturnJsonNoRetryOn4xx(t *testing.T) {
+    fastRetries(t)
+    var attempts int64
+    upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        atomic.AddInt64(&attempts, 1)
+        w.WriteHeader(http.StatusBadRequest)
+    }))
+    defer upstream.Close()
+
+    returnJson(upstream.URL, httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+    if attempts != 1 {
+        t.Fatalf("upstream attempts = %d, want 1", attempts)
+    }
+}
+
+// TestReturnJsonRetriesExhausted checks that an upstream that keeps
+// failing is reported as a 502 once the retries run out.
+func TestReturnJsonRetriesExhausted(t *testing.T) {
+    fastRetries(t)
+    var attempts int64
+    upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        atomic.AddInt64(&attempts, 1)
+        w.WriteHeader(http.StatusInternalServerError)
+    }))
+    defer upstream.Close()
+
+    w := httptest.NewRecorder()
+    returnJson(upstream.URL, w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+    if w.Code != http.StatusBadGateway {
+        t.Fatalf("returnJson status = %d, want %d", w.Code, http.StatusBadGateway)
+    }
+    if want := int64(upstreamRetries + 1); attempts != want {
+        t.Fatalf("upstream attempts = %d, want %d", attempts, want)
+    }
+}
+
+// TestReturnJsonRetryAfter answers 429 with Retry-After: 1 before
+// succeeding, checking that the retry waited for it.
+func TestReturnJsonRetryAfter(t *testing.T) {
+    fastRetries(t)
+    var attempts int64
+    upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if atomic.AddInt64(&attempts, 1) == 1 {
+            w.Header().Set("Retry-After", "1")
+            w.WriteHeader(http.StatusTooManyRequests)
+            return
+        }
+        w.Write([]byte(`{"ok":true}`))
+    }))
+    defer upstream.Close()
+
+    start := time.Now()
+    w := httptest.NewRecorder()
+    returnJson(upstream.URL, w, httptest.NewRequest(http.MethodGet, "/", nil))
+    elapsed := time.Since(start)
+
+    if w.Code != http.StatusOK || attempts != 2 {
+        t.Fatalf("returnJson = %d after %d attempts, want 200 after 2", w.Code, attempts)
+    }
+    if elapsed < 900 * time.Millisecond || elapsed > 3 * time.Second {
+        t.Errorf("returnJson took %s, want about the 1s of Retry-After", elapsed)
+    }
+}
+
+// TestReturnJsonRateLimitedExhausted checks that an upstream that keeps
+// answering 429 is passed on as a 429 with its Retry-After.
+func TestReturnJsonRateLimitedExhausted(t *testing.T) {
+    fastRetries(t)
+    retries := upstreamRetries
+    upstreamRetries = 1
+    defer func() { upstreamRetries = retries }()
+    upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("Retry-After", "0")
+        w.WriteHeader(http.StatusTooManyRequests)
+    }))
+    defer upstream.Close()
+
+    w := httptest.NewRecorder()
+    returnJson(upstream.URL, w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+    if w.Code != http.StatusTooManyRequests {
+        t.Fatalf("returnJson status = %d, want %d", w.Code, http.StatusTooManyRequests)
+    }
+    if got := w.Header().Get("Retry-After"); got != "0" {
+        t.Errorf("Retry-After = %q, want the upstream's 0", got)
+    }
+}
+
+func TestParseRetryAfter(t *testing.T) {
+    tests := []struct {
+        value string
+        want time.Duration
+        ok bool
+    }{
+        {"", 0, false},
+        {"3", 3 * time.Second, true},
+        {"soon", 0, false},
+        {"Mon, 02 Jan 2006 15:04:05 GMT", 0, true},
+    }
+    for _, tt := range tests {
+        if got, ok := parseRetryAfter(tt.value); got != tt.want || ok != tt.ok {
+            t.Errorf("parseRetryAfter(%q) = %s, %v, want %s, %v", tt.value, got, ok, tt.want, tt.ok)
+        }
+    }
+}
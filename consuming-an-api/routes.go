@@ -0,0 +1,96 @@
+package main
+
+import (
+    "bytes"
+    _ "embed"
+    "html/template"
+    "net/http"
+
+    "github.com/julienschmidt/httprouter"
+)
+
+// route is one endpoint of the API. The table returned by routes is used
+// both to register the endpoints and to document them at /docs, so that
+// the docs cannot drift from what is served.
+type route struct {
+    Method string
+    Path string
+    Description string
+    // Auth puts the route behind an API key.
+    Auth bool
+    // Bare leaves out CORS, compression and the rate, size and time
+    // limits, for health checks that come from the load balancer.
+    Bare bool
+    // Stream leaves out compression and the size and time limits, which
+    // buffer or cut short responses that go on for long.
+    Stream bool
+    // Idempotent lets clients retry the route safely by sending an
+    // Idempotency-Key.
+    Idempotent bool
+    // Feature is the feature flag that switches the route off, if any.
+    Feature string
+
+    handle httprouter.Handle
+}
+
+// routes lists every endpoint in the order they are documented.
+func routes() []route {
+    return []route{
+        {Method: http.MethodGet, Path: "/retornarUsuarioAleatorio", Description: "A random user from randomuser.me, optionally filtered with results, gender, nat and seed.", handle: retornarUsuarioAleatorio},
+        {Method: http.MethodGet, Path: "/user/simple", Description: "The name, email and country of a random user.", handle: retornarUsuarioSimples},
+        {Method: http.MethodGet, Path: "/ws/users", Description: "A WebSocket pushing a random user, trimmed as in /user/simple, every few seconds.", Stream: true, Feature: "ws", handle: transmitirUsuarios},
+        {Method: http.MethodGet, Path: "/retornarStruct", Description: "A sample Message, its fields overridable with body, number, decimal and validate.", handle: retornarStruct},
+        {Method: http.MethodPost, Path: "/message", Description: "Echoes a posted Message, validating it when Validate is set.", handle: criarMensagem},
+        {Method: http.MethodGet, Path: "/retornarPokemon/:nome", Description: "A Pokemon from PokéAPI, trimmed to its main fields or to those listed in fields.", handle: retornarPokemon},
+        {Method: http.MethodGet, Path: "/pokeapi/*path", Description: "Passes the request through to PokéAPI unchanged.", handle: proxyTo(config.PokeAPIBaseURL)},
+        {Method: http.MethodGet, Path: "/pokemon/:nome/sprite", Description: "The front sprite image of a Pokemon.", handle: retornarSprite},
+        {Method: http.MethodGet, Path: "/pokemon/:nome/moves", Description: "The names of the moves a Pokemon can learn, capped with limit.", handle: retornarMovimentos},
+        {Method: http.MethodGet, Path: "/pokemon/:nome/stats.csv", Description: "The base stats of a Pokemon as a CSV download.", handle: retornarStatsCSV},
+        {Method: http.MethodGet, Path: "/pokemon/:nome/flavor", Description: "The Pokédex text of a Pokemon in lang, falling back to English.", Feature: "flavor", handle: retornarFlavor},
+        {Method: http.MethodGet, Path: "/pokemon/:nome/card.svg", Description: "A Pokemon as an SVG trading card.", Feature: "card", handle: retornarCarta},
+        {Method: http.MethodGet, Path: "/pokemons/batch", Description: "Several PokéAPI Pokemon at once, named in names.", Feature: "batch", handle: retornarPokemonsEmLote},
+        {Method: http.MethodGet, Path: "/pokemons/stream", Description: "Several PokéAPI Pokemon named in names, streamed as NDJSON lines in the order they arrive.", Stream: true, Feature: "stream", handle: transmitirPokemons},
+        {Method: http.MethodGet, Path: "/pokemons/by-type/:type", Description: "The names of the Pokemon of a type, capped with limit.", handle: retornarPokemonsPorTipo},
+        {Method: http.MethodGet, Path: "/type/:name/effectiveness", Description: "The types a type deals double, half and no damage to.", handle: retornarEfetividade},
+        {Method: http.MethodGet, Path: "/pokemons/compare", Description: "Compares the base stats of the Pokemon a and b.", handle: compararPokemons},
+        {Method: http.MethodPost, Path: "/pokemons/team", Description: "Checks a team of up to 6 Pokemon, posted as an array of names, and sums up its types and base stats.", Feature: "team", handle: criarTime},
+        {Method: http.MethodPost, Path: "/pokemons/snapshot", Description: "Takes a snapshot of the current data of the Pokemon in a posted array of names.", Feature: "snapshot", handle: criarSnapshot},
+        {Method: http.MethodGet, Path: "/pokemons/diff", Description: "The fields of the Pokemon name that changed on PokéAPI since the snapshot snapshot.", Feature: "snapshot", handle: retornarDiff},
+        {Method: http.MethodGet, Path: "/pokemons/autocomplete", Description: "The names of the Pokemon starting with q.", handle: retornarAutocompletar},
+        {Method: http.MethodGet, Path: "/pokemons/random", Description: "A random Pokemon.", handle: retornarPokemonAleatorio},
+        {Method: http.MethodGet, Path: "/pokemons/evolution/:nome", Description: "The species in the evolution chain of a Pokemon.", handle: retornarEvolucao},
+        {Method: http.MethodGet, Path: "/pokemons", Description: "Every stored Pokemon.", handle: listarPokemons},
+        {Method: http.MethodPost, Path: "/criarPokemon", Description: "Stores a Pokemon, once per Idempotency-Key.", Auth: true, Idempotent: true, handle: criarPokemon},
+        {Method: http.MethodPost, Path: "/pokemons/bulk", Description: "Stores several Pokemon, reporting on each.", Auth: true, handle: criarPokemonsEmLote},
+        {Method: http.MethodPost, Path: "/pokemons/import", Description: "Stores the Pokemon of a name,level CSV uploaded as the file field of a form, reporting the rows skipped.", Auth: true, handle: importarPokemons},
+        {Method: http.MethodPut, Path: "/pokemon/:nome", Description: "Changes the level of a stored Pokemon.", Auth: true, handle: atualizarPokemon},
+        {Method: http.MethodDelete, Path: "/pokemon/:nome", Description: "Deletes a stored Pokemon.", Auth: true, handle: deletarPokemon},
+        {Method: http.MethodGet, Path: "/cache/stats", Description: "Hits and misses of the PokéAPI cache.", handle: retornarCacheStats},
+        {Method: http.MethodGet, Path: "/debug/cache", Description: "The keys in the PokéAPI cache, when they were stored and how long they have left.", Auth: true, handle: retornarCacheDebug},
+        {Method: http.MethodGet, Path: "/debug/features", Description: "Whether each feature flag is on.", Auth: true, handle: retornarFeatures},
+        {Method: http.MethodGet, Path: "/metrics", Description: "Request and upstream metrics, as JSON or in the Prometheus format.", handle: retornarMetricas},
+        {Method: http.MethodGet, Path: "/version", Description: "The version, commit and build time of the running build.", handle: retornarVersao},
+        {Method: http.MethodGet, Path: "/docs", Description: "This page.", handle: retornarDocs},
+        {Method: http.MethodGet, Path: "/openapi.json", Description: "The routes as an OpenAPI 3.0 document.", handle: retornarOpenAPI},
+        {Method: http.MethodGet, Path: "/upstreams", Description: "The upstream APIs and the outcome of their last health check.", handle: retornarUpstreams},
+        {Method: http.MethodGet, Path: "/healthz", Description: "Whether the process is alive.", Bare: true, handle: healthz},
+        {Method: http.MethodGet, Path: "/readyz", Description: "Whether PokéAPI can be reached.", Bare: true, handle: readyz},
+    }
+}
+
+//go:embed docs.html
+var docsHTML string
+
+var docsTemplate = template.Must(template.New("docs").Parse(docsHTML))
+
+// retornarDocs renders the route table as an HTML page.
+func retornarDocs(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+    var buf bytes.Buffer
+    if err := docsTemplate.Execute(&buf, routes()); err != nil {
+        logErrorf("rendering docs: %v", err)
+        writeError(w, http.StatusInternalServerError, "could not render docs")
+        return
+    }
+    w.Header().Set("Content-Type", "text/html; charset=utf-8")
+    w.Write(buf.Bytes())
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+)
+
+// TestRetornarDocs checks that /docs is an HTML page listing the routes.
+func TestRetornarDocs(t *testing.T) {
+    w := httptest.NewRecorder()
+    newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs", nil))
+
+    if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+        t.Fatalf("GET /docs = %d %s, want 200 text/html", w.Code, w.Header().Get("Content-Type"))
+    }
+    for _, want := range []string{"<code>/retornarPokemon/:nome</code>", "A Pokemon from PokéAPI", "<code>/docs</code>"} {
+        if !strings.Contains(w.Body.String(), want) {
+            t.Errorf("GET /docs does not contain %q", want)
+        }
+    }
+}
+
+// TestRoutesRegistered checks that every documented route is served.
+func TestRoutesRegistered(t *testing.T) {
+    router := newRouter()
+    for _, rt := range routes() {
+        if h, _, _ := router.Lookup(rt.Method, strings.Replace(strings.Replace(rt.Path, ":", "x", -1), "*", "", -1)); h == nil {
+            t.Errorf("%s %s is documented but not registered", rt.Method, rt.Path)
+        }
+    }
+}
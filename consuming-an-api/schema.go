@@ -0,0 +1,224 @@
+package main
+
+import (
+    "bytes"
+    "embed"
+    "encoding/json"
+    "io"
+    "strconv"
+    "strings"
+)
+
+// jsonSchema is the subset of JSON Schema our request bodies are checked
+// against: type, properties, required, additionalProperties, minimum,
+// maximum, minLength and maxLength.
+type jsonSchema struct {
+    Type string `json:"type"`
+    Properties map[string]*jsonSchema `json:"properties"`
+    Required []string `json:"required"`
+    AdditionalProperties *bool `json:"additionalProperties"`
+    Minimum *float64 `json:"minimum"`
+    Maximum *float64 `json:"maximum"`
+    MinLength *int `json:"minLength"`
+    MaxLength *int `json:"maxLength"`
+}
+
+//go:embed schemas/*.json
+var schemaFiles embed.FS
+
+// Schemas of the bodies of POST /criarPokemon and POST /message.
+var (
+    pokemonSchema = mustLoadSchema("pokemon.json")
+    messageSchema = mustLoadSchema("message.json")
+)
+
+// mustLoadSchema parses schemas/name, panicking if it is broken since the
+// schemas ship with the binary.
+func mustLoadSchema(name string) *jsonSchema {
+    data, err := schemaFiles.ReadFile("schemas/" + name)
+    if err != nil {
+        panic(err)
+    }
+    var s jsonSchema
+    if err := json.Unmarshal(data, &s); err != nil {
+        panic("schema " + name + ": " + err.Error())
+    }
+    return &s
+}
+
+// schemaRoot is the path reported for violations of the body as a whole.
+const schemaRoot = "(root)"
+
+// validate checks v, decoded with UseNumber, against s and maps the path
+// of each violation to what is wrong there. Nested paths are joined with
+// dots.
+func (s *jsonSchema) validate(v interface{}) map[string]string {
+    failed := make(map[string]string)
+    s.check("", v, failed)
+    return failed
+}
+
+func (s *jsonSchema) check(path string, v interface{}, failed map[string]string) {
+    at := path
+    if at == "" {
+        at = schemaRoot
+    }
+    if s.Type != "" && !hasSchemaType(v, s.Type) {
+        article := "a "
+        if strings.IndexAny(s.Type[:1], "aeiou") == 0 {
+            article = "an "
+        }
+        failed[at] = "must be " + article + s.Type
+        return
+    }
+
+    switch v := v.(type) {
+    case map[string]interface{}:
+        for _, name := range s.Required {
+            if _, ok := matchKey(v, name); !ok {
+                failed[joinPath(path, name)] = "is required"
+            }
+        }
+        for key, value := range v {
+            if name, property := s.property(key); property != nil {
+                property.check(joinPath(path, name), value, failed)
+            } else if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+                failed[joinPath(path, key)] = "is not allowed"
+            }
+        }
+    case json.Number:
+        n, _ := v.Float64()
+        if s.Minimum != nil && n < *s.Minimum {
+            failed[at] = "must be at least " + strconv.FormatFloat(*s.Minimum, 'f', -1, 64)
+        }
+        if s.Maximum != nil && n > *s.Maximum {
+            failed[at] = "must be at most " + strconv.FormatFloat(*s.Maximum, 'f', -1, 64)
+        }
+    case string:
+        n := len([]rune(v))
+        if s.MinLength != nil && n < *s.MinLength {
+            failed[at] = "must be at least " + strconv.Itoa(*s.MinLength) + " characters"
+        }
+        if s.MaxLength != nil && n > *s.MaxLength {
+            failed[at] = "must be at most " + strconv.Itoa(*s.MaxLength) + " characters"
+        }
+    }
+}
+
+// property returns the property of s that the object key stands for,
+// matched the way encoding/json matches keys to struct fields: exactly,
+// and otherwise ignoring case.
+func (s *jsonSchema) property(key string) (string, *jsonSchema) {
+    if property, ok := s.Properties[key]; ok {
+        return key, property
+    }
+    for name, property := range s.Properties {
+        if strings.EqualFold(name, key) {
+            return name, property
+        }
+    }
+    return "", nil
+}
+
+// matchKey returns the key of v that name stands for, matched like
+// property does.
+func matchKey(v map[string]interface{}, name string) (string, bool) {
+    if _, ok := v[name]; ok {
+        return name, true
+    }
+    for key := range v {
+        if strings.EqualFold(key, name) {
+            return key, true
+        }
+    }
+    return "", false
+}
+
+// hasSchemaType reports whether v is of the JSON Schema type t.
+func hasSchemaType(v interface{}, t string) bool {
+    switch v := v.(type) {
+    case map[string]interface{}:
+        return t == "object"
+    case []interface{}:
+        return t == "array"
+    case string:
+        return t == "string"
+    case bool:
+        return t == "boolean"
+    case nil:
+        return t == "null"
+    case json.Number:
+        if t == "number" {
+            return true
+        }
+        _, err := v.Int64()
+        return t == "integer" && err == nil
+    }
+    return false
+}
+
+func joinPath(path, name string) string {
+    if path == "" {
+        return name
+    }
+    return path + "." + name
+}
+
+// decodeValidated decodes the JSON body r into v, refusing fields v does
+// not have, and checks it against s. Violations are returned by path; an
+// error means the body could not be read or is not JSON at all, and is
+// meant for writeDecodeError.
+func decodeValidated(r io.Reader, s *jsonSchema, v interface{}) (map[string]string, error) {
+    data, err := io.ReadAll(r)
+    if err != nil {
+        return nil, err
+    }
+
+    failed := make(map[string]string)
+    strict := json.NewDecoder(bytes.NewReader(data))
+    strict.DisallowUnknownFields()
+    if err := strict.Decode(v); err != nil {
+        switch err := err.(type) {
+        case *json.UnmarshalTypeError:
+            // Reported below by the schema, unless it has no rule for
+            // the field.
+            field := err.Field
+            if field == "" {
+                field = schemaRoot
+            }
+            failed[field] = "must be " + err.Type.String()
+        default:
+            name := strings.TrimPrefix(err.Error(), "json: unknown field ")
+            if name == err.Error() {
+                return nil, err
+            }
+            failed[strings.Trim(name, `"`)] = "is not allowed"
+        }
+    }
+
+    var generic interface{}
+    decoder := json.NewDecoder(bytes.NewReader(data))
+    decoder.UseNumber()
+    if err := decoder.Decode(&generic); err != nil {
+        return nil, err
+    }
+    violations := s.validate(generic)
+    for path, msg := range failed {
+        if !hasViolation(violations, path) {
+            violations[path] = msg
+        }
+    }
+    return violations, nil
+}
+
+// hasViolation reports whether violations has path, ignoring case since
+// the decoder names fields as they were sent while the schema uses its
+// own names for them.
+func hasViolation(violations map[string]string, path string) bool {
+    for at := range violations {
+        if strings.EqualFold(at, path) {
+            return true
+        }
+    }
+    return false
+}
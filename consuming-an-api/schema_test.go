@@ -0,0 +1,69 @@
+package main
+
+import (
+    "encoding/json"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+func TestDecodeValidated(t *testing.T) {
+    tests := []struct {
+        body string
+        want map[string]string
+    }{
+        {`{"name":"pikachu","level":12}`, map[string]string{}},
+        {`{"name":"pikachu","shiny":true}`, map[string]string{"shiny": "is not allowed"}},
+        {`{"name":"pikachu","level":"12"}`, map[string]string{"level": "must be an integer"}},
+        {`{"name":"pikachu","level":300}`, map[string]string{"level": "must be at most 127"}},
+        {`{"name":"` + strings.Repeat("a", 65) + `"}`, map[string]string{"name": "must be at most 64 characters"}},
+        // Keys match in any case, as they do for encoding/json, and
+        // violations are reported under the schema's name.
+        {`{"Name":"pikachu","Level":5}`, map[string]string{}},
+        {`{"NAME":"pikachu","lEvEl":300}`, map[string]string{"level": "must be at most 127"}},
+        {`{"Name":"pikachu","Level":"5"}`, map[string]string{"level": "must be an integer"}},
+        {`{"Name":"pikachu","Shiny":true}`, map[string]string{"Shiny": "is not allowed"}},
+    }
+    for _, tt := range tests {
+        var p Pokemon
+        got, err := decodeValidated(strings.NewReader(tt.body), pokemonSchema, &p)
+        if err != nil {
+            t.Fatalf("decodeValidated(%s) error = %v", tt.body, err)
+        }
+        if !reflect.DeepEqual(got, tt.want) {
+            t.Errorf("decodeValidated(%s) = %v, want %v", tt.body, got, tt.want)
+        }
+    }
+}
+
+func TestDecodeValidatedSyntax(t *testing.T) {
+    var p Pokemon
+    if _, err := decodeValidated(strings.NewReader(`{"name":`), pokemonSchema, &p); err == nil {
+        t.Error("decodeValidated of truncated JSON succeeded, want an error")
+    }
+}
+
+// TestSchemaNested checks required properties and the dotted paths of
+// nested violations.
+func TestSchemaNested(t *testing.T) {
+    min := 1.0
+    s := &jsonSchema{
+        Type: "object",
+        Required: []string{"a"},
+        Properties: map[string]*jsonSchema{
+            "a": {Type: "object", Properties: map[string]*jsonSchema{"b": {Type: "number", Minimum: &min}}},
+        },
+    }
+    if got := s.validate(map[string]interface{}{}); !reflect.DeepEqual(got, map[string]string{"a": "is required"}) {
+        t.Errorf("validate({}) = %v, want a is required", got)
+    }
+    var v interface{}
+    decoder := json.NewDecoder(strings.NewReader(`{"a":{"b":0}}`))
+    decoder.UseNumber()
+    if err := decoder.Decode(&v); err != nil {
+        t.Fatal(err)
+    }
+    if got := s.validate(v); !reflect.DeepEqual(got, map[string]string{"a.b": "must be at least 1"}) {
+        t.Errorf("validate = %v, want a.b must be at least 1", got)
+    }
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+    "context"
+    "io"
+    "net/http"
+    "sync"
+    "time"
+)
+
+// semaphore is a counting semaphore; a nil one never blocks.
+type semaphore chan struct{}
+
+// newSemaphore returns a semaphore with n slots, or nil when n is not
+// positive.
+func newSemaphore(n int) semaphore {
+    if n <= 0 {
+        return nil
+    }
+    return make(semaphore, n)
+}
+
+// upstreamSlots bounds how many upstream calls the whole process makes at
+// the same time, so that a burst of batch requests cannot flood PokéAPI.
+var upstreamSlots = newSemaphore(defaultConfig.MaxUpstreamConcurrency)
+
+// upstreamSlotWait is how long a call waits for a free slot before giving
+// up with errUpstreamBusy.
+var upstreamSlotWait = 2 * time.Second
+
+var errUpstreamBusy = &httpError{http.StatusServiceUnavailable, "too many upstream requests, try again later"}
+
+// acquire takes a slot, waiting at most upstreamSlotWait and never past
+// the end of ctx.
+func (s semaphore) acquire(ctx context.Context) error {
+    if s == nil {
+        return nil
+    }
+    timer := time.NewTimer(upstreamSlotWait)
+    defer timer.Stop()
+    select {
+    case s <- struct{}{}:
+        return nil
+    case <-timer.C:
+        return errUpstreamBusy
+    case <-ctx.Done():
+        return errUpstreamBusy
+    }
+}
+
+// release gives back a slot taken by acquire.
+func (s semaphore) release() {
+    if s != nil {
+        <-s
+    }
+}
+
+// releasingBody is a response body that releases its upstream slot once
+// closed, so that streamed replies hold the slot while they stream.
+type releasingBody struct {
+    io.ReadCloser
+    slots semaphore
+    once sync.Once
+}
+
+func (b *releasingBody) Close() error {
+    err := b.ReadCloser.Close()
+    b.once.Do(b.slots.release)
+    return err
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+    "context"
+    "net/http"
+    "net/http/httptest"
+    "sync"
+    "testing"
+    "time"
+)
+
+// limitUpstream sets the upstream concurrency to n until the test ends.
+func limitUpstream(t *testing.T, n int) {
+    saved := upstreamSlots
+    upstreamSlots = newSemaphore(n)
+    t.Cleanup(func() { upstreamSlots = saved })
+}
+
+// TestUpstreamSlotsSerialize makes concurrent upstream calls with a
+// single slot, checking that the upstream never sees two at once.
+func TestUpstreamSlotsSerialize(t *testing.T) {
+    limitUpstream(t, 1)
+    var mu sync.Mutex
+    inFlight, maxInFlight := 0, 0
+    upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        mu.Lock()
+        inFlight++
+        if inFlight > maxInFlight {
+            maxInFlight = inFlight
+        }
+        mu.Unlock()
+        time.Sleep(10 * time.Millisecond)
+        mu.Lock()
+        inFlight--
+        mu.Unlock()
+        w.Write([]byte(`{}`))
+    }))
+    defer upstream.Close()
+
+    var wg sync.WaitGroup
+    for i := 0; i < 5; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            if _, err := fetchUpstream(context.Background(), upstream.URL); err != nil {
+                t.Error(err)
+            }
+        }()
+    }
+    wg.Wait()
+
+    if maxInFlight != 1 {
+        t.Errorf("upstream saw %d calls at once, want 1", maxInFlight)
+    }
+}
+
+// TestUpstreamSlotsBusy holds the only slot, checking that another call
+// gives up with a 503 after upstreamSlotWait.
+func TestUpstreamSlotsBusy(t *testing.T) {
+    limitUpstream(t, 1)
+    saved := upstreamSlotWait
+    upstreamSlotWait = 10 * time.Millisecond
+    t.Cleanup(func() { upstreamSlotWait = saved })
+
+    if err := upstreamSlots.acquire(context.Background()); err != nil {
+        t.Fatal(err)
+    }
+    defer upstreamSlots.release()
+
+    _, err := fetchUpstream(context.Background(), closedURL(t))
+    if he, ok := err.(*httpError); !ok || he.status != http.StatusServiceUnavailable {
+        t.Errorf("fetchUpstream with no free slot = %v, want a 503", err)
+    }
+}
+
+// TestUpstreamSlotsReleased checks that streamed, failed and not found
+// calls all give their slot back.
+func TestUpstreamSlotsReleased(t *testing.T) {
+    limitUpstream(t, 1)
+    fastRetries(t)
+    upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.URL.Path == "/missing" {
+            http.NotFound(w, r)
+            return
+        }
+        w.Write([]byte(`{}`))
+    }))
+    defer upstream.Close()
+
+    for _, url := range []string{upstream.URL, upstream.URL + "/missing", closedURL(t), upstream.URL} {
+        fetchUpstream(context.Background(), url)
+    }
+    if len(upstreamSlots) != 0 {
+        t.Errorf("%d upstream slots still taken, want 0", len(upstreamSlots))
+    }
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+    "bytes"
+    "crypto/rand"
+    "encoding/hex"
+    "encoding/json"
+    "net/http"
+    "sort"
+    "sync"
+    "time"
+
+    "github.com/julienschmidt/httprouter"
+)
+
+// maxSnapshots is how many snapshots are kept; taking one more drops the
+// oldest.
+const maxSnapshots = 100
+
+// snapshot is the data of a set of Pokemon as of when it was taken.
+type snapshot struct {
+    ID string `json:"id"`
+    TakenAt time.Time `json:"taken_at"`
+    Names []string `json:"names"`
+
+    pokemon map[string]PokemonResponse
+}
+
+// snapshotStore holds the snapshots in the order they were taken.
+type snapshotStore struct {
+    mu sync.Mutex
+    byID map[string]*snapshot
+    order []string
+}
+
+func newSnapshotStore() *snapshotStore {
+    return &snapshotStore{byID: make(map[string]*snapshot)}
+}
+
+// snapshots holds the snapshots taken through /pokemons/snapshot.
+var snapshots = newSnapshotStore()
+
+// randomHex returns n random bytes in hex.
+func randomHex(n int) string {
+    b := make([]byte, n)
+    rand.Read(b)
+    return hex.EncodeToString(b)
+}
+
+// add stores snap under a new ID, which it sets.
+func (s *snapshotStore) add(snap *snapshot) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    snap.ID = randomHex(8)
+    s.byID[snap.ID] = snap
+    s.order = append(s.order, snap.ID)
+    if len(s.order) > maxSnapshots {
+        delete(s.byID, s.order[0])
+        s.order = s.order[1:]
+    }
+}
+
+func (s *snapshotStore) get(id string) (*snapshot, bool) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    snap, ok := s.byID[id]
+    return snap, ok
+}
+
+// fieldChange is a field whose value differs between a snapshot and the
+// live data.
+type fieldChange struct {
+    Field string `json:"field"`
+    Before json.RawMessage `json:"before"`
+    After json.RawMessage `json:"after"`
+}
+
+// pokemonDiff is the reply of /pokemons/diff.
+type pokemonDiff struct {
+    Snapshot string `json:"snapshot"`
+    TakenAt time.Time `json:"taken_at"`
+    Name string `json:"name"`
+    Changed []fieldChange `json:"changed"`
+}
+
+// diffPokemon lists the fields that differ between before and after, by
+// name. A field missing on one side, such as empty stats, has a null
+// value there.
+func diffPokemon(before, after PokemonResponse) ([]fieldChange, error) {
+    old, err := jsonObject(before)
+    if err != nil {
+        return nil, err
+    }
+    current, err := jsonObject(after)
+    if err != nil {
+        return nil, err
+    }
+
+    changed := []fieldChange{}
+    for _, field := range pokemonFields {
+        b, a := old[field], current[field]
+        if bytes.Equal(b, a) {
+            continue
+        }
+        if b == nil {
+            b = json.RawMessage("null")
+        }
+        if a == nil {
+            a = json.RawMessage("null")
+        }
+        changed = append(changed, fieldChange{field, b, a})
+    }
+    return changed, nil
+}
+
+// criarSnapshot takes a snapshot of the Pokemon in the posted array of
+// names, fetching them live and concurrently. As in /pokemons/team, names
+// PokéAPI does not know are reported together with a 422.
+func criarSnapshot(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+    var names []string
+    if err := json.NewDecoder(r.Body).Decode(&names); err != nil {
+        writeDecodeError(w, err)
+        return
+    }
+    if len(names) == 0 {
+        writeError(w, http.StatusBadRequest, "a snapshot needs at least one pokemon")
+        return
+    }
+    if len(names) > maxBatchNames {
+        writeError(w, http.StatusBadRequest, "too many names")
+        return
+    }
+    if err := trimNames(names); err != nil {
+        writeHttpError(w, err)
+        return
+    }
+
+    fetched := make([]PokemonResponse, len(names))
+    errs := make([]error, len(names))
+    var wg sync.WaitGroup
+    for i := range names {
+        wg.Add(1)
+        go func(i int) {
+            defer wg.Done()
+            fetched[i], errs[i] = fetchLivePokemon(r.Context(), names[i])
+        }(i)
+    }
+    wg.Wait()
+
+    failed := make(map[string]string)
+    for i, err := range errs {
+        if he, ok := err.(*httpError); ok && he.status == http.StatusNotFound {
+            failed[names[i]] = "pokemon not found"
+        } else if err != nil {
+            writeHttpError(w, err)
+            return
+        }
+    }
+    if len(failed) > 0 {
+        writeValidationFailed(w, failed)
+        return
+    }
+
+    snap := &snapshot{TakenAt: time.Now().UTC(), pokemon: make(map[string]PokemonResponse, len(names))}
+    for i, name := range names {
+        snap.pokemon[name] = fetched[i]
+    }
+    for name := range snap.pokemon {
+        snap.Names = append(snap.Names, name)
+    }
+    sort.Strings(snap.Names)
+    snapshots.add(snap)
+    writeJsonFor(w, r, http.StatusCreated, snap)
+}
+
+// retornarDiff compares the Pokemon ?name= in the snapshot ?snapshot=
+// with what PokéAPI has now, listing the fields that changed.
+func retornarDiff(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+    query := r.URL.Query()
+    id, name := query.Get("snapshot"), query.Get("name")
+    if id == "" || name == "" {
+        writeError(w, http.StatusBadRequest, "snapshot and name are required")
+        return
+    }
+    snap, ok := snapshots.get(id)
+    if !ok {
+        writeError(w, http.StatusNotFound, "snapshot not found")
+        return
+    }
+    before, ok := snap.pokemon[name]
+    if !ok {
+        writeError(w, http.StatusNotFound, "pokemon not in snapshot")
+        return
+    }
+
+    after, err := fetchLivePokemon(r.Context(), name)
+    if err != nil {
+        writeHttpError(w, err)
+        return
+    }
+    changed, err := diffPokemon(before, after)
+    if err != nil {
+        logErrorf("%v", err)
+        writeError(w, http.StatusInternalServerError, "could not compare pokemon")
+        return
+    }
+    writeJsonFor(w, r, http.StatusOK, pokemonDiff{Snapshot: snap.ID, TakenAt: snap.TakenAt, Name: name, Changed: changed})
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync/atomic"
+    "testing"
+)
+
+// TestRetornarDiff snapshots pikachu, levels up the mock PokéAPI's copy
+// and diffs, checking that only the changed fields are reported.
+func TestRetornarDiff(t *testing.T) {
+    var evolved int32
+    usePokeApi(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.URL.Path != "/pokemon/pikachu" {
+            http.NotFound(w, r)
+            return
+        }
+        if atomic.LoadInt32(&evolved) == 1 {
+            fmt.Fprint(w, `{"name":"pikachu","id":25,"height":8,"weight":60,"base_experience":150,"types":[{"type":{"name":"electric"}}]}`)
+            return
+        }
+        fmt.Fprint(w, `{"name":"pikachu","id":25,"height":4,"weight":60,"base_experience":112,"types":[{"type":{"name":"electric"}}]}`)
+    }))
+    snapshots = newSnapshotStore()
+    router := newRouter()
+
+    w := httptest.NewRecorder()
+    router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/pokemons/snapshot", strings.NewReader(`["pikachu"]`)))
+    var snap snapshot
+    if w.Code != http.StatusCreated || json.Unmarshal(w.Body.Bytes(), &snap) != nil || snap.ID == "" {
+        t.Fatalf("POST /pokemons/snapshot = %d %s, want a 201 with an id", w.Code, w.Body)
+    }
+
+    atomic.StoreInt32(&evolved, 1)
+    w = httptest.NewRecorder()
+    router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemons/diff?snapshot=" + snap.ID + "&name=pikachu", nil))
+    var diff pokemonDiff
+    if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &diff) != nil {
+        t.Fatalf("GET /pokemons/diff = %d %s, want 200", w.Code, w.Body)
+    }
+    got := make(map[string]string)
+    for _, c := range diff.Changed {
+        got[c.Field] = string(c.Before) + "->" + string(c.After)
+    }
+    want := map[string]string{"base_experience": "112->150", "height": "4->8"}
+    if fmt.Sprint(got) != fmt.Sprint(want) {
+        t.Errorf("changed = %v, want %v", got, want)
+    }
+}
+
+// TestCriarSnapshotBlankName checks that blank names are refused rather
+// than sent to PokéAPI.
+func TestCriarSnapshotBlankName(t *testing.T) {
+    var calls int32
+    usePokeApi(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        atomic.AddInt32(&calls, 1)
+        fmt.Fprint(w, `{}`)
+    }))
+    snapshots = newSnapshotStore()
+
+    for _, body := range []string{`[""]`, `["  "]`, `["pikachu", ""]`} {
+        w := httptest.NewRecorder()
+        newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/pokemons/snapshot", strings.NewReader(body)))
+        if w.Code != http.StatusBadRequest {
+            t.Errorf("POST /pokemons/snapshot %s = %d, want %d", body, w.Code, http.StatusBadRequest)
+        }
+    }
+    if n := atomic.LoadInt32(&calls); n != 0 {
+        t.Errorf("PokéAPI got %d calls, want none", n)
+    }
+}
+
+func TestRetornarDiffNotFound(t *testing.T) {
+    overrideConfig(t).PokeAPIBaseURL = closedURL(t)
+    snapshots = newSnapshotStore()
+    snap := &snapshot{pokemon: map[string]PokemonResponse{"pikachu": {Name: "pikachu"}}}
+    snapshots.add(snap)
+
+    for _, query := range []string{"snapshot=missing&name=pikachu", "snapshot=" + snap.ID + "&name=ditto"} {
+        w := httptest.NewRecorder()
+        newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemons/diff?" + query, nil))
+        if w.Code != http.StatusNotFound {
+            t.Errorf("?%s status = %d, want %d", query, w.Code, http.StatusNotFound)
+        }
+    }
+}
+
+// TestSnapshotStoreLimit checks that the oldest snapshot goes once there
+// are more than maxSnapshots.
+func TestSnapshotStoreLimit(t *testing.T) {
+    s := newSnapshotStore()
+    first := &snapshot{}
+    s.add(first)
+    for i := 0; i < maxSnapshots; i++ {
+        s.add(&snapshot{})
+    }
+    if _, ok := s.get(first.ID); ok {
+        t.Error("oldest snapshot still stored")
+    }
+    if len(s.byID) != maxSnapshots {
+        t.Errorf("stored %d snapshots, want %d", len(s.byID), maxSnapshots)
+    }
+}
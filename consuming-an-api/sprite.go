@@ -0,0 +1,41 @@
+package main
+
+import (
+    "io"
+    "net/http"
+
+    "github.com/julienschmidt/httprouter"
+)
+
+// retornarSprite streams the front sprite image of a Pokemon.
+func retornarSprite(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+    pokemon, err := fetchRawPokemon(r.Context(), ps.ByName("nome"))
+    if err != nil {
+        writeHttpError(w, err)
+        return
+    }
+    if pokemon.Sprites.FrontDefault == "" {
+        writeError(w, http.StatusNotFound, "sprite not found")
+        return
+    }
+
+    response, err := openUpstream(r.Context(), pokemon.Sprites.FrontDefault)
+    if he, ok := err.(*httpError); ok && he.status == http.StatusNotFound {
+        writeError(w, http.StatusNotFound, "sprite not found")
+        return
+    }
+    if err != nil {
+        writeHttpError(w, err)
+        return
+    }
+    defer response.Body.Close()
+
+    contentType := response.Header.Get("Content-Type")
+    if contentType == "" {
+        contentType = "application/octet-stream"
+    }
+    w.Header().Set("Content-Type", contentType)
+    if _, err := io.Copy(w, response.Body); err != nil {
+        logErrorf("copying sprite of %s: %v", pokemon.Name, err)
+    }
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+    "bytes"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+)
+
+// TestRetornarSprite serves a Pokemon from one mock and its sprite from
+// another, checking the image bytes and Content-Type come through.
+func TestRetornarSprite(t *testing.T) {
+    image := []byte("\x89PNG\r\n\x1a\nnot really a png")
+    images := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("Content-Type", "image/png")
+        w.Write(image)
+    }))
+    defer images.Close()
+    usePokeApi(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        switch r.URL.Path {
+        case "/pokemon/pikachu":
+            fmt.Fprintf(w, `{"name":"pikachu","sprites":{"front_default":%q}}`, images.URL + "/25.png")
+        case "/pokemon/unown":
+            w.Write([]byte(`{"name":"unown","sprites":{"front_default":null}}`))
+        default:
+            http.NotFound(w, r)
+        }
+    }))
+
+    w := httptest.NewRecorder()
+    newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemon/pikachu/sprite", nil))
+    if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), image) {
+        t.Fatalf("GET /pokemon/pikachu/sprite = %d %q, want 200 with the image", w.Code, w.Body.Bytes())
+    }
+    if got := w.Header().Get("Content-Type"); got != "image/png" {
+        t.Fatalf("Content-Type = %q, want image/png", got)
+    }
+
+    w = httptest.NewRecorder()
+    newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemon/unown/sprite", nil))
+    if w.Code != http.StatusNotFound {
+        t.Fatalf("GET /pokemon/unown/sprite = %d, want %d", w.Code, http.StatusNotFound)
+    }
+}
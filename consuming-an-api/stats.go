@@ -0,0 +1,40 @@
+package main
+
+import (
+    "bytes"
+    "encoding/csv"
+    "mime"
+    "net/http"
+    "strconv"
+
+    "github.com/julienschmidt/httprouter"
+)
+
+// retornarStatsCSV serves the base stats of a Pokemon as a CSV download,
+// one stat_name,base_value row per stat.
+func retornarStatsCSV(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+    pokemon, err := fetchRawPokemon(r.Context(), ps.ByName("nome"))
+    if err != nil {
+        writeHttpError(w, err)
+        return
+    }
+
+    // Written to a buffer first so that a failure can still be reported
+    // with an error status.
+    var buf bytes.Buffer
+    out := csv.NewWriter(&buf)
+    out.Write([]string{"stat_name", "base_value"})
+    for _, s := range pokemon.Stats {
+        out.Write([]string{s.Stat.Name, strconv.Itoa(s.BaseStat)})
+    }
+    out.Flush()
+    if err := out.Error(); err != nil {
+        logErrorf("writing stats csv: %v", err)
+        writeError(w, http.StatusInternalServerError, "internal error")
+        return
+    }
+
+    w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+    w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": pokemon.Name + "-stats.csv"}))
+    w.Write(buf.Bytes())
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+)
+
+// TestRetornarStatsCSV exports the stats of a recorded payload.
+func TestRetornarStatsCSV(t *testing.T) {
+    pokeApiFixture(t, "pikachu.json")
+
+    w := httptest.NewRecorder()
+    newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemon/pikachu/stats.csv", nil))
+    if w.Code != http.StatusOK {
+        t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+    }
+    if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+        t.Errorf("Content-Type = %q, want text/csv", ct)
+    }
+    if cd := w.Header().Get("Content-Disposition"); cd != "attachment; filename=pikachu-stats.csv" {
+        t.Errorf("Content-Disposition = %q, want an attachment named pikachu-stats.csv", cd)
+    }
+
+    lines := strings.Split(w.Body.String(), "\n")
+    if lines[0] != "stat_name,base_value" {
+        t.Errorf("header row = %q, want %q", lines[0], "stat_name,base_value")
+    }
+    if len(lines) < 2 || lines[1] != "hp,35" {
+        t.Errorf("body = %q, want an hp,35 row after the header", w.Body)
+    }
+}
+
+func TestRetornarStatsCSVMissing(t *testing.T) {
+    mockPokeApi(t, "ditto")
+
+    w := httptest.NewRecorder()
+    newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pokemon/missingno/stats.csv", nil))
+    if w.Code != http.StatusNotFound {
+        t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
+    }
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+    "errors"
+    "sort"
+    "sync"
+    "time"
+)
+
+var (
+    errNameRequired = errors.New("name is required")
+    errPokemonExists = errors.New("pokemon already exists")
+    errPokemonNotFound = errors.New("pokemon not found")
+    errVersionConflict = errors.New("pokemon was modified by someone else")
+)
+
+// pokemonStore keeps the Pokemon created through the API in memory,
+// keyed by name. A store opened with openPokemonStore also saves them to
+// a file shortly after every change.
+type pokemonStore struct {
+    mu sync.Mutex
+    pokemons map[string]storedPokemon
+    file string
+    saveTimer *time.Timer
+}
+
+// storedPokemon is a Pokemon along with its version, which starts at 1
+// and grows with every update.
+type storedPokemon struct {
+    Pokemon
+    version int
+}
+
+func newPokemonStore() *pokemonStore {
+    return &pokemonStore{pokemons: make(map[string]storedPokemon)}
+}
+
+// store is the store used by the HTTP handlers.
+var store = newPokemonStore()
+
+// validatePokemon checks that p can be stored.
+func validatePokemon(p Pokemon) error {
+    if p.Name == "" {
+        return errNameRequired
+    }
+    return nil
+}
+
+// add stores p, failing if a Pokemon with the same name already exists.
+func (s *pokemonStore) add(p Pokemon) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if _, ok := s.pokemons[p.Name]; ok {
+        return errPokemonExists
+    }
+    s.pokemons[p.Name] = storedPokemon{p, 1}
+    s.changed()
+    return nil
+}
+
+// all returns every stored Pokemon sorted by name.
+func (s *pokemonStore) all() []Pokemon {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    pokemons := make([]Pokemon, 0, len(s.pokemons))
+    for _, p := range s.pokemons {
+        pokemons = append(pokemons, p.Pokemon)
+    }
+    sort.Slice(pokemons, func(i, j int) bool {
+        return pokemons[i].Name < pokemons[j].Name
+    })
+    return pokemons
+}
+
+// updateLevel sets the level of the named Pokemon and returns it with its
+// new version. A non-zero version must match the stored one, so that an
+// update based on stale data is refused instead of silently lost.
+func (s *pokemonStore) updateLevel(name string, level int8, version int) (storedPokemon, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    p, ok := s.pokemons[name]
+    if !ok {
+        return storedPokemon{}, errPokemonNotFound
+    }
+    if version != 0 && version != p.version {
+        return storedPokemon{}, errVersionConflict
+    }
+    p.Level = level
+    p.version++
+    s.pokemons[name] = p
+    s.changed()
+    return p, nil
+}
+
+// remove deletes the named Pokemon and reports whether it was stored.
+func (s *pokemonStore) remove(name string) bool {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if _, ok := s.pokemons[name]; !ok {
+        return false
+    }
+    delete(s.pokemons, name)
+    s.changed()
+    return true
+}
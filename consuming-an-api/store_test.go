@@ -0,0 +1,68 @@
+package main
+
+import (
+    "sync"
+    "testing"
+)
+
+// TestStoreAddDuplicate adds the same Pokemon twice, checking that the
+// second add is rejected.
+func TestStoreAddDuplicate(t *testing.T) {
+    s := newPokemonStore()
+    if err := s.add(Pokemon{"pikachu", 5}); err != nil {
+        t.Fatalf("add(pikachu) = %v, want nil", err)
+    }
+    if err := s.add(Pokemon{"pikachu", 7}); err != errPokemonExists {
+        t.Fatalf("add(pikachu) again = %v, want %v", err, errPokemonExists)
+    }
+}
+
+// TestStoreAll checks that all returns every Pokemon sorted by name.
+func TestStoreAll(t *testing.T) {
+    s := newPokemonStore()
+    s.add(Pokemon{"squirtle", 3})
+    s.add(Pokemon{"bulbasaur", 1})
+
+    got := s.all()
+    if len(got) != 2 || got[0].Name != "bulbasaur" || got[1].Name != "squirtle" {
+        t.Fatalf("all() = %v, want [bulbasaur squirtle]", got)
+    }
+}
+
+// TestStoreConcurrentRemove deletes and lists Pokemon from several
+// goroutines at once; run with -race to catch unsynchronized access.
+func TestStoreConcurrentRemove(t *testing.T) {
+    s := newPokemonStore()
+    names := []string{"bulbasaur", "charmander", "squirtle", "pikachu"}
+    for _, name := range names {
+        s.add(Pokemon{name, 1})
+    }
+
+    var wg sync.WaitGroup
+    removed := make(chan bool, len(names) * 2)
+    for i := 0; i < 2; i++ {
+        for _, name := range names {
+            wg.Add(2)
+            go func(name string) {
+                defer wg.Done()
+                removed <- s.remove(name)
+            }(name)
+            go func() {
+                defer wg.Done()
+                s.all()
+            }()
+        }
+    }
+    wg.Wait()
+    close(removed)
+
+    n := 0
+    for ok := range removed {
+        if ok {
+            n++
+        }
+    }
+    if n != len(names) || len(s.all()) != 0 {
+        t.Fatalf("%d removes succeeded leaving %v, want %d leaving none", n, s.all(), len(names))
+    }
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+    "encoding/json"
+    "io/ioutil"
+    "os"
+    "time"
+)
+
+// storeSaveDelay is how long a store backed by a file waits after a
+// change before writing, so that a burst of changes costs one write.
+var storeSaveDelay = time.Second
+
+// persistedPokemon is how a stored Pokemon is written to the store file.
+type persistedPokemon struct {
+    Pokemon
+    Version int `json:"version"`
+}
+
+// openPokemonStore returns a store backed by the JSON file at path,
+// loading the Pokemon already in it. A missing file gives an empty
+// store, and so does an unreadable one, after logging why.
+func openPokemonStore(path string) *pokemonStore {
+    s := newPokemonStore()
+    s.file = path
+
+    data, err := ioutil.ReadFile(path)
+    if os.IsNotExist(err) {
+        return s
+    }
+    if err != nil {
+        logErrorf("reading store file %s, starting empty: %v", path, err)
+        return s
+    }
+
+    var pokemons []persistedPokemon
+    if err := json.Unmarshal(data, &pokemons); err != nil {
+        logErrorf("decoding store file %s, starting empty: %v", path, err)
+        return s
+    }
+    for _, p := range pokemons {
+        if validatePokemon(p.Pokemon) != nil || p.Version < 1 {
+            logErrorf("skipping invalid pokemon %q in store file %s", p.Name, path)
+            continue
+        }
+        s.pokemons[p.Name] = storedPokemon{p.Pokemon, p.Version}
+    }
+    logInfof("loaded %d pokemon from %s", len(s.pokemons), path)
+    return s
+}
+
+// changed schedules a save after a change. It must be called with s.mu
+// held.
+func (s *pokemonStore) changed() {
+    if s.file == "" || s.saveTimer != nil {
+        return
+    }
+    s.saveTimer = time.AfterFunc(storeSaveDelay, s.flush)
+}
+
+// flush writes the store to its file straight away. The file is replaced
+// through a rename, so a crash mid-write never leaves it half written.
+func (s *pokemonStore) flush() {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if s.file == "" {
+        return
+    }
+    if s.saveTimer != nil {
+        s.saveTimer.Stop()
+        s.saveTimer = nil
+    }
+
+    pokemons := make([]persistedPokemon, 0, len(s.pokemons))
+    for _, p := range s.pokemons {
+        pokemons = append(pokemons, persistedPokemon{p.Pokemon, p.version})
+    }
+    data, err := json.Marshal(pokemons)
+    if err != nil {
+        logErrorf("encoding store: %v", err)
+        return
+    }
+
+    tmp := s.file + ".tmp"
+    if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+        logErrorf("writing store file: %v", err)
+        return
+    }
+    if err := os.Rename(tmp, s.file); err != nil {
+        logErrorf("writing store file: %v", err)
+    }
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+    "io/ioutil"
+    "path/filepath"
+    "reflect"
+    "testing"
+    "time"
+)
+
+// TestPokemonStoreFileRoundTrip saves a store to a file and opens it
+// again, checking that Pokemon and versions survive.
+func TestPokemonStoreFileRoundTrip(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "store.json")
+
+    s := openPokemonStore(path)
+    for _, p := range []Pokemon{{"pikachu", 5}, {"ditto", 10}, {"eevee", 1}} {
+        if err := s.add(p); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if _, err := s.updateLevel("pikachu", 6, 0); err != nil {
+        t.Fatal(err)
+    }
+    s.remove("eevee")
+    s.flush()
+
+    reloaded := openPokemonStore(path)
+    want := []Pokemon{{"ditto", 10}, {"pikachu", 6}}
+    if got := reloaded.all(); !reflect.DeepEqual(got, want) {
+        t.Errorf("reloaded all() = %v, want %v", got, want)
+    }
+    if _, err := reloaded.updateLevel("pikachu", 7, 2); err != nil {
+        t.Errorf("updateLevel with the saved version: %v", err)
+    }
+}
+
+// TestPokemonStoreFileDebounced checks that a change is written to the
+// file on its own once storeSaveDelay has passed.
+func TestPokemonStoreFileDebounced(t *testing.T) {
+    saved := storeSaveDelay
+    storeSaveDelay = 10 * time.Millisecond
+    t.Cleanup(func() { storeSaveDelay = saved })
+    path := filepath.Join(t.TempDir(), "store.json")
+
+    s := openPokemonStore(path)
+    s.add(Pokemon{"pikachu", 5})
+
+    deadline := time.Now().Add(time.Second)
+    for len(openPokemonStore(path).all()) != 1 {
+        if time.Now().After(deadline) {
+            t.Fatal("store was not saved after storeSaveDelay")
+        }
+        time.Sleep(5 * time.Millisecond)
+    }
+}
+
+// TestPokemonStoreFileCorrupt opens a store over a truncated file,
+// checking that it starts empty and is still usable.
+func TestPokemonStoreFileCorrupt(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "store.json")
+    if err := ioutil.WriteFile(path, []byte(`[{"name":"pikachu","lev`), 0644); err != nil {
+        t.Fatal(err)
+    }
+    captureLog(t)
+
+    s := openPokemonStore(path)
+    if got := s.all(); len(got) != 0 {
+        t.Fatalf("all() over a corrupt file = %v, want empty", got)
+    }
+    if err := s.add(Pokemon{"ditto", 10}); err != nil {
+        t.Fatal(err)
+    }
+    s.flush()
+    if got := openPokemonStore(path).all(); len(got) != 1 {
+        t.Errorf("all() after saving over a corrupt file = %v, want [ditto]", got)
+    }
+}
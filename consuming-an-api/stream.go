@@ -0,0 +1,73 @@
+package main
+
+import (
+    "encoding/json"
+    "net/http"
+    "sync"
+
+    "github.com/julienschmidt/httprouter"
+)
+
+// streamedPokemon is one line of /pokemons/stream: the Pokemon fetched
+// for Name, or why it could not be.
+type streamedPokemon struct {
+    Name string `json:"name"`
+    Pokemon *PokemonResponse `json:"pokemon,omitempty"`
+    Error string `json:"error,omitempty"`
+}
+
+// transmitirPokemons fetches every Pokemon in ?names= concurrently, like
+// retornarPokemonsEmLote, but writes each one as a line of NDJSON as soon
+// as it arrives instead of holding them all for a single reply.
+func transmitirPokemons(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+    names, err := batchNames(r)
+    if err != nil {
+        writeHttpError(w, err)
+        return
+    }
+
+    jobs := make(chan string)
+    results := make(chan streamedPokemon)
+    var wg sync.WaitGroup
+    for i := 0; i < batchWorkers && i < len(names); i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for name := range jobs {
+                line := streamedPokemon{Name: name}
+                if pokemon, err := fetchPokemon(r.Context(), name); err != nil {
+                    line.Error = err.Error()
+                } else {
+                    line.Pokemon = &pokemon
+                }
+                results <- line
+            }
+        }()
+    }
+    go func() {
+        for _, name := range names {
+            jobs <- name
+        }
+        close(jobs)
+        wg.Wait()
+        close(results)
+    }()
+
+    w.Header().Set("Content-Type", "application/x-ndjson")
+    w.WriteHeader(http.StatusOK)
+    flush(w)
+    encoder := json.NewEncoder(w)
+    failed := false
+    for line := range results {
+        // Once the client is gone, the results are still drained so that
+        // the workers can finish.
+        if failed {
+            continue
+        }
+        if err := encoder.Encode(line); err != nil {
+            failed = true
+            continue
+        }
+        flush(w)
+    }
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+    "bufio"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+)
+
+// TestTransmitirPokemons streams three names, one of them unknown,
+// checking for one JSON line per name.
+func TestTransmitirPokemons(t *testing.T) {
+    mockPokeApi(t, "pikachu", "ditto")
+    server := httptest.NewServer(newRouter())
+    defer server.Close()
+
+    res, err := http.Get(server.URL + "/pokemons/stream?names=pikachu,ditto,missingno")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer res.Body.Close()
+    if ct := res.Header.Get("Content-Type"); res.StatusCode != http.StatusOK || ct != "application/x-ndjson" {
+        t.Fatalf("GET /pokemons/stream = %d %s, want 200 application/x-ndjson", res.StatusCode, ct)
+    }
+
+    lines := make(map[string]streamedPokemon)
+    scanner := bufio.NewScanner(res.Body)
+    for scanner.Scan() {
+        var line streamedPokemon
+        if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+            t.Fatalf("line %q: %v", scanner.Text(), err)
+        }
+        if _, dup := lines[line.Name]; dup {
+            t.Errorf("%s streamed twice", line.Name)
+        }
+        lines[line.Name] = line
+    }
+    if err := scanner.Err(); err != nil {
+        t.Fatal(err)
+    }
+
+    if len(lines) != 3 {
+        t.Fatalf("streamed %d lines, want 3: %v", len(lines), lines)
+    }
+    for _, name := range []string{"pikachu", "ditto"} {
+        if p := lines[name].Pokemon; p == nil || p.Name != name {
+            t.Errorf("%s line = %+v, want the Pokemon", name, lines[name])
+        }
+    }
+    if line := lines["missingno"]; line.Pokemon != nil || line.Error == "" {
+        t.Errorf("missingno line = %+v, want an error", line)
+    }
+}
+
+// TestTransmitirPokemonsFlushes holds back one Pokemon until the other
+// has been read, which only works if each line is flushed on its own.
+func TestTransmitirPokemonsFlushes(t *testing.T) {
+    release := make(chan struct{})
+    usePokeApi(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        name := strings.TrimPrefix(r.URL.Path, "/pokemon/")
+        if name == "slowpoke" {
+            <-release
+        }
+        fmt.Fprintf(w, `{"name":%q,"id":1}`, name)
+    }))
+    usePokemonCache(t, 0)
+    server := httptest.NewServer(newRouter())
+    defer server.Close()
+    defer close(release)
+
+    res, err := http.Get(server.URL + "/pokemons/stream?names=slowpoke,pikachu")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer res.Body.Close()
+    line, err := bufio.NewReader(res.Body).ReadString('\n')
+    if err != nil || !strings.Contains(line, `"pikachu"`) {
+        t.Fatalf("first line = %q, %v, want pikachu", line, err)
+    }
+}
+
+// TestTransmitirPokemonsWriteTimeout streams for longer than the server's
+// WriteTimeout, checking that the stream is exempt from it.
+func TestTransmitirPokemonsWriteTimeout(t *testing.T) {
+    usePokeApi(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        time.Sleep(100 * time.Millisecond)
+        fmt.Fprintf(w, `{"name":%q,"id":1}`, strings.TrimPrefix(r.URL.Path, "/pokemon/"))
+    }))
+    config.WriteTimeout = 20 * time.Millisecond
+    usePokemonCache(t, 0)
+    server := httptest.NewUnstartedServer(nil)
+    server.Config = newServer(newRouter())
+    server.Start()
+    defer server.Close()
+
+    res, err := http.Get(server.URL + "/pokemons/stream?names=pikachu")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer res.Body.Close()
+    line, err := bufio.NewReader(res.Body).ReadString('\n')
+    if err != nil || !strings.Contains(line, `"pikachu"`) {
+        t.Fatalf("first line = %q, %v, want pikachu", line, err)
+    }
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+    "encoding/json"
+    "net/http"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+
+    "github.com/julienschmidt/httprouter"
+)
+
+// maxTeamSize is the most Pokemon a team may have, as in the games.
+const maxTeamSize = 6
+
+// teamSummary is the reply of /pokemons/team. Types lists each type in
+// the team once.
+type teamSummary struct {
+    Names []string `json:"names"`
+    Types []string `json:"types"`
+    TotalBaseStats int `json:"total_base_stats"`
+}
+
+// summarizeTeam adds up the types and base stats of team.
+func summarizeTeam(team []PokemonResponse) teamSummary {
+    summary := teamSummary{Names: make([]string, 0, len(team)), Types: []string{}}
+    seen := make(map[string]bool)
+    for _, p := range team {
+        summary.Names = append(summary.Names, p.Name)
+        for _, t := range p.Types {
+            if !seen[t] {
+                seen[t] = true
+                summary.Types = append(summary.Types, t)
+            }
+        }
+        for _, value := range p.Stats {
+            summary.TotalBaseStats += value
+        }
+    }
+    sort.Strings(summary.Types)
+    return summary
+}
+
+// trimNames trims the blanks around each of the posted names, refusing a
+// name that is blank altogether, which PokéAPI would take for its list of
+// every Pokemon.
+func trimNames(names []string) error {
+    for i, name := range names {
+        names[i] = strings.TrimSpace(name)
+        if names[i] == "" {
+            return &httpError{http.StatusBadRequest, "pokemon names must not be blank"}
+        }
+    }
+    return nil
+}
+
+// criarTime checks that every Pokemon in the posted array of names
+// exists, fetching them concurrently, and summarizes the team. Names
+// PokéAPI does not know are reported together with a 422.
+func criarTime(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+    var names []string
+    if err := json.NewDecoder(r.Body).Decode(&names); err != nil {
+        writeDecodeError(w, err)
+        return
+    }
+    if len(names) == 0 {
+        writeError(w, http.StatusBadRequest, "a team needs at least one pokemon")
+        return
+    }
+    if len(names) > maxTeamSize {
+        writeError(w, http.StatusBadRequest, "a team has at most " + strconv.Itoa(maxTeamSize) + " pokemon")
+        return
+    }
+    if err := trimNames(names); err != nil {
+        writeHttpError(w, err)
+        return
+    }
+
+    team := make([]PokemonResponse, len(names))
+    errs := make([]error, len(names))
+    var wg sync.WaitGroup
+    for i := range names {
+        wg.Add(1)
+        go func(i int) {
+            defer wg.Done()
+            team[i], errs[i] = fetchPokemon(r.Context(), names[i])
+        }(i)
+    }
+    wg.Wait()
+
+    failed := make(map[string]string)
+    for i, err := range errs {
+        if he, ok := err.(*httpError); ok && he.status == http.StatusNotFound {
+            failed[names[i]] = "pokemon not found"
+        } else if err != nil {
+            writeHttpError(w, err)
+            return
+        }
+    }
+    if len(failed) > 0 {
+        writeValidationFailed(w, failed)
+        return
+    }
+    writeJsonFor(w, r, http.StatusOK, summarizeTeam(team))
+}
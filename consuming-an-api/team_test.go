@@ -0,0 +1,77 @@
+package main
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+func postTeam(body string) *httptest.ResponseRecorder {
+    w := httptest.NewRecorder()
+    newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/pokemons/team", strings.NewReader(body)))
+    return w
+}
+
+func TestCriarTime(t *testing.T) {
+    mockTeamPokeApi(t, map[string]string{"pikachu": "electric", "raichu": "electric", "squirtle": "water"})
+
+    w := postTeam(`["pikachu","squirtle","raichu"]`)
+    if w.Code != http.StatusOK {
+        t.Fatalf("status = %d %s, want %d", w.Code, w.Body, http.StatusOK)
+    }
+    var got teamSummary
+    if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+        t.Fatal(err)
+    }
+    want := teamSummary{Names: []string{"pikachu", "squirtle", "raichu"}, Types: []string{"electric", "water"}, TotalBaseStats: 60}
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("summary = %+v, want %+v", got, want)
+    }
+}
+
+func TestCriarTimeTooLarge(t *testing.T) {
+    mockTeamPokeApi(t, map[string]string{})
+
+    if w := postTeam(`["a","b","c","d","e","f","g"]`); w.Code != http.StatusBadRequest {
+        t.Errorf("status of a 7 Pokemon team = %d, want %d", w.Code, http.StatusBadRequest)
+    }
+    if w := postTeam(`[]`); w.Code != http.StatusBadRequest {
+        t.Errorf("status of an empty team = %d, want %d", w.Code, http.StatusBadRequest)
+    }
+}
+
+// TestCriarTimeBlankName checks that blank names are refused rather than
+// sent to PokéAPI, and that names are trimmed.
+func TestCriarTimeBlankName(t *testing.T) {
+    mockTeamPokeApi(t, map[string]string{"pikachu": "electric"})
+
+    for _, body := range []string{`[""]`, `["  "]`, `["pikachu", ""]`} {
+        if w := postTeam(body); w.Code != http.StatusBadRequest {
+            t.Errorf("status of team %s = %d, want %d", body, w.Code, http.StatusBadRequest)
+        }
+    }
+    if w := postTeam(`[" pikachu "]`); w.Code != http.StatusOK {
+        t.Errorf("status of team [\" pikachu \"] = %d, want %d", w.Code, http.StatusOK)
+    }
+}
+
+// TestCriarTimeInvalidName checks that an unknown name is reported by
+// name with a 422.
+func TestCriarTimeInvalidName(t *testing.T) {
+    mockTeamPokeApi(t, map[string]string{"pikachu": "electric"})
+
+    w := postTeam(`["pikachu","missingno"]`)
+    if w.Code != http.StatusUnprocessableEntity {
+        t.Fatalf("status = %d, want %d", w.Code, http.StatusUnprocessableEntity)
+    }
+    var body errorResponse
+    if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+        t.Fatal(err)
+    }
+    if !reflect.DeepEqual(body.Fields, map[string]string{"missingno": "pokemon not found"}) {
+        t.Errorf("fields = %v, want missingno not found", body.Fields)
+    }
+}
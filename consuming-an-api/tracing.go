@@ -0,0 +1,89 @@
+package main
+
+import (
+    "context"
+    "net/http"
+
+    "github.com/julienschmidt/httprouter"
+    "go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+    "go.opentelemetry.io/otel/attribute"
+    "go.opentelemetry.io/otel/codes"
+    "go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+    "go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+    "go.opentelemetry.io/otel/propagation"
+    sdktrace "go.opentelemetry.io/otel/sdk/trace"
+    "go.opentelemetry.io/otel/trace"
+)
+
+// Requests are traced with OpenTelemetry: a server span per request, a
+// client span per upstream call nested in it through the context, and
+// W3C traceparent headers to link us with the services around us.
+
+// tracerName names the instrumentation the spans come from.
+const tracerName = "example.com/consuming-an-api"
+
+// tracerProvider makes the spans of this process. It has no exporter
+// until exportSpans gives it one, so spans are linked and propagated but
+// dropped once they end.
+var tracerProvider = sdktrace.NewTracerProvider()
+
+// tracePropagator reads and writes the traceparent headers.
+var tracePropagator = propagation.TraceContext{}
+
+// exportSpans sends the spans that end to the exporter named by
+// config.TraceExporter: "stdout" prints them as JSON, and "otlp" sends
+// them over OTLP/HTTP to the collector set in the standard
+// $OTEL_EXPORTER_OTLP_ENDPOINT. The returned function flushes the spans
+// still queued.
+func exportSpans(name string) (func(context.Context) error, error) {
+    var exporter sdktrace.SpanExporter
+    var err error
+    switch name {
+    case "stdout":
+        exporter, err = stdouttrace.New()
+    case "otlp":
+        exporter, err = otlptracehttp.New(context.Background())
+    default:
+        return func(context.Context) error { return nil }, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+    processor := sdktrace.NewBatchSpanProcessor(exporter)
+    tracerProvider.RegisterSpanProcessor(processor)
+    return processor.Shutdown, nil
+}
+
+// traceRequests wraps every request handled by next in a server span
+// named after route, continuing the trace of an incoming traceparent.
+func traceRequests(route string, next httprouter.Handle) httprouter.Handle {
+    tracer := tracerProvider.Tracer(tracerName)
+    return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+        ctx := tracePropagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+        ctx, span := tracer.Start(ctx, r.Method + " " + route,
+            trace.WithSpanKind(trace.SpanKindServer),
+            trace.WithAttributes(
+                attribute.String("http.request.method", r.Method),
+                attribute.String("http.route", route),
+            ),
+        )
+        defer span.End()
+
+        rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+        next(rec, r.WithContext(ctx), ps)
+
+        span.SetAttributes(attribute.Int("http.response.status_code", rec.status))
+        if rec.status >= 500 {
+            span.SetStatus(codes.Error, http.StatusText(rec.status))
+        }
+    }
+}
+
+// newTracingTransport wraps every upstream call made through next in a
+// client span and passes the trace on in a traceparent header.
+func newTracingTransport(next http.RoundTripper) http.RoundTripper {
+    return otelhttp.NewTransport(next,
+        otelhttp.WithTracerProvider(tracerProvider),
+        otelhttp.WithPropagators(tracePropagator),
+    )
+}
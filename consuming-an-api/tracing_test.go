@@ -0,0 +1,117 @@
+package main
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+    "go.opentelemetry.io/otel/attribute"
+    "go.opentelemetry.io/otel/propagation"
+    sdktrace "go.opentelemetry.io/otel/sdk/trace"
+    "go.opentelemetry.io/otel/sdk/trace/tracetest"
+    "go.opentelemetry.io/otel/trace"
+)
+
+// recordSpans exports the spans that end to memory until the test ends.
+func recordSpans(t *testing.T) *tracetest.InMemoryExporter {
+    exporter := tracetest.NewInMemoryExporter()
+    processor := sdktrace.NewSimpleSpanProcessor(exporter)
+    tracerProvider.RegisterSpanProcessor(processor)
+    t.Cleanup(func() { tracerProvider.UnregisterSpanProcessor(processor) })
+    return exporter
+}
+
+// spanAttribute returns the value of the attribute key of span, as a
+// string.
+func spanAttribute(span tracetest.SpanStub, key attribute.Key) string {
+    for _, kv := range span.Attributes {
+        if kv.Key == key {
+            return kv.Value.Emit()
+        }
+    }
+    return ""
+}
+
+// TestTraceRequests requests a Pokemon, checking that its server span
+// has the upstream call as a nested client span, and that the upstream
+// got the client span's traceparent.
+func TestTraceRequests(t *testing.T) {
+    spans := recordSpans(t)
+    var traceparent string
+    upstream := usePokeApi(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        traceparent = r.Header.Get("traceparent")
+        w.Write([]byte(`{"name":"ditto","id":132}`))
+    }))
+
+    w := httptest.NewRecorder()
+    newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/retornarPokemon/ditto", nil))
+    if w.Code != http.StatusOK {
+        t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+    }
+
+    ended := spans.GetSpans()
+    if len(ended) != 2 {
+        t.Fatalf("got %d spans, want a client and a server span", len(ended))
+    }
+    client, server := ended[0], ended[1]
+    if server.SpanKind != trace.SpanKindServer || server.Name != "GET /retornarPokemon/:nome" || spanAttribute(server, "http.route") != "/retornarPokemon/:nome" || server.Parent.IsValid() {
+        t.Errorf("server span = %s %s, want a root GET /retornarPokemon/:nome server span", server.SpanKind, server.Name)
+    }
+    if status := spanAttribute(server, "http.response.status_code"); status != "200" {
+        t.Errorf("server status = %q, want 200", status)
+    }
+    if client.SpanKind != trace.SpanKindClient || client.SpanContext.TraceID() != server.SpanContext.TraceID() || client.Parent.SpanID() != server.SpanContext.SpanID() {
+        t.Errorf("client span = %s %s, want a child of the server span %s", client.SpanKind, client.Name, server.SpanContext.SpanID())
+    }
+    if host := strings.TrimPrefix(upstream.URL, "http://"); spanAttribute(client, "server.address") + ":" + spanAttribute(client, "server.port") != host {
+        t.Errorf("client server.address = %q, want %q", spanAttribute(client, "server.address"), host)
+    }
+    carrier := propagation.MapCarrier{}
+    tracePropagator.Inject(trace.ContextWithSpanContext(t.Context(), client.SpanContext), carrier)
+    if traceparent != carrier["traceparent"] {
+        t.Errorf("upstream traceparent = %q, want %q", traceparent, carrier["traceparent"])
+    }
+}
+
+// TestTraceRequestsRemoteParent checks that an incoming traceparent makes
+// the server span continue its trace.
+func TestTraceRequestsRemoteParent(t *testing.T) {
+    spans := recordSpans(t)
+
+    r := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+    r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+    newRouter().ServeHTTP(httptest.NewRecorder(), r)
+
+    ended := spans.GetSpans()
+    if len(ended) != 1 {
+        t.Fatalf("got %d spans, want 1", len(ended))
+    }
+    if span := ended[0]; span.SpanContext.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" || span.Parent.SpanID().String() != "00f067aa0ba902b7" || !span.Parent.IsRemote() {
+        t.Errorf("span trace %s parent %s, want the incoming trace and parent", span.SpanContext.TraceID(), span.Parent.SpanID())
+    }
+}
+
+// TestTraceRequestsBadTraceparent checks that a malformed traceparent
+// starts a new trace.
+func TestTraceRequestsBadTraceparent(t *testing.T) {
+    for _, header := range []string{"00-abc-def-01", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", "00-4bf92f3577b34da6a3ce929d0e0e473g-00f067aa0ba902b7-01"} {
+        spans := recordSpans(t)
+        r := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+        r.Header.Set("traceparent", header)
+        newRouter().ServeHTTP(httptest.NewRecorder(), r)
+
+        if ended := spans.GetSpans(); len(ended) == 0 || ended[len(ended) - 1].Parent.IsValid() {
+            t.Errorf("traceparent %q was continued, want a new trace", header)
+        }
+    }
+}
+
+// TestExportSpans checks that the exporters are known and "none" adds
+// none.
+func TestExportSpans(t *testing.T) {
+    flush, err := exportSpans("none")
+    if err != nil || flush(t.Context()) != nil {
+        t.Errorf("exportSpans(none) = %v", err)
+    }
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+    "context"
+    "net/http"
+
+    "github.com/julienschmidt/httprouter"
+)
+
+// upstreamClient is used for every outbound request. It has a timeout so
+// that a slow upstream cannot hang a handler forever, and keeps
+// connections alive so that they are reused between calls.
+var upstreamClient = newUpstreamClient(defaultConfig)
+
+// newUpstreamClient builds the client for upstream calls from c.
+func newUpstreamClient(c Config) *http.Client {
+    transport := http.DefaultTransport.(*http.Transport).Clone()
+    transport.DisableKeepAlives = false
+    transport.MaxIdleConns = c.UpstreamMaxIdleConns
+    transport.MaxIdleConnsPerHost = c.UpstreamMaxIdleConnsPerHost
+    transport.IdleConnTimeout = c.UpstreamIdleConnTimeout
+
+    return &http.Client{
+        Transport: newTracingTransport(&headerTransport{next: transport, userAgent: c.UpstreamUserAgent}),
+        Timeout: c.UpstreamTimeout,
+    }
+}
+
+// headerTransport identifies us to upstreams with our User-Agent and
+// passes on the Accept-Language of the request that caused the call.
+type headerTransport struct {
+    next http.RoundTripper
+    userAgent string
+}
+
+func (t *headerTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+    // A RoundTripper must not change the request it is given.
+    request = request.Clone(request.Context())
+    if t.userAgent != "" {
+        request.Header.Set("User-Agent", t.userAgent)
+    }
+    if lang := acceptLanguageFromContext(request.Context()); lang != "" && request.Header.Get("Accept-Language") == "" {
+        request.Header.Set("Accept-Language", lang)
+    }
+    return t.next.RoundTrip(request)
+}
+
+// withAcceptLanguage keeps the request's Accept-Language in its context,
+// for headerTransport to forward.
+func withAcceptLanguage(next httprouter.Handle) httprouter.Handle {
+    return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+        if lang := r.Header.Get("Accept-Language"); lang != "" {
+            r = r.WithContext(context.WithValue(r.Context(), acceptLanguageKey, lang))
+        }
+        next(w, r, ps)
+    }
+}
+
+// acceptLanguageFromContext returns the Accept-Language of the request
+// ctx belongs to, or "" when it had none.
+func acceptLanguageFromContext(ctx context.Context) string {
+    lang, _ := ctx.Value(acceptLanguageKey).(string)
+    return lang
+}
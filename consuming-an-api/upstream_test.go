@@ -0,0 +1,74 @@
+package main
+
+import (
+    "context"
+    "net"
+    "net/http"
+    "net/http/httptest"
+    "sync/atomic"
+    "testing"
+)
+
+// TestUpstreamClientReusesConnections makes several sequential upstream
+// calls, checking that the server only accepted one connection.
+func TestUpstreamClientReusesConnections(t *testing.T) {
+    var accepted int64
+    upstream := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Write([]byte(`{}`))
+    }))
+    upstream.Config.ConnState = func(c net.Conn, state http.ConnState) {
+        if state == http.StateNew {
+            atomic.AddInt64(&accepted, 1)
+        }
+    }
+    upstream.Start()
+    defer upstream.Close()
+
+    defer func(c *http.Client) { upstreamClient = c }(upstreamClient)
+    upstreamClient = newUpstreamClient(defaultConfig)
+
+    for i := 0; i < 5; i++ {
+        if _, err := fetchUpstream(context.Background(), upstream.URL); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if n := atomic.LoadInt64(&accepted); n != 1 {
+        t.Fatalf("upstream accepted %d connections for 5 calls, want 1", n)
+    }
+}
+
+// TestUpstreamHeaders calls a mock upstream on behalf of a request with
+// an Accept-Language, checking that it receives that and our User-Agent.
+func TestUpstreamHeaders(t *testing.T) {
+    var got http.Header
+    upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        got = r.Header.Clone()
+        w.Write([]byte(`{"results":[]}`))
+    }))
+    defer upstream.Close()
+
+    c := overrideConfig(t)
+    c.RandomUserBaseURL = upstream.URL
+    c.UpstreamUserAgent = "go-learn/test"
+    defer func(c *http.Client) { upstreamClient = c }(upstreamClient)
+    upstreamClient = newUpstreamClient(*c)
+
+    r := httptest.NewRequest(http.MethodGet, "/retornarUsuarioAleatorio", nil)
+    r.Header.Set("Accept-Language", "pt-BR, en;q=0.8")
+    newRouter().ServeHTTP(httptest.NewRecorder(), r)
+
+    if ua := got.Get("User-Agent"); ua != "go-learn/test" {
+        t.Errorf("upstream User-Agent = %q, want go-learn/test", ua)
+    }
+    if lang := got.Get("Accept-Language"); lang != "pt-BR, en;q=0.8" {
+        t.Errorf("upstream Accept-Language = %q, want the client's", lang)
+    }
+}
+
+// TestUpstreamUserAgentDefault checks the default User-Agent names us and
+// our version.
+func TestUpstreamUserAgentDefault(t *testing.T) {
+    if want := "go-learn/" + version; defaultConfig.UpstreamUserAgent != want {
+        t.Errorf("default UpstreamUserAgent = %q, want %q", defaultConfig.UpstreamUserAgent, want)
+    }
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+    "fmt"
+    "reflect"
+    "strconv"
+    "strings"
+)
+
+// validateStruct checks the fields of the struct v against the rules in
+// their validate tags and maps the name of each failing field to why it
+// failed. The rules, separated by commas, are:
+//
+//    required   the field is not its zero value
+//    min=N      a number is at least N, a string at least N characters
+//    max=N      a number is at most N, a string at most N characters
+//
+// A malformed tag is a programming error and panics.
+func validateStruct(v interface{}) map[string]string {
+    value := reflect.ValueOf(v)
+    if value.Kind() == reflect.Ptr {
+        value = value.Elem()
+    }
+
+    failed := make(map[string]string)
+    for i := 0; i < value.NumField(); i++ {
+        field := value.Type().Field(i)
+        tag := field.Tag.Get("validate")
+        if tag == "" {
+            continue
+        }
+        for _, rule := range strings.Split(tag, ",") {
+            if msg := checkRule(value.Field(i), rule); msg != "" {
+                failed[field.Name] = msg
+                break
+            }
+        }
+    }
+    return failed
+}
+
+// checkRule checks one validate rule against v, returning why it failed
+// or "" when it holds.
+func checkRule(v reflect.Value, rule string) string {
+    name, arg := rule, ""
+    if i := strings.Index(rule, "="); i >= 0 {
+        name, arg = rule[:i], rule[i+1:]
+    }
+
+    switch name {
+    case "required":
+        if v.IsZero() {
+            return "is required"
+        }
+        return ""
+    case "min", "max":
+        bound, err := strconv.ParseFloat(arg, 64)
+        if err != nil {
+            panic(fmt.Sprintf("validate: bad %s bound %q", name, arg))
+        }
+        n, unit := measure(v)
+        if name == "min" && n < bound {
+            return fmt.Sprintf("must be at least %s%s", arg, unit)
+        }
+        if name == "max" && n > bound {
+            return fmt.Sprintf("must be at most %s%s", arg, unit)
+        }
+        return ""
+    }
+    panic(fmt.Sprintf("validate: unknown rule %q", rule))
+}
+
+// measure returns what min and max compare against: the value of a
+// number or the length of a string, along with the unit to report.
+func measure(v reflect.Value) (float64, string) {
+    switch v.Kind() {
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        return float64(v.Int()), ""
+    case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+        return float64(v.Uint()), ""
+    case reflect.Float32, reflect.Float64:
+        return v.Float(), ""
+    case reflect.String:
+        return float64(len([]rune(v.String()))), " characters"
+    }
+    panic(fmt.Sprintf("validate: min and max do not apply to %s", v.Kind()))
+}
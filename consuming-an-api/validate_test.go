@@ -0,0 +1,68 @@
+package main
+
+import (
+    "reflect"
+    "testing"
+)
+
+// validated has one field per validate rule.
+type validated struct {
+    Name string `validate:"required"`
+    Count int `validate:"required,min=1,max=10"`
+    Ratio float64 `validate:"min=0,max=1"`
+    Code string `validate:"min=2,max=3"`
+    Skipped bool
+}
+
+// TestValidateStruct breaks each rule in turn, checking the reported
+// field and message.
+func TestValidateStruct(t *testing.T) {
+    valid := validated{Name: "pikachu", Count: 5, Ratio: 0.5, Code: "br"}
+    tests := []struct {
+        name string
+        change func(v *validated)
+        want map[string]string
+    }{
+        {"valid", func(v *validated) {}, map[string]string{}},
+        {"required string", func(v *validated) { v.Name = "" }, map[string]string{"Name": "is required"}},
+        {"required number", func(v *validated) { v.Count = 0 }, map[string]string{"Count": "is required"}},
+        {"min int", func(v *validated) { v.Count = -1 }, map[string]string{"Count": "must be at least 1"}},
+        {"max int", func(v *validated) { v.Count = 11 }, map[string]string{"Count": "must be at most 10"}},
+        {"min float", func(v *validated) { v.Ratio = -0.1 }, map[string]string{"Ratio": "must be at least 0"}},
+        {"max float", func(v *validated) { v.Ratio = 1.5 }, map[string]string{"Ratio": "must be at most 1"}},
+        {"min string", func(v *validated) { v.Code = "b" }, map[string]string{"Code": "must be at least 2 characters"}},
+        {"max string", func(v *validated) { v.Code = "bras" }, map[string]string{"Code": "must be at most 3 characters"}},
+        {"several", func(v *validated) { v.Name, v.Ratio = "", 2 }, map[string]string{"Name": "is required", "Ratio": "must be at most 1"}},
+    }
+    for _, tt := range tests {
+        v := valid
+        tt.change(&v)
+        if got := validateStruct(v); !reflect.DeepEqual(got, tt.want) {
+            t.Errorf("%s: validateStruct(%+v) = %v, want %v", tt.name, v, got, tt.want)
+        }
+    }
+}
+
+// TestValidateStructMessage checks the rules on Message.
+func TestValidateStructMessage(t *testing.T) {
+    if got := validateStruct(defaultMessage); len(got) != 0 {
+        t.Errorf("validateStruct(defaultMessage) = %v, want no failures", got)
+    }
+    got := validateStruct(&Message{Decimal: -1})
+    want := map[string]string{"Body": "is required", "Decimal": "must be at least 0"}
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("validateStruct(empty Message) = %v, want %v", got, want)
+    }
+}
+
+// TestValidateStructBadTag checks that a malformed tag panics.
+func TestValidateStructBadTag(t *testing.T) {
+    defer func() {
+        if recover() == nil {
+            t.Error("validateStruct with an unknown rule did not panic")
+        }
+    }()
+    validateStruct(struct {
+        Name string `validate:"uppercase"`
+    }{"pikachu"})
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+    "net/http"
+
+    "github.com/julienschmidt/httprouter"
+)
+
+// Build information, set at build time with e.g.
+//
+//    go build -ldflags "-X main.version=1.2.0 -X main.commit=$(git rev-parse HEAD) -X main.buildTime=$(date -u +%FT%TZ)"
+var (
+    version = "dev"
+    commit = "unknown"
+    buildTime = "unknown"
+)
+
+// retornarVersao reports which build is running.
+func retornarVersao(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+    writeJsonFor(w, r, http.StatusOK, map[string]string{
+        "version": version,
+        "commit": commit,
+        "build_time": buildTime,
+    })
+}
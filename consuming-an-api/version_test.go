@@ -0,0 +1,44 @@
+package main
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+)
+
+// getVersion requests /version and decodes the reply.
+func getVersion(t *testing.T) map[string]string {
+    w := httptest.NewRecorder()
+    newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/version", nil))
+    if w.Code != http.StatusOK {
+        t.Fatalf("GET /version status = %d, want %d", w.Code, http.StatusOK)
+    }
+    var got map[string]string
+    if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+        t.Fatal(err)
+    }
+    return got
+}
+
+// TestRetornarVersaoDefaults checks the values reported when no -ldflags
+// were given.
+func TestRetornarVersaoDefaults(t *testing.T) {
+    got := getVersion(t)
+    if got["version"] != "dev" || got["commit"] != "unknown" || got["build_time"] != "unknown" {
+        t.Errorf("GET /version = %v, want the dev/unknown defaults", got)
+    }
+}
+
+// TestRetornarVersao sets the build variables as -ldflags -X would,
+// checking that they are reported.
+func TestRetornarVersao(t *testing.T) {
+    savedVersion, savedCommit, savedBuildTime := version, commit, buildTime
+    t.Cleanup(func() { version, commit, buildTime = savedVersion, savedCommit, savedBuildTime })
+    version, commit, buildTime = "1.2.0", "abc123", "2024-01-02T03:04:05Z"
+
+    got := getVersion(t)
+    if got["version"] != version || got["commit"] != commit || got["build_time"] != buildTime {
+        t.Errorf("GET /version = %v, want %s %s %s", got, version, commit, buildTime)
+    }
+}
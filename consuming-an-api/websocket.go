@@ -0,0 +1,116 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "net/http"
+    "time"
+
+    "github.com/gorilla/websocket"
+    "github.com/julienschmidt/httprouter"
+)
+
+// wsMaxMessage is the largest message we read; clients have nothing to
+// send us but control frames. Longer messages close the connection with
+// 1009, and frames breaking the protocol, such as unmasked ones, with
+// 1002.
+const wsMaxMessage = 1 << 16
+
+// wsWriteTimeout bounds each write, so that a client that stopped reading
+// does not hold the stream open.
+const wsWriteTimeout = 10 * time.Second
+
+// wsUpgrader answers the WebSocket handshakes. Browsers are held to the
+// origins CORS allows, since they send no preflight for WebSockets.
+var wsUpgrader = websocket.Upgrader{
+    HandshakeTimeout: 10 * time.Second,
+    CheckOrigin: func(r *http.Request) bool {
+        origin := r.Header.Get("Origin")
+        return origin == "" || corsPolicy{config.CORSAllowedOrigins}.allow(http.Header{}, origin)
+    },
+    Error: func(w http.ResponseWriter, r *http.Request, status int, reason error) {
+        writeError(w, status, reason.Error())
+    },
+}
+
+// upgradeWebSocket completes the WebSocket handshake for r and takes over
+// its connection. When it fails it has already replied with an error.
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request) (*websocket.Conn, error) {
+    if !websocket.IsWebSocketUpgrade(r) {
+        w.Header().Set("Upgrade", "websocket")
+        writeError(w, http.StatusUpgradeRequired, "websocket upgrade required")
+        return nil, errors.New("not a websocket handshake")
+    }
+    conn, err := wsUpgrader.Upgrade(w, r, nil)
+    if err != nil {
+        return nil, err
+    }
+    conn.SetReadLimit(wsMaxMessage)
+    return conn, nil
+}
+
+// readUntilClose reads what the peer sends, which answers its pings and
+// close, until the peer closes the connection or it fails.
+func readUntilClose(conn *websocket.Conn) {
+    for {
+        if _, _, err := conn.NextReader(); err != nil {
+            return
+        }
+    }
+}
+
+// writeClose sends a close frame with code.
+func writeClose(conn *websocket.Conn, code int) error {
+    return conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, ""), time.Now().Add(wsWriteTimeout))
+}
+
+// transmitirUsuarios upgrades to a WebSocket and pushes a random user
+// every config.UserStreamInterval until the client goes away or the
+// server shuts down.
+func transmitirUsuarios(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+    conn, err := upgradeWebSocket(w, r)
+    if err != nil {
+        logDebugf("request_id=%s websocket: %v", requestIDFromContext(r.Context()), err)
+        return
+    }
+
+    ctx, cancel := context.WithCancel(r.Context())
+    defer cancel()
+    done := make(chan struct{})
+    go func() {
+        defer close(done)
+        defer cancel()
+        readUntilClose(conn)
+    }()
+    defer func() {
+        conn.Close()
+        <-done
+    }()
+
+    ticker := time.NewTicker(config.UserStreamInterval)
+    defer ticker.Stop()
+    shutdown := shutdownFromContext(r.Context())
+    for {
+        user, err := fetchRandomUser(ctx)
+        switch {
+        case ctx.Err() != nil:
+            return
+        case err != nil:
+            logErrorf("streaming users: %v", err)
+        default:
+            conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+            if err := conn.WriteJSON(user); err != nil {
+                return
+            }
+        }
+
+        select {
+        case <-ctx.Done():
+            return
+        case <-shutdown:
+            writeClose(conn, websocket.CloseGoingAway)
+            return
+        case <-ticker.C:
+        }
+    }
+}
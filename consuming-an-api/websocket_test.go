@@ -0,0 +1,203 @@
+package main
+
+import (
+    "bufio"
+    "encoding/binary"
+    "errors"
+    "io"
+    "net"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/gorilla/websocket"
+)
+
+// dialWebSocket opens a WebSocket to path on server, acting as a client.
+func dialWebSocket(t *testing.T, server *httptest.Server, path string) *websocket.Conn {
+    conn, response, err := websocket.DefaultDialer.Dial("ws" + strings.TrimPrefix(server.URL, "http") + path, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { conn.Close() })
+    if response.StatusCode != http.StatusSwitchingProtocols {
+        t.Fatalf("handshake status = %d, want %d", response.StatusCode, http.StatusSwitchingProtocols)
+    }
+    conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+    return conn
+}
+
+// dialRawWebSocket completes the handshake to path on server by hand,
+// returning the connection so that the test can send frames a real client
+// would not.
+func dialRawWebSocket(t *testing.T, server *httptest.Server, path string) (net.Conn, *bufio.Reader) {
+    conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { conn.Close() })
+    conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+    request, _ := http.NewRequest(http.MethodGet, server.URL + path, nil)
+    request.Header.Set("Connection", "Upgrade")
+    request.Header.Set("Upgrade", "websocket")
+    request.Header.Set("Sec-WebSocket-Version", "13")
+    request.Header.Set("Sec-WebSocket-Key", "MDEyMzQ1Njc4OWFiY2RlZg==")
+    if err := request.Write(conn); err != nil {
+        t.Fatal(err)
+    }
+    reader := bufio.NewReader(conn)
+    response, err := http.ReadResponse(reader, request)
+    if err != nil || response.StatusCode != http.StatusSwitchingProtocols {
+        t.Fatalf("handshake = %v, %v, want %d", response, err, http.StatusSwitchingProtocols)
+    }
+    return conn, reader
+}
+
+// readCloseCode reads frames from the server until its close frame and
+// returns the close code in it.
+func readCloseCode(t *testing.T, reader *bufio.Reader) int {
+    for {
+        var header [2]byte
+        if _, err := io.ReadFull(reader, header[:]); err != nil {
+            t.Fatalf("waiting for a close frame: %v", err)
+        }
+        n := int(header[1] & 0x7F)
+        if n == 126 {
+            var ext [2]byte
+            if _, err := io.ReadFull(reader, ext[:]); err != nil {
+                t.Fatalf("waiting for a close frame: %v", err)
+            }
+            n = int(binary.BigEndian.Uint16(ext[:]))
+        }
+        payload := make([]byte, n)
+        if _, err := io.ReadFull(reader, payload); err != nil {
+            t.Fatalf("waiting for a close frame: %v", err)
+        }
+        if header[0] & 0x0F == websocket.CloseMessage {
+            return int(binary.BigEndian.Uint16(payload))
+        }
+    }
+}
+
+func TestTransmitirUsuarios(t *testing.T) {
+    overrideConfig(t).UserStreamInterval = 10 * time.Millisecond
+    server := newTestServer(t)
+
+    conn := dialWebSocket(t, server, "/ws/users")
+    for i := 0; i < 2; i++ {
+        var user RandomUser
+        if err := conn.ReadJSON(&user); err != nil || user.Email == "" {
+            t.Fatalf("message %d = %+v, %v, want a user", i, user, err)
+        }
+    }
+
+    err := conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+    if err != nil {
+        t.Fatal(err)
+    }
+    for {
+        _, _, err := conn.ReadMessage()
+        var closed *websocket.CloseError
+        if errors.As(err, &closed) {
+            if closed.Code != websocket.CloseNormalClosure {
+                t.Errorf("close code = %d, want %d", closed.Code, websocket.CloseNormalClosure)
+            }
+            break
+        }
+        if err != nil {
+            t.Fatalf("waiting for the close reply: %v", err)
+        }
+    }
+}
+
+func TestTransmitirUsuariosNotUpgrade(t *testing.T) {
+    w := httptest.NewRecorder()
+    newRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ws/users", nil))
+
+    if w.Code != http.StatusUpgradeRequired {
+        t.Errorf("status = %d, want %d", w.Code, http.StatusUpgradeRequired)
+    }
+}
+
+// TestTransmitirUsuariosOrigin checks that browsers on origins CORS does
+// not allow cannot open the stream.
+func TestTransmitirUsuariosOrigin(t *testing.T) {
+    server := newTestServer(t)
+    config.CORSAllowedOrigins = []string{"https://example.com"}
+
+    url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws/users"
+    _, response, err := websocket.DefaultDialer.Dial(url, http.Header{"Origin": {"https://evil.example"}})
+    if err == nil || response == nil || response.StatusCode != http.StatusForbidden {
+        t.Fatalf("handshake from another origin = %v, %v, want %d", response, err, http.StatusForbidden)
+    }
+    conn, _, err := websocket.DefaultDialer.Dial(url, http.Header{"Origin": {"https://example.com"}})
+    if err != nil {
+        t.Fatalf("handshake from an allowed origin: %v", err)
+    }
+    conn.Close()
+}
+
+// TestTransmitirUsuariosProtocolErrors sends frames breaking the
+// protocol, checking that the server closes with the matching code.
+func TestTransmitirUsuariosProtocolErrors(t *testing.T) {
+    overrideConfig(t).UserStreamInterval = time.Hour
+    server := newTestServer(t)
+
+    tests := []struct {
+        name string
+        frame []byte
+        code int
+    }{
+        // A text frame without the mask clients must set.
+        {"unmasked", []byte{0x81, 0x02, 'h', 'i'}, websocket.CloseProtocolError},
+        // A continuation frame with no message to continue.
+        {"stray continuation", []byte{0x80, 0x80, 0, 0, 0, 0}, websocket.CloseProtocolError},
+        // A masked text frame longer than wsMaxMessage, of which only
+        // the header is sent.
+        {"too large", []byte{0x81, 0xFF, 0, 0, 0, 0, 0, 0x10, 0, 0, 0, 0, 0, 0}, websocket.CloseMessageTooBig},
+    }
+    for _, tt := range tests {
+        conn, reader := dialRawWebSocket(t, server, "/ws/users")
+        if _, err := conn.Write(tt.frame); err != nil {
+            t.Fatal(err)
+        }
+        if code := readCloseCode(t, reader); code != tt.code {
+            t.Errorf("%s frame: close code = %d, want %d", tt.name, code, tt.code)
+        }
+    }
+}
+
+// TestTransmitirUsuariosShutdown checks that a stream is closed as going
+// away when the server shuts down.
+func TestTransmitirUsuariosShutdown(t *testing.T) {
+    overrideConfig(t).RandomUserBaseURL = fixtureServer(t, "randomuser.json").URL
+    config.UserStreamInterval = time.Hour
+    shortDrain(t, 0)
+
+    listener, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    stop := make(chan os.Signal, 1)
+    served := make(chan error, 1)
+    go func() { served <- serve(listener, newRouter(), "", "", stop) }()
+
+    conn := dialWebSocket(t, &httptest.Server{URL: "http://" + listener.Addr().String()}, "/ws/users")
+    var user RandomUser
+    if err := conn.ReadJSON(&user); err != nil {
+        t.Fatalf("first message: %v, want a user", err)
+    }
+
+    stop <- os.Interrupt
+    _, _, err = conn.ReadMessage()
+    if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+        t.Fatalf("message after shutdown error = %v, want a going away close", err)
+    }
+    if err := <-served; err != nil {
+        t.Fatal(err)
+    }
+}